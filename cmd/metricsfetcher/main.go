@@ -18,18 +18,25 @@ import (
 // Config holds
 // config parameters.
 type Config struct {
-	Client      *dd.Client
-	APIKey      string
-	AppKey      string
-	PartnQuery  string
-	BrokerQuery string
-	BrokerIDTag string
-	Span        int
-	ZKAddr      string
-	ZKPrefix    string
-	Verbose     bool
-	DryRun      bool
-	Compression bool
+	Client             *dd.Client
+	APIKey             string
+	AppKey             string
+	PartnQuery         string
+	PartnBytesInQuery  string
+	PartnBytesOutQuery string
+	BrokerQuery        string
+	BrokerTotalQuery   string
+	LogDirQuery        string
+	LogDirTotalQuery   string
+	BrokerIDTag        string
+	LogDirTag          string
+	InstanceTypeTag    string
+	Span               int
+	ZKAddr             string
+	ZKPrefix           string
+	Verbose            bool
+	DryRun             bool
+	Compression        bool
 }
 
 var (
@@ -43,8 +50,15 @@ func init() {
 	flag.StringVar(&config.APIKey, "api-key", "", "Datadog API key")
 	flag.StringVar(&config.AppKey, "app-key", "", "Datadog app key")
 	bq := flag.String("broker-storage-query", "avg:system.disk.free{service:kafka,device:/data}", "Datadog metric query to get broker storage free")
+	btq := flag.String("broker-storage-total-query", "", "Datadog metric query to get broker storage total, used for capacity-aware rebalancing (optional)")
 	flag.StringVar(&config.BrokerIDTag, "broker-id-tag", "broker_id", "Datadog host tag for broker ID")
+	ldq := flag.String("log-dir-storage-query", "", "Datadog metric query to get storage free by broker log dir, used for log dir placement (optional)")
+	ldtq := flag.String("log-dir-storage-total-query", "", "Datadog metric query to get storage total by broker log dir (optional)")
+	flag.StringVar(&config.LogDirTag, "log-dir-tag", "log_dir", "Datadog tag for broker log dir paths")
+	flag.StringVar(&config.InstanceTypeTag, "instance-type-tag", "", "Datadog host tag for broker instance types, e.g. 'instance-type' (optional)")
 	pq := flag.String("partition-size-query", "max:kafka.log.partition.size{service:kafka} by {topic,partition}", "Datadog metric query to get partition size by topic, partition")
+	biq := flag.String("partition-bytes-in-query", "", "Datadog metric query to get partition bytes in/s by topic, partition (optional)")
+	boq := flag.String("partition-bytes-out-query", "", "Datadog metric query to get partition bytes out/s by topic, partition (optional)")
 	flag.IntVar(&config.Span, "span", 3600, "Query range in seconds (now - span)")
 	flag.StringVar(&config.ZKAddr, "zk-addr", "localhost:2181", "ZooKeeper connect string")
 	flag.StringVar(&config.ZKPrefix, "zk-prefix", "topicmappr", "ZooKeeper namespace prefix")
@@ -62,7 +76,30 @@ func init() {
 
 	// Complete query string.
 	config.BrokerQuery = fmt.Sprintf("%s by {%s}.rollup(avg, %d)", *bq, config.BrokerIDTag, config.Span)
+	if config.InstanceTypeTag != "" {
+		config.BrokerQuery = fmt.Sprintf("%s by {%s,%s}.rollup(avg, %d)", *bq, config.BrokerIDTag, config.InstanceTypeTag, config.Span)
+	}
 	config.PartnQuery = fmt.Sprintf("%s.rollup(avg, %d)", *pq, config.Span)
+
+	if *btq != "" {
+		config.BrokerTotalQuery = fmt.Sprintf("%s by {%s}.rollup(avg, %d)", *btq, config.BrokerIDTag, config.Span)
+	}
+
+	if *ldq != "" {
+		config.LogDirQuery = fmt.Sprintf("%s by {%s,%s}.rollup(avg, %d)", *ldq, config.BrokerIDTag, config.LogDirTag, config.Span)
+	}
+
+	if *ldtq != "" {
+		config.LogDirTotalQuery = fmt.Sprintf("%s by {%s,%s}.rollup(avg, %d)", *ldtq, config.BrokerIDTag, config.LogDirTag, config.Span)
+	}
+
+	if *biq != "" {
+		config.PartnBytesInQuery = fmt.Sprintf("%s.rollup(avg, %d)", *biq, config.Span)
+	}
+
+	if *boq != "" {
+		config.PartnBytesOutQuery = fmt.Sprintf("%s.rollup(avg, %d)", *boq, config.Span)
+	}
 }
 
 func main() {
@@ -92,18 +129,14 @@ func main() {
 	}
 
 	// Fetch metrics data.
-	fmt.Printf("Submitting %s\n", config.PartnQuery)
 	pm, err := partitionMetrics(config)
 	exitOnErr(err)
-	fmt.Println("success")
 
 	partnData, err := json.Marshal(pm)
 	exitOnErr(err)
 
-	fmt.Printf("Submitting %s\n", config.BrokerQuery)
 	bm, err := brokerMetrics(config)
 	exitOnErr(err)
-	fmt.Println("success")
 
 	brokerData, err := json.Marshal(bm)
 	exitOnErr(err)
@@ -115,9 +148,10 @@ func main() {
 	}
 
 	if config.Verbose {
-		fmt.Printf("Broker data (will store at %s, query %s):\n%s\n"+
+		fmt.Printf("Broker data (will store at %s, query %s %s %s %s):\n%s\n"+
 			"Partition data (will store at %s, query %s):\n%s\n",
-			paths[1], config.BrokerQuery, brokerData,
+			paths[1], config.BrokerQuery, config.BrokerTotalQuery,
+			config.LogDirQuery, config.LogDirTotalQuery, brokerData,
 			paths[0], config.PartnQuery, partnData)
 	}
 
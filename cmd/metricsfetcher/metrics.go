@@ -1,20 +1,52 @@
 package main
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
 )
 
 func partitionMetrics(c *Config) (map[string]map[string]map[string]float64, error) {
+	d := map[string]map[string]map[string]float64{}
+
+	// Partition throughput queries
+	// are optional.
+	queries := []struct {
+		query string
+		field string
+	}{
+		{c.PartnQuery, "Size"},
+		{c.PartnBytesInQuery, "BytesIn"},
+		{c.PartnBytesOutQuery, "BytesOut"},
+	}
+
+	for _, q := range queries {
+		if q.query == "" {
+			continue
+		}
+
+		fmt.Printf("Submitting %s\n", q.query)
+		if err := partitionMetric(c, q.query, q.field, d); err != nil {
+			return nil, err
+		}
+		fmt.Println("success")
+	}
+
+	return d, nil
+}
+
+// partitionMetric runs a partition metric query and stores
+// the results in d as the field for each topic, partition.
+func partitionMetric(c *Config, query, field string, d map[string]map[string]map[string]float64) error {
 	start := time.Now().Add(-time.Duration(c.Span) * time.Second).Unix()
-	o, err := c.Client.QueryMetrics(start, time.Now().Unix(), c.PartnQuery)
+	o, err := c.Client.QueryMetrics(start, time.Now().Unix(), query)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	d := map[string]map[string]map[string]float64{}
-
 	for _, ts := range o {
 		topic := tagValFromScope(ts.GetScope(), "topic")
 		// Cope with the double underscore
@@ -29,23 +61,73 @@ func partitionMetrics(c *Config) (map[string]map[string]map[string]float64, erro
 			d[topic] = map[string]map[string]float64{}
 		}
 
-		d[topic][partition] = map[string]float64{}
-		d[topic][partition]["Size"] = *ts.Points[0][1]
+		if _, exists := d[topic][partition]; !exists {
+			d[topic][partition] = map[string]float64{}
+		}
+
+		d[topic][partition][field] = *ts.Points[0][1]
+	}
+
+	return nil
+}
+
+// brokerMetricSetter sets a metric value v for the log dir
+// (if the metric is per log dir) in the *BrokerMetrics.
+type brokerMetricSetter func(m *kafkazk.BrokerMetrics, dir string, v float64)
+
+func brokerMetrics(c *Config) (map[string]*kafkazk.BrokerMetrics, error) {
+	d := map[string]*kafkazk.BrokerMetrics{}
+
+	// The broker storage total and
+	// log dir queries are optional.
+	queries := []struct {
+		query string
+		set   brokerMetricSetter
+	}{
+		{c.BrokerQuery, func(m *kafkazk.BrokerMetrics, _ string, v float64) { m.StorageFree = v }},
+		{c.BrokerTotalQuery, func(m *kafkazk.BrokerMetrics, _ string, v float64) { m.StorageTotal = v }},
+		{c.LogDirQuery, func(m *kafkazk.BrokerMetrics, dir string, v float64) { logDirMetrics(m, dir).StorageFree = v }},
+		{c.LogDirTotalQuery, func(m *kafkazk.BrokerMetrics, dir string, v float64) { logDirMetrics(m, dir).StorageTotal = v }},
+	}
+
+	for _, q := range queries {
+		if q.query == "" {
+			continue
+		}
+
+		fmt.Printf("Submitting %s\n", q.query)
+		if err := brokerMetric(c, q.query, q.set, d); err != nil {
+			return nil, err
+		}
+		fmt.Println("success")
 	}
 
 	return d, nil
 }
 
-func brokerMetrics(c *Config) (map[string]map[string]float64, error) {
+// logDirMetrics returns the *LogDirMeta for the log
+// dir in the *BrokerMetrics, creating it if needed.
+func logDirMetrics(m *kafkazk.BrokerMetrics, dir string) *kafkazk.LogDirMeta {
+	if m.LogDirs == nil {
+		m.LogDirs = kafkazk.LogDirMetaMap{}
+	}
+
+	if _, exists := m.LogDirs[dir]; !exists {
+		m.LogDirs[dir] = &kafkazk.LogDirMeta{}
+	}
+
+	return m.LogDirs[dir]
+}
+
+// brokerMetric runs a broker metric query and stores
+// the results in d using the brokerMetricSetter.
+func brokerMetric(c *Config, query string, set brokerMetricSetter, d map[string]*kafkazk.BrokerMetrics) error {
 	start := time.Now().Add(-time.Duration(c.Span) * time.Second).Unix()
-	o, err := c.Client.QueryMetrics(start, time.Now().Unix(), c.BrokerQuery)
+	o, err := c.Client.QueryMetrics(start, time.Now().Unix(), query)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	// Populate.
-	d := map[string]map[string]float64{}
-
 	for _, ts := range o {
 		broker := tagValFromScope(ts.GetScope(), c.BrokerIDTag)
 
@@ -59,13 +141,21 @@ func brokerMetrics(c *Config) (map[string]map[string]float64, error) {
 		}
 
 		if _, exists := d[broker]; !exists {
-			d[broker] = map[string]float64{}
+			d[broker] = &kafkazk.BrokerMetrics{}
 		}
 
-		d[broker]["StorageFree"] = *ts.Points[0][1]
+		set(d[broker], tagValFromScope(ts.GetScope(), c.LogDirTag), *ts.Points[0][1])
+
+		// The instance type is only grouped
+		// on the broker storage query.
+		if c.InstanceTypeTag != "" {
+			if it := tagValFromScope(ts.GetScope(), c.InstanceTypeTag); it != "" && it != "N/A" {
+				d[broker].InstanceType = it
+			}
+		}
 	}
 
-	return d, nil
+	return nil
 }
 
 // tagValFromScope takes a metric scope string
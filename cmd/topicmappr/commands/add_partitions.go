@@ -0,0 +1,178 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+var addPartitionsCmd = &cobra.Command{
+	Use:   "add-partitions",
+	Short: "Place new partitions for a topic under rack ID and storage constraints",
+	Long: `add-partitions builds replica placements for the number of new partitions
+provided via --count for the topic provided via --topic. New partitions are placed
+on the brokers provided via the --brokers parameter under rack ID and storage
+constraints, balanced against the current load of the topic (and the current storage
+free of each broker when using storage placement). New partitions are assumed to
+grow to the mean size and throughput of the existing partitions. Existing partitions
+are not moved.
+
+The full assignment of existing and new partitions is written as a partition map
+and in the format of the kafka-topics --replica-assignment flag, which can be
+applied with:
+
+  kafka-topics --alter --topic <topic> --partitions <total> --replica-assignment <assignment>
+
+Kafka does not support removing partitions.`,
+	Run: addPartitions,
+}
+
+func init() {
+	rootCmd.AddCommand(addPartitionsCmd)
+
+	addPartitionsCmd.Flags().String("topic", "", "Topic to add partitions to")
+	addPartitionsCmd.Flags().Int("count", 0, "Number of partitions to add")
+	addPartitionsCmd.Flags().Int("replication", 0, "Replication factor of new partitions (0 uses the most common replication factor of the topic)")
+	addPartitionsCmd.Flags().String("brokers", "", "Broker list to place new partitions on ('-1' for all currently mapped brokers, '-2' for all brokers in cluster)")
+	addPartitionsCmd.Flags().String("out-path", "", "Path to write output map files to")
+	addPartitionsCmd.Flags().String("placement", "count", "Partition placement strategy: [count, storage, throughput]")
+	addPartitionsCmd.Flags().String("optimize", "distribution", "Optimization priority for the storage placement strategy: [distribution, storage]")
+	addPartitionsCmd.Flags().Bool("rack-leader-spread", false, "Spread partition leaders evenly across rack IDs")
+	addPartitionsCmd.Flags().Int("min-rack-ids", 0, "Minimum number of required of unique rack IDs per replica set (0 requires that all are unique)")
+	addPartitionsCmd.Flags().Float64("partition-size-factor", 1.0, "Factor by which to multiply partition sizes when using storage or throughput placement")
+	addPartitionsCmd.Flags().String("zk-metrics-prefix", "topicmappr", "ZooKeeper namespace prefix for Kafka metrics (when using storage or throughput placement)")
+	addPartitionsCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes) (when using storage or throughput placement)")
+
+	// Required.
+	addPartitionsCmd.MarkFlagRequired("topic")
+	addPartitionsCmd.MarkFlagRequired("count")
+	addPartitionsCmd.MarkFlagRequired("brokers")
+}
+
+func addPartitions(cmd *cobra.Command, _ []string) {
+	topic := cmd.Flag("topic").Value.String()
+	n, _ := cmd.Flags().GetInt("count")
+	r, _ := cmd.Flags().GetInt("replication")
+	p := cmd.Flag("placement").Value.String()
+	o := cmd.Flag("optimize").Value.String()
+
+	switch {
+	case n < 1:
+		fmt.Println("\n[ERROR] --count must be > 0")
+		defaultsAndExit()
+	case r < 0:
+		fmt.Println("\n[ERROR] --replication must be >= 0")
+		defaultsAndExit()
+	case p != "count" && p != "storage" && p != "throughput":
+		fmt.Println("\n[ERROR] --placement must be one of 'count', 'storage' or 'throughput'")
+		defaultsAndExit()
+	case o != "distribution" && o != "storage":
+		fmt.Println("\n[ERROR] --optimize must be either 'distribution' or 'storage'")
+		defaultsAndExit()
+	}
+
+	bootstrap(cmd)
+
+	// ZooKeeper init.
+	zk, err := initZooKeeper(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	defer zk.Close()
+
+	// Get broker and partition metadata.
+	withMetrics := usesStorageMetrics(cmd)
+	if withMetrics {
+		checkMetaAge(cmd, zk)
+	}
+
+	brokerMeta := getBrokerMeta(cmd, zk, withMetrics)
+
+	var partitionMeta kafkazk.PartitionMetaMap
+	if withMetrics {
+		partitionMeta = getPartitionMeta(cmd, zk)
+	}
+
+	// Get the current partition map.
+	partitionMapIn, err := zk.GetPartitionMap(topic)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if r == 0 {
+		r = majorityReplication(partitionMapIn)
+	}
+
+	// Build a map of stub partitions to be placed.
+	newMap := newPartitions(partitionMapIn, topic, n, r)
+
+	// The broker map includes the existing partitions
+	// so that placements are balanced against them.
+	combined := partitionMapIn.Copy()
+	combined.Partitions = append(combined.Partitions, newMap.Partitions...)
+
+	brokers, bs := getBrokers(cmd, combined, brokerMeta)
+	brokersOrig := brokers.Copy()
+
+	if bs.Changes() {
+		fmt.Printf("%s-\n", indent)
+	}
+
+	if withMetrics {
+		ensureBrokerMetrics(cmd, brokers, brokerMeta)
+		partitionMeta = estimatePartitionMeta(partitionMeta, partitionMapIn, newMap)
+	}
+
+	fmt.Printf("\nAction:\n")
+	fmt.Printf("%sAdding %d partition(s) with replication factor %d to %s\n", indent, n, r, topic)
+
+	// Place the new partitions.
+	newMapOut, errs := buildMap(cmd, newMap, partitionMeta, brokers, nil, nil, nil, getClusterMap(cmd, zk))
+
+	sort.Sort(newMapOut.Partitions)
+
+	partitionMapOut := partitionMapIn.Copy()
+	partitionMapOut.Partitions = append(partitionMapOut.Partitions, newMapOut.Partitions...)
+
+	// Print new partition placements.
+	fmt.Println("\nNew partitions:")
+	for _, partn := range newMapOut.Partitions {
+		fmt.Printf("%s%s p%d: %v\n", indent, partn.Topic, partn.Partition, partn.Replicas)
+	}
+
+	// Print broker assignment statistics.
+	errs = append(errs, printBrokerAssignmentStats(cmd, partitionMapIn, partitionMapOut, brokersOrig, brokers)...)
+
+	// Print error/warnings.
+	handleOverridableErrs(cmd, errs)
+
+	assignment := replicaAssignment(partitionMapOut)
+	outPath := cmd.Flag("out-path").Value.String()
+
+	fmt.Println("\nNew partition maps:")
+
+	if err := kafkazk.WriteMap(partitionMapOut, outPath+topic); err != nil {
+		fmt.Printf("%s%s\n", indent, err)
+	} else {
+		fmt.Printf("%s%s%s.json\n", indent, outPath, topic)
+	}
+
+	path := fmt.Sprintf("%s%s-replica-assignment.txt", outPath, topic)
+	if err := ioutil.WriteFile(path, []byte(assignment+"\n"), 0644); err != nil {
+		fmt.Printf("%s%s\n", indent, err)
+	} else {
+		fmt.Printf("%s%s\n", indent, path)
+	}
+
+	fmt.Println("\nApply with:")
+	fmt.Printf("%skafka-topics --alter --topic %s --partitions %d --replica-assignment %s\n",
+		indent, topic, len(partitionMapOut.Partitions), assignment)
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+// majorityReplication returns the most common replication factor
+// in the *PartitionMap. Ties are broken by the greater replication
+// factor.
+func majorityReplication(pm *kafkazk.PartitionMap) int {
+	counts := map[int]int{}
+	for _, partn := range pm.Partitions {
+		counts[len(partn.Replicas)]++
+	}
+
+	var rf int
+	for n, count := range counts {
+		if count > counts[rf] || (count == counts[rf] && n > rf) {
+			rf = n
+		}
+	}
+
+	return rf
+}
+
+// newPartitions takes the current *PartitionMap of a topic, a partition
+// count n and replication factor r and returns a *PartitionMap of n
+// partitions numbered after the existing partitions with all replicas
+// set to the stub broker ID.
+func newPartitions(pm *kafkazk.PartitionMap, topic string, n, r int) *kafkazk.PartitionMap {
+	next := 0
+	for _, partn := range pm.Partitions {
+		if partn.Partition >= next {
+			next = partn.Partition + 1
+		}
+	}
+
+	newPM := kafkazk.NewPartitionMap(kafkazk.Populate(topic, n, r))
+	for i := range newPM.Partitions {
+		newPM.Partitions[i].Partition += next
+	}
+
+	return newPM
+}
+
+// estimatePartitionMeta takes a PartitionMetaMap, the current *PartitionMap
+// of a topic and a *PartitionMap of new partitions and returns a copy of
+// the PartitionMetaMap where each new partition is assigned the mean size
+// and throughput of the existing partitions. Existing partitions without
+// metadata are excluded from the mean.
+func estimatePartitionMeta(pmm kafkazk.PartitionMetaMap, pm, newPM *kafkazk.PartitionMap) kafkazk.PartitionMetaMap {
+	out := kafkazk.NewPartitionMetaMap()
+	for t, partns := range pmm {
+		out[t] = map[int]*kafkazk.PartitionMeta{}
+		for p, m := range partns {
+			meta := *m
+			out[t][p] = &meta
+		}
+	}
+
+	mean := kafkazk.PartitionMeta{}
+	var n float64
+	for _, partn := range pm.Partitions {
+		if m, exist := pmm[partn.Topic][partn.Partition]; exist {
+			mean.Size += m.Size
+			mean.BytesIn += m.BytesIn
+			mean.BytesOut += m.BytesOut
+			n++
+		}
+	}
+
+	if n > 0 {
+		mean.Size /= n
+		mean.BytesIn /= n
+		mean.BytesOut /= n
+	}
+
+	for _, partn := range newPM.Partitions {
+		if _, exist := out[partn.Topic]; !exist {
+			out[partn.Topic] = map[int]*kafkazk.PartitionMeta{}
+		}
+		meta := mean
+		out[partn.Topic][partn.Partition] = &meta
+	}
+
+	return out
+}
+
+// replicaAssignment returns the *PartitionMap in the format of the
+// kafka-topics --replica-assignment flag: a comma delimited list of
+// colon delimited replica sets ordered by partition number.
+func replicaAssignment(pm *kafkazk.PartitionMap) string {
+	pl := append(kafkazk.PartitionList{}, pm.Partitions...)
+	sort.Sort(pl)
+
+	var sets []string
+	for _, partn := range pl {
+		ids := make([]string, len(partn.Replicas))
+		for i, id := range partn.Replicas {
+			ids[i] = strconv.Itoa(id)
+		}
+		sets = append(sets, strings.Join(ids, ":"))
+	}
+
+	return strings.Join(sets, ",")
+}
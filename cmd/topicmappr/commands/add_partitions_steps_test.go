@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func testAddPartitionsMap() *kafkazk.PartitionMap {
+	pm, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":1,"replicas":[1002,1003]},
+		{"topic":"test_topic","partition":2,"replicas":[1003,1001,1002]}]}`)
+
+	return pm
+}
+
+func TestMajorityReplication(t *testing.T) {
+	if r := majorityReplication(testAddPartitionsMap()); r != 2 {
+		t.Errorf("Expected replication factor 2, got %d", r)
+	}
+}
+
+func TestNewPartitions(t *testing.T) {
+	newPM := newPartitions(testAddPartitionsMap(), "test_topic", 2, 3)
+
+	if len(newPM.Partitions) != 2 {
+		t.Fatalf("Expected 2 partitions, got %d", len(newPM.Partitions))
+	}
+
+	for i, partn := range newPM.Partitions {
+		if partn.Partition != i+3 {
+			t.Errorf("Expected partition %d, got %d", i+3, partn.Partition)
+		}
+
+		if len(partn.Replicas) != 3 || partn.Replicas[0] != kafkazk.StubBrokerID {
+			t.Errorf("Expected 3 stub replicas, got %v", partn.Replicas)
+		}
+	}
+}
+
+func TestEstimatePartitionMeta(t *testing.T) {
+	pm := testAddPartitionsMap()
+	newPM := newPartitions(pm, "test_topic", 1, 2)
+
+	pmm := kafkazk.NewPartitionMetaMap()
+	pmm["test_topic"] = map[int]*kafkazk.PartitionMeta{
+		0: &kafkazk.PartitionMeta{Size: 100, BytesIn: 10},
+		1: &kafkazk.PartitionMeta{Size: 300, BytesIn: 30},
+	}
+
+	out := estimatePartitionMeta(pmm, pm, newPM)
+
+	m := out["test_topic"][3]
+	if m == nil || m.Size != 200 || m.BytesIn != 20 {
+		t.Errorf("Expected size 200, bytes in 20, got %+v", m)
+	}
+
+	// The input isn't modified.
+	if _, exist := pmm["test_topic"][3]; exist {
+		t.Errorf("Unexpected input modification")
+	}
+}
+
+func TestReplicaAssignment(t *testing.T) {
+	expected := "1001:1002,1002:1003,1003:1001:1002"
+	if a := replicaAssignment(testAddPartitionsMap()); a != expected {
+		t.Errorf("Expected %s, got %s", expected, a)
+	}
+}
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+// parseAntiAffinityGroups takes a semicolon delimited list of anti-affinity
+// groups, each a comma delimited list of topic names and/or regex patterns,
+// and returns the compiled regexes for each group.
+func parseAntiAffinityGroups(s string) ([][]*regexp.Regexp, error) {
+	var groups [][]*regexp.Regexp
+
+	for _, g := range strings.Split(s, ";") {
+		if strings.TrimSpace(g) == "" {
+			continue
+		}
+
+		var group []*regexp.Regexp
+		for _, t := range strings.Split(g, ",") {
+			t = strings.TrimSpace(t)
+			if t == "" {
+				continue
+			}
+
+			if !containsRegex(t) {
+				t = fmt.Sprintf(`^%s$`, t)
+			}
+
+			r, err := regexp.Compile(t)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid anti-affinity topic regex: %s", t)
+			}
+
+			group = append(group, r)
+		}
+
+		if len(group) < 2 {
+			return nil, fmt.Errorf("Anti-affinity group '%s' must reference at least two topics", g)
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// antiAffinities takes anti-affinity group regexes, the *PartitionMap being
+// rebuilt and a kafkazk.Handler and returns a *kafkazk.TopicAntiAffinities.
+// Group regexes are matched against topics in the map and in ZooKeeper;
+// placements for grouped topics not in the map are looked up in ZooKeeper.
+func antiAffinities(groups [][]*regexp.Regexp, pm *kafkazk.PartitionMap, zk kafkazk.Handler) (*kafkazk.TopicAntiAffinities, error) {
+	if zk == nil {
+		return nil, fmt.Errorf("--anti-affinity-groups requires a ZooKeeper connection")
+	}
+
+	inMap := map[string]bool{}
+	for _, t := range pm.Topics() {
+		inMap[t] = true
+	}
+
+	var topicGroups [][]string
+	external := map[string]bool{}
+
+	for _, g := range groups {
+		topics, err := zk.GetTopics(g)
+		if err != nil {
+			return nil, err
+		}
+
+		members := map[string]bool{}
+		for _, t := range topics {
+			members[t] = true
+			if !inMap[t] {
+				external[t] = true
+			}
+		}
+
+		// Topics provided via --map-string
+		// may not exist in ZooKeeper.
+		for t := range inMap {
+			for _, r := range g {
+				if r.MatchString(t) {
+					members[t] = true
+				}
+			}
+		}
+
+		var names []string
+		for t := range members {
+			names = append(names, t)
+		}
+
+		sort.Strings(names)
+		topicGroups = append(topicGroups, names)
+	}
+
+	aa := kafkazk.NewTopicAntiAffinities(topicGroups)
+
+	var sorted []string
+	for t := range external {
+		sorted = append(sorted, t)
+	}
+
+	sort.Strings(sorted)
+
+	for _, t := range sorted {
+		m, err := zk.GetPartitionMap(t)
+		if err != nil {
+			return nil, err
+		}
+		aa.Set(m)
+	}
+
+	aa.Set(pm)
+
+	return aa, nil
+}
+
+// antiAffinityViolations takes a *PartitionMap and *kafkazk.TopicAntiAffinities
+// and returns an error for each broker in a partition's replica set that
+// hosts a topic sharing an anti-affinity group with the partition topic.
+func antiAffinityViolations(pm *kafkazk.PartitionMap, aa *kafkazk.TopicAntiAffinities) errors {
+	if aa == nil {
+		return nil
+	}
+
+	aa = aa.Copy()
+	aa.Set(pm)
+
+	var errs errors
+
+	for _, partn := range pm.Partitions {
+		for _, id := range partn.Replicas {
+			if c := aa.Conflict(partn.Topic, id); c != "" {
+				errs = append(errs, fmt.Errorf("%s p%d: broker %d hosts anti-affinity topic %s",
+					partn.Topic, partn.Partition, id, c))
+			}
+		}
+	}
+
+	return errs
+}
+
+// getAntiAffinities returns a *kafkazk.TopicAntiAffinities from the
+// --anti-affinity-groups flag, if set. A nil *kafkazk.TopicAntiAffinities
+// is returned if no groups are set.
+func getAntiAffinities(cmd *cobra.Command, pm *kafkazk.PartitionMap, zk kafkazk.Handler) *kafkazk.TopicAntiAffinities {
+	s := cmd.Flag("anti-affinity-groups").Value.String()
+	if s == "" {
+		return nil
+	}
+
+	groups, err := parseAntiAffinityGroups(s)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	aa, err := antiAffinities(groups, pm, zk)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	return aa
+}
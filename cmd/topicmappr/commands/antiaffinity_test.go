@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func TestParseAntiAffinityGroups(t *testing.T) {
+	groups, err := parseAntiAffinityGroups("test_topic,test_topic2; test_topic3,test_.*4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(groups))
+	}
+
+	expected := [][]string{{"^test_topic$", "^test_topic2$"}, {"^test_topic3$", "test_.*4"}}
+	for i, g := range groups {
+		for j, r := range g {
+			if r.String() != expected[i][j] {
+				t.Errorf("Expected regex '%s', got '%s'", expected[i][j], r)
+			}
+		}
+	}
+
+	for _, s := range []string{"test_topic", "test_topic,(", "a,b;c"} {
+		if _, err := parseAntiAffinityGroups(s); err == nil {
+			t.Errorf("Expected non-nil error for '%s'", s)
+		}
+	}
+}
+
+func TestAntiAffinityViolations(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm, _ := zk.GetPartitionMap("test_topic")
+
+	groups, _ := parseAntiAffinityGroups("test_topic,test_topic2")
+	aa, err := antiAffinities(groups, pm, zk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The mock test_topic2 map shares all brokers.
+	if c := aa.Conflict("test_topic", 1001); c != "test_topic2" {
+		t.Errorf("Expected conflict 'test_topic2', got '%s'", c)
+	}
+
+	if errs := antiAffinityViolations(pm, aa); len(errs) != 10 {
+		t.Errorf("Expected 10 violations, got %d", len(errs))
+	}
+
+	// Move test_topic off of all test_topic2 brokers.
+	for i := range pm.Partitions {
+		pm.Partitions[i].Replicas = []int{1005, 1006}
+	}
+
+	if errs := antiAffinityViolations(pm, aa); len(errs) != 0 {
+		t.Errorf("Unexpected violations: %v", errs)
+	}
+
+	if errs := antiAffinityViolations(pm, nil); errs != nil {
+		t.Errorf("Unexpected violations: %v", errs)
+	}
+}
@@ -0,0 +1,230 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+// batchParams holds the limits used to split
+// a reassignment into ordered batches. A zero
+// value for any limit disables that limit.
+type batchParams struct {
+	// Partition moves per batch.
+	partitions int
+	// Bytes replicated per batch.
+	bytes float64
+	// Concurrent moves per broker per batch.
+	perBroker int
+}
+
+// enabled returns whether any batch limits are set.
+func (b batchParams) enabled() bool {
+	return b.partitions > 0 || b.bytes > 0 || b.perBroker > 0
+}
+
+// partitionMove describes a single partition reassignment.
+type partitionMove struct {
+	partition kafkazk.Partition
+	// Brokers gaining or losing a replica.
+	brokers []int
+	// Bytes to be replicated to new replicas.
+	bytes float64
+}
+
+// reassignmentBatch is an accumulator
+// for a batch of partitionMoves.
+type reassignmentBatch struct {
+	moves     []partitionMove
+	bytes     float64
+	perBroker map[int]int
+}
+
+// fits returns whether the partitionMove can be added
+// to the reassignmentBatch without exceeding the batchParams.
+// An empty batch always accepts a move to ensure that moves
+// exceeding the limits on their own are still scheduled.
+func (r *reassignmentBatch) fits(m partitionMove, p batchParams) bool {
+	if len(r.moves) == 0 {
+		return true
+	}
+
+	switch {
+	case p.partitions > 0 && len(r.moves)+1 > p.partitions:
+		return false
+	case p.bytes > 0 && r.bytes+m.bytes > p.bytes:
+		return false
+	}
+
+	if p.perBroker > 0 {
+		for _, id := range m.brokers {
+			if r.perBroker[id]+1 > p.perBroker {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// add adds a partitionMove to the reassignmentBatch.
+func (r *reassignmentBatch) add(m partitionMove) {
+	r.moves = append(r.moves, m)
+	r.bytes += m.bytes
+
+	for _, id := range m.brokers {
+		r.perBroker[id]++
+	}
+}
+
+// maxPerBroker returns the greatest number of concurrent
+// moves for any single broker in the reassignmentBatch.
+func (r *reassignmentBatch) maxPerBroker() int {
+	var max int
+	for _, n := range r.perBroker {
+		if n > max {
+			max = n
+		}
+	}
+
+	return max
+}
+
+// getBatchParams returns the batchParams
+// configured via the --batch-* flags.
+func getBatchParams(cmd *cobra.Command) batchParams {
+	bp, _ := cmd.Flags().GetInt("batch-partitions")
+	bgb, _ := cmd.Flags().GetFloat64("batch-size-gb")
+	bbc, _ := cmd.Flags().GetInt("batch-broker-concurrency")
+
+	return batchParams{
+		partitions: bp,
+		bytes:      bgb * div,
+		perBroker:  bbc,
+	}
+}
+
+// partitionMoves takes the original and new PartitionMaps and returns
+// a partitionMove for each partition that has changed. If a non-nil
+// PartitionMetaMap is provided, the bytes to be replicated is populated
+// for each partitionMove.
+func partitionMoves(pm1, pm2 *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap) ([]partitionMove, error) {
+	var moves []partitionMove
+
+	for i := range pm1.Partitions {
+		p1, p2 := pm1.Partitions[i], pm2.Partitions[i]
+		if p1.Equal(p2) {
+			continue
+		}
+
+		before, after := map[int]struct{}{}, map[int]struct{}{}
+		for _, id := range p1.Replicas {
+			before[id] = struct{}{}
+		}
+		for _, id := range p2.Replicas {
+			after[id] = struct{}{}
+		}
+
+		move := partitionMove{partition: p2}
+
+		// Brokers receiving a replica.
+		var added int
+		for _, id := range p2.Replicas {
+			if _, exist := before[id]; !exist {
+				move.brokers = append(move.brokers, id)
+				added++
+			}
+		}
+
+		// Brokers dropping a replica.
+		for _, id := range p1.Replicas {
+			if _, exist := after[id]; !exist {
+				move.brokers = append(move.brokers, id)
+			}
+		}
+
+		if pmm != nil && added > 0 {
+			size, err := pmm.Size(p2)
+			if err != nil {
+				return nil, err
+			}
+			move.bytes = size * float64(added)
+		}
+
+		moves = append(moves, move)
+	}
+
+	return moves, nil
+}
+
+// batchReassignment takes a list of partitionMoves and batchParams and
+// returns ordered batches of moves. Moves are considered in order and
+// are added to the current batch if they fit within the configured
+// limits; moves that don't fit are deferred to a subsequent batch.
+func batchReassignment(moves []partitionMove, p batchParams) []*reassignmentBatch {
+	var batches []*reassignmentBatch
+
+	remaining := moves
+	for len(remaining) > 0 {
+		batch := &reassignmentBatch{perBroker: map[int]int{}}
+		var deferred []partitionMove
+
+		for _, m := range remaining {
+			if batch.fits(m, p) {
+				batch.add(m)
+			} else {
+				deferred = append(deferred, m)
+			}
+		}
+
+		batches = append(batches, batch)
+		remaining = deferred
+	}
+
+	return batches
+}
+
+// getBatches takes the original and new PartitionMaps along with
+// a PartitionMetaMap and returns the new PartitionMap split into
+// ordered batches according to the --batch-* flags. If batching
+// isn't configured, nil is returned.
+func getBatches(cmd *cobra.Command, pm1, pm2 *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap) []*kafkazk.PartitionMap {
+	params := getBatchParams(cmd)
+	if !params.enabled() {
+		return nil
+	}
+
+	moves, err := partitionMoves(pm1, pm2, pmm)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	batches := batchReassignment(moves, params)
+
+	fmt.Println("\nReassignment batches:")
+
+	if len(batches) == 0 {
+		fmt.Printf("%s[none]\n", indent)
+	}
+
+	var maps []*kafkazk.PartitionMap
+	for i, b := range batches {
+		pm := kafkazk.NewPartitionMap()
+		for _, m := range b.moves {
+			pm.Partitions = append(pm.Partitions, m.partition)
+		}
+
+		sort.Sort(pm.Partitions)
+		maps = append(maps, pm)
+
+		fmt.Printf("%sbatch%d: %d partitions, %.2fGB, max %d moves per broker\n",
+			indent, i+1, len(b.moves), b.bytes/div, b.maxPerBroker())
+	}
+
+	return maps
+}
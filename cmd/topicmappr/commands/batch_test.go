@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func TestPartitionMoves(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm1, _ := zk.GetPartitionMap("test_topic")
+	pmm, _ := zk.GetAllPartitionMeta()
+
+	pm2 := pm1.Copy()
+	// Replace a broker.
+	pm2.Partitions[0].Replicas = []int{1001, 1005}
+	// Change the preferred leader.
+	pm2.Partitions[1].Replicas = []int{1001, 1002}
+
+	moves, err := partitionMoves(pm1, pm2, pmm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(moves) != 2 {
+		t.Fatalf("Expected 2 moves, got %d", len(moves))
+	}
+
+	// Partition 0: 1002 -> 1005.
+	if moves[0].bytes != 1000.00 {
+		t.Errorf("Expected 1000.00 bytes, got %.2f", moves[0].bytes)
+	}
+
+	if len(moves[0].brokers) != 2 {
+		t.Errorf("Expected 2 brokers involved, got %v", moves[0].brokers)
+	}
+
+	// Partition 1: leadership only.
+	if moves[1].bytes != 0.00 || len(moves[1].brokers) != 0 {
+		t.Errorf("Expected no data movement, got %.2f bytes, brokers %v",
+			moves[1].bytes, moves[1].brokers)
+	}
+}
+
+func TestBatchReassignment(t *testing.T) {
+	moves := []partitionMove{
+		partitionMove{brokers: []int{1001, 1002}, bytes: 100},
+		partitionMove{brokers: []int{1001, 1003}, bytes: 100},
+		partitionMove{brokers: []int{1004, 1005}, bytes: 300},
+		partitionMove{brokers: []int{1002, 1003}, bytes: 100},
+	}
+
+	// Partition limit.
+	batches := batchReassignment(moves, batchParams{partitions: 3})
+	if len(batches) != 2 {
+		t.Errorf("Expected 2 batches, got %d", len(batches))
+	}
+
+	// Bytes limit; the 300 byte move exceeds the
+	// limit alongside any other moves.
+	batches = batchReassignment(moves, batchParams{bytes: 300})
+	expected := []int{3, 1}
+	if len(batches) != len(expected) {
+		t.Fatalf("Expected %d batches, got %d", len(expected), len(batches))
+	}
+
+	for i, b := range batches {
+		if len(b.moves) != expected[i] {
+			t.Errorf("Batch %d: expected %d moves, got %d", i+1, expected[i], len(b.moves))
+		}
+	}
+
+	// Per-broker limit.
+	batches = batchReassignment(moves, batchParams{perBroker: 1})
+	expected = []int{2, 1, 1}
+	if len(batches) != len(expected) {
+		t.Fatalf("Expected %d batches, got %d", len(expected), len(batches))
+	}
+
+	for i, b := range batches {
+		if len(b.moves) != expected[i] {
+			t.Errorf("Batch %d: expected %d moves, got %d", i+1, expected[i], len(b.moves))
+		}
+
+		if b.maxPerBroker() > 1 {
+			t.Errorf("Batch %d: expected at most 1 move per broker, got %d", i+1, b.maxPerBroker())
+		}
+	}
+}
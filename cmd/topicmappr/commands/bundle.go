@@ -0,0 +1,503 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var errBundleReadOnly = fmt.Errorf("bundles are read-only")
+
+// Flags that aren't saved to bundles.
+var bundleSkipFlags = map[string]bool{
+	"out-path":    true,
+	"out-file":    true,
+	"save-bundle": true,
+	"from-bundle": true,
+	"zk-addr":     true,
+	"zk-prefix":   true,
+	"snapshot":    true,
+	"help":        true,
+}
+
+// bundle holds every input used to generate a plan. A bundle is
+// written to a directory with --save-bundle and replayed offline
+// with --from-bundle.
+type bundle struct {
+	Manifest      bundleManifest
+	BrokerMeta    kafkazk.BrokerMetaMap
+	BrokerMetrics kafkazk.BrokerMetricsMap
+	PartitionMeta kafkazk.PartitionMetaMap
+	PartitionMap  *kafkazk.PartitionMap
+}
+
+// bundleManifest describes a bundle along with the flags
+// and any ZooKeeper lookups that aren't stored in a
+// dedicated bundle file.
+type bundleManifest struct {
+	Command   string            `json:"command"`
+	Version   string            `json:"version"`
+	CreatedAt string            `json:"created_at"`
+	Flags     map[string]string `json:"flags"`
+	// Seed is the search optimizer seed. Rebuild placement
+	// seeds are derived from the inputs.
+	Seed            int64               `json:"seed"`
+	Topics          map[string][]string `json:"topics"`
+	PendingDeletion []string            `json:"pending_deletion"`
+	MetaAge         string              `json:"meta_age"`
+}
+
+// Bundle file names.
+const (
+	bundleManifestFile      = "bundle.json"
+	bundleBrokerMetaFile    = "brokermeta.json"
+	bundleBrokerMetricsFile = "brokermetrics.json"
+	bundlePartitionMetaFile = "partitionmeta.json"
+	bundlePartitionMapFile  = "partitionmap.json"
+)
+
+func newBundle() *bundle {
+	return &bundle{
+		Manifest: bundleManifest{
+			Flags:  map[string]string{},
+			Topics: map[string][]string{},
+		},
+		BrokerMeta:    kafkazk.BrokerMetaMap{},
+		BrokerMetrics: kafkazk.BrokerMetricsMap{},
+		PartitionMap:  kafkazk.NewPartitionMap(),
+	}
+}
+
+// topicsKey returns the bundle key for a GetTopics lookup.
+func topicsKey(ts []*regexp.Regexp) string {
+	var s []string
+	for _, t := range ts {
+		s = append(s, t.String())
+	}
+
+	return strings.Join(s, ",")
+}
+
+// bundleRecorder is a kafkazk.Handler that records all
+// responses used for planning into a bundle.
+type bundleRecorder struct {
+	kafkazk.Handler
+	b *bundle
+}
+
+// GetTopics records GetTopics.
+func (r *bundleRecorder) GetTopics(ts []*regexp.Regexp) ([]string, error) {
+	topics, err := r.Handler.GetTopics(ts)
+	if err == nil {
+		r.b.Manifest.Topics[topicsKey(ts)] = topics
+	}
+
+	return topics, err
+}
+
+// GetPartitionMap records GetPartitionMap.
+func (r *bundleRecorder) GetPartitionMap(t string) (*kafkazk.PartitionMap, error) {
+	pm, err := r.Handler.GetPartitionMap(t)
+	if err != nil {
+		return pm, err
+	}
+
+	// Topics may be looked up more than once.
+	for _, p := range r.b.PartitionMap.Partitions {
+		if p.Topic == t {
+			return pm, err
+		}
+	}
+
+	r.b.PartitionMap.Partitions = append(r.b.PartitionMap.Partitions, pm.Copy().Partitions...)
+
+	return pm, err
+}
+
+// GetAllBrokerMeta records GetAllBrokerMeta. Broker
+// metrics are recorded separately from the metadata.
+func (r *bundleRecorder) GetAllBrokerMeta(withMetrics bool) (kafkazk.BrokerMetaMap, []error) {
+	bmm, errs := r.Handler.GetAllBrokerMeta(withMetrics)
+
+	for id, m := range bmm {
+		meta := *m
+		meta.StorageFree, meta.StorageTotal, meta.MetricsIncomplete = 0, 0, false
+		meta.LogDirs, meta.InstanceType = nil, ""
+		r.b.BrokerMeta[id] = &meta
+
+		if withMetrics && !m.MetricsIncomplete {
+			r.b.BrokerMetrics[id] = &kafkazk.BrokerMetrics{
+				StorageFree:  m.StorageFree,
+				StorageTotal: m.StorageTotal,
+				LogDirs:      m.LogDirs.Copy(),
+				InstanceType: m.InstanceType,
+			}
+		}
+	}
+
+	return bmm, errs
+}
+
+// GetAllPartitionMeta records GetAllPartitionMeta.
+func (r *bundleRecorder) GetAllPartitionMeta() (kafkazk.PartitionMetaMap, error) {
+	pmm, err := r.Handler.GetAllPartitionMeta()
+	if err == nil {
+		r.b.PartitionMeta = pmm
+	}
+
+	return pmm, err
+}
+
+// MaxMetaAge records MaxMetaAge.
+func (r *bundleRecorder) MaxMetaAge() (time.Duration, error) {
+	age, err := r.Handler.MaxMetaAge()
+	if err == nil {
+		r.b.Manifest.MetaAge = age.String()
+	}
+
+	return age, err
+}
+
+// GetPendingDeletion records GetPendingDeletion.
+func (r *bundleRecorder) GetPendingDeletion() ([]string, error) {
+	pd, err := r.Handler.GetPendingDeletion()
+	if err == nil {
+		r.b.Manifest.PendingDeletion = pd
+	}
+
+	return pd, err
+}
+
+// recordBundle wraps the kafkazk.Handler with a
+// bundleRecorder if --save-bundle is set.
+func recordBundle(cmd *cobra.Command, zk kafkazk.Handler) kafkazk.Handler {
+	if f := cmd.Flag("save-bundle"); f == nil || f.Value.String() == "" {
+		return zk
+	}
+
+	return &bundleRecorder{Handler: zk, b: newBundle()}
+}
+
+// bundleReplay is a read-only kafkazk.Handler
+// that replays responses from a bundle.
+type bundleReplay struct {
+	b *bundle
+}
+
+// GetTopics replays GetTopics.
+func (r *bundleReplay) GetTopics(ts []*regexp.Regexp) ([]string, error) {
+	topics, exist := r.b.Manifest.Topics[topicsKey(ts)]
+	if !exist {
+		return nil, fmt.Errorf("No topics lookup for %s found in bundle", topicsKey(ts))
+	}
+
+	return topics, nil
+}
+
+// GetPartitionMap replays GetPartitionMap.
+func (r *bundleReplay) GetPartitionMap(t string) (*kafkazk.PartitionMap, error) {
+	pm := kafkazk.NewPartitionMap()
+	for _, p := range r.b.PartitionMap.Copy().Partitions {
+		if p.Topic == t {
+			pm.Partitions = append(pm.Partitions, p)
+		}
+	}
+
+	if len(pm.Partitions) == 0 {
+		return nil, fmt.Errorf("Topic %s not found in bundle", t)
+	}
+
+	return pm, nil
+}
+
+// GetAllBrokerMeta replays GetAllBrokerMeta.
+func (r *bundleReplay) GetAllBrokerMeta(withMetrics bool) (kafkazk.BrokerMetaMap, []error) {
+	var errs []error
+	bmm := kafkazk.BrokerMetaMap{}
+
+	for id, m := range r.b.BrokerMeta {
+		meta := *m
+		bmm[id] = &meta
+
+		if !withMetrics {
+			continue
+		}
+
+		if bm, exist := r.b.BrokerMetrics[id]; exist {
+			meta.StorageFree = bm.StorageFree
+			meta.StorageTotal = bm.StorageTotal
+			meta.LogDirs = bm.LogDirs.Copy()
+			meta.InstanceType = bm.InstanceType
+		} else {
+			errs = append(errs, fmt.Errorf("Metrics not found for broker %d", id))
+			meta.MetricsIncomplete = true
+		}
+	}
+
+	return bmm, errs
+}
+
+// GetAllPartitionMeta replays GetAllPartitionMeta.
+func (r *bundleReplay) GetAllPartitionMeta() (kafkazk.PartitionMetaMap, error) {
+	if r.b.PartitionMeta == nil {
+		return nil, fmt.Errorf("No partition meta found in bundle")
+	}
+
+	return r.b.PartitionMeta, nil
+}
+
+// MaxMetaAge replays MaxMetaAge.
+func (r *bundleReplay) MaxMetaAge() (time.Duration, error) {
+	if r.b.Manifest.MetaAge == "" {
+		return time.Nanosecond, fmt.Errorf("No metrics metadata age found in bundle")
+	}
+
+	return time.ParseDuration(r.b.Manifest.MetaAge)
+}
+
+// GetPendingDeletion replays GetPendingDeletion.
+func (r *bundleReplay) GetPendingDeletion() ([]string, error) {
+	return r.b.Manifest.PendingDeletion, nil
+}
+
+// Ready returns true.
+func (r *bundleReplay) Ready() bool { return true }
+
+// Close is a no-op.
+func (r *bundleReplay) Close() {}
+
+// Lookups that aren't recorded
+// in bundles return an error.
+
+// Exists returns an error.
+func (r *bundleReplay) Exists(string) (bool, error) { return false, errBundleReadOnly }
+
+// Create returns an error.
+func (r *bundleReplay) Create(string, string) error { return errBundleReadOnly }
+
+// CreateSequential returns an error.
+func (r *bundleReplay) CreateSequential(string, string) error { return errBundleReadOnly }
+
+// Set returns an error.
+func (r *bundleReplay) Set(string, string) error { return errBundleReadOnly }
+
+// Get returns an error.
+func (r *bundleReplay) Get(string) ([]byte, error) { return nil, errBundleReadOnly }
+
+// Delete returns an error.
+func (r *bundleReplay) Delete(string) error { return errBundleReadOnly }
+
+// Children returns an error.
+func (r *bundleReplay) Children(string) ([]string, error) { return nil, errBundleReadOnly }
+
+// GetTopicState returns an error.
+func (r *bundleReplay) GetTopicState(string) (*kafkazk.TopicState, error) {
+	return nil, errBundleReadOnly
+}
+
+// GetTopicStateISR returns an error.
+func (r *bundleReplay) GetTopicStateISR(string) (kafkazk.TopicStateISR, error) {
+	return nil, errBundleReadOnly
+}
+
+// UpdateKafkaConfig returns an error.
+func (r *bundleReplay) UpdateKafkaConfig(kafkazk.KafkaConfig) ([]bool, error) {
+	return nil, errBundleReadOnly
+}
+
+// GetReassignments returns no reassignments.
+func (r *bundleReplay) GetReassignments() kafkazk.Reassignments {
+	return kafkazk.Reassignments{}
+}
+
+// ListReassignments returns no reassignments.
+func (r *bundleReplay) ListReassignments() (kafkazk.Reassignments, error) {
+	return kafkazk.Reassignments{}, nil
+}
+
+// CreateReassignments returns an error.
+func (r *bundleReplay) CreateReassignments(*kafkazk.PartitionMap) error {
+	return errBundleReadOnly
+}
+
+// GetTopicConfig returns an error.
+func (r *bundleReplay) GetTopicConfig(string) (*kafkazk.TopicConfig, error) {
+	return nil, errBundleReadOnly
+}
+
+// writeBundle writes the bundle to the directory.
+func writeBundle(b *bundle, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := []struct {
+		name string
+		v    interface{}
+	}{
+		{bundleManifestFile, b.Manifest},
+		{bundleBrokerMetaFile, b.BrokerMeta},
+		{bundleBrokerMetricsFile, b.BrokerMetrics},
+		{bundlePartitionMetaFile, b.PartitionMeta},
+		{bundlePartitionMapFile, b.PartitionMap},
+	}
+
+	for _, f := range files {
+		out, err := json.MarshalIndent(f.v, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(dir, f.name), append(out, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readBundle reads a bundle from the directory.
+func readBundle(dir string) (*bundle, error) {
+	b := newBundle()
+
+	files := []struct {
+		name string
+		v    interface{}
+	}{
+		{bundleManifestFile, &b.Manifest},
+		{bundleBrokerMetaFile, &b.BrokerMeta},
+		{bundleBrokerMetricsFile, &b.BrokerMetrics},
+		{bundlePartitionMetaFile, &b.PartitionMeta},
+		{bundlePartitionMapFile, &b.PartitionMap},
+	}
+
+	for _, f := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.name))
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(data, f.v); err != nil {
+			return nil, fmt.Errorf("%s: %s", f.name, err)
+		}
+	}
+
+	return b, nil
+}
+
+// bundleFlags returns the values of all flags for the
+// command, excluding those not saved to bundles.
+func bundleFlags(cmd *cobra.Command) map[string]string {
+	flags := map[string]string{}
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if !bundleSkipFlags[f.Name] {
+			flags[f.Name] = f.Value.String()
+		}
+	})
+
+	return flags
+}
+
+// bundlePreRun applies the flags saved in a bundle if --from-bundle is
+// set. Flags explicitly set on the command line take precedence.
+func bundlePreRun(cmd *cobra.Command, _ []string) {
+	dir := cmd.Flag("from-bundle").Value.String()
+	if dir == "" {
+		return
+	}
+
+	b, err := readBundle(dir)
+	if err != nil {
+		fmt.Printf("Error reading bundle: %s\n", err)
+		os.Exit(1)
+	}
+
+	if b.Manifest.Command != cmd.Name() {
+		fmt.Printf("Bundle %s was saved by %s, not %s\n", dir, b.Manifest.Command, cmd.Name())
+		os.Exit(1)
+	}
+
+	for name, v := range b.Manifest.Flags {
+		f := cmd.Flags().Lookup(name)
+		if f == nil || f.Changed {
+			continue
+		}
+
+		// Saved files are relative to the bundle.
+		if name == "policy-file" && v != "" {
+			v = filepath.Join(dir, v)
+		}
+
+		if err := cmd.Flags().Set(name, v); err != nil {
+			fmt.Printf("Error applying bundle flag --%s: %s\n", name, err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("\nReplaying bundle %s (created at %s by topicmappr %s)\n",
+		dir, b.Manifest.CreatedAt, b.Manifest.Version)
+}
+
+// saveBundle writes a bundle of all inputs recorded by the
+// kafkazk.Handler along with the command flags if --save-bundle
+// is set. The kafkazk.Handler may be nil if ZooKeeper was not used.
+func saveBundle(cmd *cobra.Command, zk kafkazk.Handler) {
+	dir := cmd.Flag("save-bundle").Value.String()
+	if dir == "" {
+		return
+	}
+
+	b := newBundle()
+	if r, ok := zk.(*bundleRecorder); ok {
+		b = r.b
+	}
+
+	b.Manifest.Command = cmd.Name()
+	b.Manifest.Version = version
+	b.Manifest.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	b.Manifest.Flags = bundleFlags(cmd)
+
+	if f := cmd.Flags().Lookup("search-seed"); f != nil {
+		seed, _ := cmd.Flags().GetInt64("search-seed")
+		b.Manifest.Seed = seed
+	}
+
+	fmt.Println("\nBundle:")
+
+	// Copy the policy file into the bundle.
+	if p := b.Manifest.Flags["policy-file"]; p != "" {
+		name := "policy-file" + filepath.Ext(p)
+
+		data, err := ioutil.ReadFile(p)
+		if err == nil {
+			err = os.MkdirAll(dir, 0755)
+		}
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(dir, name), data, 0644)
+		}
+		if err != nil {
+			fmt.Printf("%s%s\n", indent, err)
+			return
+		}
+
+		b.Manifest.Flags["policy-file"] = name
+	}
+
+	if err := writeBundle(b, dir); err != nil {
+		fmt.Printf("%s%s\n", indent, err)
+		return
+	}
+
+	fmt.Printf("%s%s\n", indent, dir)
+}
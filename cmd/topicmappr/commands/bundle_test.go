@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func TestBundleRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "topicmappr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	zk := &kafkazk.Mock{}
+	r := &bundleRecorder{Handler: zk, b: newBundle()}
+
+	// Record lookups.
+	topics := []*regexp.Regexp{regexp.MustCompile("test_topic.*")}
+	pm, err := kafkazk.PartitionMapFromZK(topics, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bmm, _ := r.GetAllBrokerMeta(true)
+	pmm, _ := r.GetAllPartitionMeta()
+
+	if _, err := r.MaxMetaAge(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Metrics are stored separately.
+	if len(r.b.BrokerMetrics) != len(bmm) {
+		t.Errorf("Expected %d broker metrics, got %d", len(bmm), len(r.b.BrokerMetrics))
+	}
+
+	for id, m := range r.b.BrokerMeta {
+		if m.StorageFree != 0 {
+			t.Errorf("Expected no storage free in broker %d meta, got %f", id, m.StorageFree)
+		}
+	}
+
+	if err := writeBundle(r.b, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := readBundle(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay lookups.
+	replay := &bundleReplay{b: b}
+
+	pm2, err := kafkazk.PartitionMapFromZK(topics, replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Sort(pm.Partitions)
+	sort.Sort(pm2.Partitions)
+
+	if same, err := pm.Equal(pm2); !same {
+		t.Errorf("Unexpected map inequality: %s", err)
+	}
+
+	bmm2, errs := replay.GetAllBrokerMeta(true)
+	if len(errs) != 0 {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+
+	for id, m := range bmm {
+		m2, exist := bmm2[id]
+		if !exist || m2.Rack != m.Rack || m2.StorageFree != m.StorageFree {
+			t.Errorf("Expected broker %d meta %v, got %v", id, m, m2)
+		}
+	}
+
+	pmm2, _ := replay.GetAllPartitionMeta()
+	for i, p := range pmm["test_topic"] {
+		if pmm2["test_topic"][i].Size != p.Size {
+			t.Errorf("Expected partition %d size %f, got %f", i, p.Size, pmm2["test_topic"][i].Size)
+		}
+	}
+
+	if _, err := replay.GetPartitionMap("missing_topic"); err == nil {
+		t.Error("Expected error for missing topic")
+	}
+
+	if err := replay.Set("/path", "data"); err != errBundleReadOnly {
+		t.Errorf("Expected read-only error, got %v", err)
+	}
+
+	// Brokers without recorded metrics.
+	delete(b.BrokerMetrics, 1001)
+
+	bmm2, errs = replay.GetAllBrokerMeta(true)
+	if len(errs) != 1 || !bmm2[1001].MetricsIncomplete {
+		t.Errorf("Expected incomplete metrics for broker 1001")
+	}
+}
+
+func TestBundleFlags(t *testing.T) {
+	flags := bundleFlags(rebalanceCmd)
+
+	if flags["search-seed"] != "1" {
+		t.Errorf("Expected search-seed 1, got %s", flags["search-seed"])
+	}
+
+	for f := range bundleSkipFlags {
+		if _, exist := flags[f]; exist {
+			t.Errorf("Unexpected flag %s", f)
+		}
+	}
+}
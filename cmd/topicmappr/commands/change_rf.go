@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+var changeRFCmd = &cobra.Command{
+	Use:   "change-rf",
+	Short: "Change the replication factor of one or more topics",
+	Long: `change-rf sets the replication factor of the topics provided via the --topics
+parameter to the value provided via --replication. Increases add replicas to brokers
+chosen under rack ID and storage constraints from the brokers currently holding the
+topics along with any provided via --brokers; existing replicas are not moved.
+Decreases remove, one at a time, the follower whose removal best preserves the unique
+rack IDs of the replica set, preferring replicas on the brokers holding the most
+replicas. With --optimize-leadership, leaders may also be removed where that improves
+the partition leadership balance.`,
+	Run: changeRF,
+}
+
+func init() {
+	rootCmd.AddCommand(changeRFCmd)
+
+	changeRFCmd.Flags().String("topics", "", "Topics to change (comma delim. list) by lookup in ZooKeeper")
+	changeRFCmd.Flags().Int("replication", 0, "Target replication factor")
+	changeRFCmd.Flags().String("brokers", "", "Additional broker IDs eligible for added replicas (comma delim. list; '-2' for all brokers in cluster)")
+	changeRFCmd.Flags().String("out-path", "", "Path to write output map files to")
+	changeRFCmd.Flags().String("out-file", "", "If defined, write a combined map of all topics to a file")
+	changeRFCmd.Flags().String("placement", "count", "Partition placement strategy for added replicas: [count, storage, throughput]")
+	changeRFCmd.Flags().String("optimize", "distribution", "Optimization priority for the storage placement strategy: [distribution, storage]")
+	changeRFCmd.Flags().Bool("rack-leader-spread", false, "Spread partition leaders evenly across rack IDs")
+	changeRFCmd.Flags().Bool("optimize-leadership", false, "Allow replica removals to move partition leadership to balance broker leader counts")
+	changeRFCmd.Flags().Int("min-rack-ids", 0, "Minimum number of required of unique rack IDs per replica set (0 requires that all are unique)")
+	changeRFCmd.Flags().Float64("partition-size-factor", 1.0, "Factor by which to multiply partition sizes when using storage or throughput placement")
+	changeRFCmd.Flags().String("zk-metrics-prefix", "topicmappr", "ZooKeeper namespace prefix for Kafka metrics (when using storage or throughput placement)")
+	changeRFCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes) (when using storage or throughput placement)")
+
+	// Required.
+	changeRFCmd.MarkFlagRequired("topics")
+	changeRFCmd.MarkFlagRequired("replication")
+}
+
+func changeRF(cmd *cobra.Command, _ []string) {
+	r, _ := cmd.Flags().GetInt("replication")
+	p := cmd.Flag("placement").Value.String()
+	o := cmd.Flag("optimize").Value.String()
+
+	switch {
+	case r < 1:
+		fmt.Println("\n[ERROR] --replication must be > 0")
+		defaultsAndExit()
+	case p != "count" && p != "storage" && p != "throughput":
+		fmt.Println("\n[ERROR] --placement must be one of 'count', 'storage' or 'throughput'")
+		defaultsAndExit()
+	case o != "distribution" && o != "storage":
+		fmt.Println("\n[ERROR] --optimize must be either 'distribution' or 'storage'")
+		defaultsAndExit()
+	}
+
+	bootstrap(cmd)
+
+	// Brokers currently holding the
+	// topics are always eligible.
+	Config.brokers = append(Config.brokers, -1)
+
+	// ZooKeeper init.
+	zk, err := initZooKeeper(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	defer zk.Close()
+
+	// Get broker and partition metadata.
+	withMetrics := usesStorageMetrics(cmd)
+	if withMetrics {
+		checkMetaAge(cmd, zk)
+	}
+
+	brokerMeta := getBrokerMeta(cmd, zk, withMetrics)
+
+	var partitionMeta kafkazk.PartitionMetaMap
+	if withMetrics {
+		partitionMeta = getPartitionMeta(cmd, zk)
+	}
+
+	// Get the current partition map.
+	partitionMapIn, err := kafkazk.PartitionMapFromZK(Config.topics, zk)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Exclude any topics that are pending deletion.
+	pending := stripPendingDeletes(partitionMapIn, zk)
+
+	printTopics(partitionMapIn)
+	printExcludedTopics(pending)
+
+	// Reduce replica sets exceeding the replication factor.
+	ol, _ := cmd.Flags().GetBool("optimize-leadership")
+	partitionMapOut := reduceReplication(partitionMapIn, r, brokerMeta, ol)
+
+	// Extend replica sets below the replication factor with
+	// stub brokers, which are replaced in a rebuild.
+	partitionMapOut.SetReplication(r)
+
+	brokers, bs := getBrokers(cmd, partitionMapOut, brokerMeta)
+	brokersOrig := brokers.Copy()
+
+	if bs.Changes() {
+		fmt.Printf("%s-\n", indent)
+	}
+
+	if withMetrics {
+		ensureBrokerMetrics(cmd, brokers, brokerMeta)
+
+		// Add back the storage of removed replicas.
+		allBrokers := func(b *kafkazk.Broker) bool { return true }
+		dropped := removedReplicas(partitionMapIn, partitionMapOut, brokers)
+		if err := brokers.SubStorage(dropped, partitionMeta, allBrokers); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	added, removed := replicationChanges(partitionMapIn, partitionMapOut)
+
+	fmt.Printf("\nAction:\n")
+	switch {
+	case added == 0 && removed == 0:
+		fmt.Printf("%sno-op\n", indent)
+	default:
+		fmt.Printf("%sSetting replication factor %d: adding replicas to %d partition(s), removing replicas from %d partition(s)\n",
+			indent, r, added, removed)
+	}
+
+	// Rebuild to place any added replicas. Only the
+	// stub brokers are replaced.
+	partitionMapOut, errs := buildMap(cmd, partitionMapOut, partitionMeta, brokers, nil, nil, nil, getClusterMap(cmd, zk))
+
+	// Ensure that rack diversity wasn't reduced.
+	errs = append(errs, rackDiversityViolations(partitionMapIn, partitionMapOut, brokerMeta)...)
+
+	// Print map change results.
+	printMapChanges(partitionMapIn, partitionMapOut)
+
+	// Print broker assignment statistics.
+	errs = append(errs, printBrokerAssignmentStats(cmd, partitionMapIn, partitionMapOut, brokersOrig, brokers)...)
+
+	// Print error/warnings.
+	handleOverridableErrs(cmd, errs)
+
+	originalMap, partitionMapOut := skipReassignmentNoOps(partitionMapIn, partitionMapOut)
+
+	writeMaps(cmd, partitionMapOut, nil, originalMap)
+}
@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+// replicaRemovalState tracks the replica and leader counts
+// per broker used to score replica removals.
+type replicaRemovalState struct {
+	racks    map[int]string
+	replicas map[int]int
+	leaders  map[int]int
+	// moveLeaders allows removing leaders
+	// to balance leader counts.
+	moveLeaders bool
+}
+
+// newReplicaRemovalState takes a *PartitionMap, a BrokerMetaMap and
+// whether leaders may be removed and returns a *replicaRemovalState.
+func newReplicaRemovalState(pm *kafkazk.PartitionMap, bmm kafkazk.BrokerMetaMap, moveLeaders bool) *replicaRemovalState {
+	s := &replicaRemovalState{
+		racks:       map[int]string{},
+		replicas:    map[int]int{},
+		leaders:     map[int]int{},
+		moveLeaders: moveLeaders,
+	}
+
+	for id, m := range bmm {
+		s.racks[id] = m.Rack
+	}
+
+	for _, partn := range pm.Partitions {
+		for i, id := range partn.Replicas {
+			s.replicas[id]++
+			if i == 0 {
+				s.leaders[id]++
+			}
+		}
+	}
+
+	return s
+}
+
+// uniqueRacks returns the number of unique rack IDs among the
+// replicas excluding position skip. Brokers without a rack ID
+// are counted as unique.
+func (s *replicaRemovalState) uniqueRacks(replicas []int, skip int) int {
+	racks := map[string]struct{}{}
+	var n int
+
+	for i, id := range replicas {
+		if i == skip {
+			continue
+		}
+
+		switch rack := s.racks[id]; rack {
+		case "":
+			n++
+		default:
+			racks[rack] = struct{}{}
+		}
+	}
+
+	return n + len(racks)
+}
+
+// leaderCost returns the change in the sum of squared leader counts
+// from removing the replica at position i. Removing a follower has no
+// cost; removing the leader transfers leadership to the next replica.
+func (s *replicaRemovalState) leaderCost(replicas []int, i int) int {
+	if i != 0 || len(replicas) < 2 {
+		return 0
+	}
+
+	a, b := s.leaders[replicas[0]], s.leaders[replicas[1]]
+
+	return 2*(b-a) + 2
+}
+
+// dropPosition returns the position of the replica whose removal best
+// preserves the replica set. Candidates are ranked by the unique rack
+// IDs remaining, then by the effect on leadership balance, then by the
+// replica count of the broker (the most loaded broker is preferred) and
+// finally by position (the last position is preferred). The leader is
+// only a candidate if moveLeaders is set.
+func (s *replicaRemovalState) dropPosition(replicas []int) int {
+	best := -1
+	var bestRacks, bestLeaderCost, bestCount int
+
+	for i, id := range replicas {
+		if i == 0 && !s.moveLeaders && len(replicas) > 1 {
+			continue
+		}
+
+		racks := s.uniqueRacks(replicas, i)
+		leaderCost := s.leaderCost(replicas, i)
+		count := s.replicas[id]
+
+		switch {
+		case best < 0,
+			racks > bestRacks,
+			racks == bestRacks && leaderCost < bestLeaderCost,
+			racks == bestRacks && leaderCost == bestLeaderCost && count >= bestCount:
+			best, bestRacks, bestLeaderCost, bestCount = i, racks, leaderCost, count
+		}
+	}
+
+	return best
+}
+
+// drop removes the replica at position i and updates the counts.
+func (s *replicaRemovalState) drop(replicas []int, i int) []int {
+	id := replicas[i]
+	s.replicas[id]--
+
+	if i == 0 {
+		s.leaders[id]--
+		if len(replicas) > 1 {
+			s.leaders[replicas[1]]++
+		}
+	}
+
+	out := make([]int, 0, len(replicas)-1)
+	out = append(out, replicas[:i]...)
+	return append(out, replicas[i+1:]...)
+}
+
+// reduceReplication takes a *PartitionMap, a replication factor r, a
+// BrokerMetaMap and whether leaders may be removed and returns a copy
+// of the map with all replica sets exceeding r reduced to r. Replicas
+// are removed one at a time according to dropPosition.
+func reduceReplication(pm *kafkazk.PartitionMap, r int, bmm kafkazk.BrokerMetaMap, moveLeaders bool) *kafkazk.PartitionMap {
+	out := pm.Copy()
+	s := newReplicaRemovalState(out, bmm, moveLeaders)
+
+	for n, partn := range out.Partitions {
+		for len(partn.Replicas) > r {
+			partn.Replicas = s.drop(partn.Replicas, s.dropPosition(partn.Replicas))
+		}
+		out.Partitions[n].Replicas = partn.Replicas
+	}
+
+	return out
+}
+
+// removedReplicas takes the original and reduced *PartitionMap and a
+// BrokerMap and returns a *PartitionMap of the replicas removed from
+// brokers in the BrokerMap.
+func removedReplicas(pm1, pm2 *kafkazk.PartitionMap, bm kafkazk.BrokerMap) *kafkazk.PartitionMap {
+	removed := kafkazk.NewPartitionMap()
+
+	for i, partn := range pm1.Partitions {
+		var replicas []int
+		for _, id := range partn.Replicas {
+			if _, exist := bm[id]; exist && notInReplicaSet(id, pm2.Partitions[i].Replicas) {
+				replicas = append(replicas, id)
+			}
+		}
+
+		if len(replicas) > 0 {
+			removed.Partitions = append(removed.Partitions, kafkazk.Partition{
+				Topic:     partn.Topic,
+				Partition: partn.Partition,
+				Replicas:  replicas,
+			})
+		}
+	}
+
+	return removed
+}
+
+// replicationChanges takes the original and updated *PartitionMap and
+// returns the number of partitions with replicas added and removed.
+func replicationChanges(pm1, pm2 *kafkazk.PartitionMap) (int, int) {
+	var added, removed int
+
+	for i := range pm1.Partitions {
+		switch l1, l2 := len(pm1.Partitions[i].Replicas), len(pm2.Partitions[i].Replicas); {
+		case l2 > l1:
+			added++
+		case l2 < l1:
+			removed++
+		}
+	}
+
+	return added, removed
+}
+
+// rackDiversityViolations returns an error for each partition in the
+// output map with fewer unique rack IDs than in the input map, capped
+// at the new replication factor.
+func rackDiversityViolations(pm1, pm2 *kafkazk.PartitionMap, bmm kafkazk.BrokerMetaMap) errors {
+	var errs errors
+
+	s := newReplicaRemovalState(pm1, bmm, false)
+	for i, partn := range pm2.Partitions {
+		before := s.uniqueRacks(pm1.Partitions[i].Replicas, -1)
+		after := s.uniqueRacks(partn.Replicas, -1)
+
+		if after < before && after < len(partn.Replicas) {
+			errs = append(errs, fmt.Errorf("%s p%d: unique rack IDs reduced from %d to %d",
+				partn.Topic, partn.Partition, before, after))
+		}
+	}
+
+	return errs
+}
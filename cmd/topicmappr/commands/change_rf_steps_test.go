@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func testChangeRFInput() (*kafkazk.PartitionMap, kafkazk.BrokerMetaMap) {
+	pm, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002,1003]},
+		{"topic":"test_topic","partition":1,"replicas":[1002,1003,1004]},
+		{"topic":"test_topic","partition":2,"replicas":[1003,1001,1004]},
+		{"topic":"test_topic","partition":3,"replicas":[1004,1002]}]}`)
+
+	bmm := kafkazk.BrokerMetaMap{
+		1001: &kafkazk.BrokerMeta{Rack: "a"},
+		1002: &kafkazk.BrokerMeta{Rack: "b"},
+		1003: &kafkazk.BrokerMeta{Rack: "c"},
+		1004: &kafkazk.BrokerMeta{Rack: "a"},
+	}
+
+	return pm, bmm
+}
+
+func TestDropPosition(t *testing.T) {
+	pm, bmm := testChangeRFInput()
+	s := newReplicaRemovalState(pm, bmm, false)
+
+	// All removals keep 2 rack IDs. Followers are preferred over
+	// the leader and 1003 and 1004 both hold 3 replicas, so the
+	// last position is removed.
+	if i := s.dropPosition([]int{1002, 1003, 1004}); i != 2 {
+		t.Errorf("Expected position 2, got %d", i)
+	}
+
+	// 1001 and 1004 share rack a; 1004
+	// holds the most replicas.
+	if i := s.dropPosition([]int{1003, 1001, 1004}); i != 2 {
+		t.Errorf("Expected position 2, got %d", i)
+	}
+
+	// Rack diversity takes precedence over the last position.
+	if i := s.dropPosition([]int{1001, 1004, 1002}); i != 1 {
+		t.Errorf("Expected position 1, got %d", i)
+	}
+
+	// Moving leadership from 1002 to 1003 would improve the
+	// leader balance, but the leader is kept by default.
+	s.leaders[1002] = 3
+	if i := s.dropPosition([]int{1002, 1003, 1004}); i != 2 {
+		t.Errorf("Expected position 2, got %d", i)
+	}
+
+	// Unless leaders may be removed.
+	s.moveLeaders = true
+	if i := s.dropPosition([]int{1002, 1003, 1004}); i != 0 {
+		t.Errorf("Expected position 0, got %d", i)
+	}
+}
+
+func TestReduceReplication(t *testing.T) {
+	pm, bmm := testChangeRFInput()
+
+	out := reduceReplication(pm, 2, bmm, false)
+
+	expected := [][]int{
+		{1001, 1002},
+		{1002, 1003},
+		{1003, 1001},
+		{1004, 1002},
+	}
+
+	for i, partn := range out.Partitions {
+		if !intsEqual(partn.Replicas, expected[i]) {
+			t.Errorf("p%d: expected replicas %v, got %v", i, expected[i], partn.Replicas)
+		}
+	}
+
+	// The input map isn't modified.
+	if len(pm.Partitions[0].Replicas) != 3 {
+		t.Errorf("Unexpected input map modification")
+	}
+
+	if added, removed := replicationChanges(pm, out); added != 0 || removed != 3 {
+		t.Errorf("Expected 0 added, 3 removed; got %d, %d", added, removed)
+	}
+
+	if errs := rackDiversityViolations(pm, out, bmm); len(errs) != 0 {
+		t.Errorf("Unexpected violations %v", errs)
+	}
+}
+
+func TestRemovedReplicas(t *testing.T) {
+	pm, bmm := testChangeRFInput()
+	out := reduceReplication(pm, 2, bmm, false)
+
+	bm := kafkazk.BrokerMap{
+		1002: &kafkazk.Broker{ID: 1002},
+		1003: &kafkazk.Broker{ID: 1003},
+		1004: &kafkazk.Broker{ID: 1004},
+	}
+
+	removed := removedReplicas(pm, out, bm)
+
+	expected := map[int][]int{
+		0: {1003},
+		1: {1004},
+		2: {1004},
+	}
+
+	if len(removed.Partitions) != len(expected) {
+		t.Fatalf("Expected %d partitions, got %d", len(expected), len(removed.Partitions))
+	}
+
+	for _, partn := range removed.Partitions {
+		if !intsEqual(partn.Replicas, expected[partn.Partition]) {
+			t.Errorf("p%d: expected replicas %v, got %v", partn.Partition, expected[partn.Partition], partn.Replicas)
+		}
+	}
+}
+
+func intsEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
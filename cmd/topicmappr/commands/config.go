@@ -32,13 +32,17 @@ var (
 )
 
 func bootstrap(cmd *cobra.Command) {
-	b, _ := cmd.Flags().GetString("brokers")
-	Config.brokers = brokerStringToSlice(b)
+	// Not all commands reference brokers.
+	if b, _ := cmd.Flags().GetString("brokers"); b != "" {
+		Config.brokers = brokerStringToSlice(b)
+	}
 
 	// Append trailing slash if not included.
-	op := cmd.Flag("out-path").Value.String()
-	if op != "" && !strings.HasSuffix(op, "/") {
-		cmd.Flags().Set("out-path", op+"/")
+	// Not all commands write output.
+	if f := cmd.Flag("out-path"); f != nil {
+		if op := f.Value.String(); op != "" && !strings.HasSuffix(op, "/") {
+			cmd.Flags().Set("out-path", op+"/")
+		}
 	}
 
 	// Determine if regexp was provided in the topic
@@ -76,13 +80,37 @@ func initZooKeeper(cmd *cobra.Command) (kafkazk.Handler, error) {
 	// Suppress underlying ZK client noise.
 	log.SetOutput(ioutil.Discard)
 
+	// Replay a bundle in place of ZooKeeper if configured.
+	if f := cmd.Flag("from-bundle"); f != nil && f.Value.String() != "" {
+		b, err := readBundle(f.Value.String())
+		if err != nil {
+			return nil, fmt.Errorf("Error reading bundle: %s", err)
+		}
+		return recordBundle(cmd, &bundleReplay{b: b}), nil
+	}
+
+	// Read from a snapshot in place of ZooKeeper if configured.
+	if p := cmd.Parent().Flag("snapshot").Value.String(); p != "" {
+		zk, err := kafkazk.NewSnapshotHandlerFromFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading snapshot: %s", err)
+		}
+		return recordBundle(cmd, zk), nil
+	}
+
 	zkAddr := cmd.Parent().Flag("zk-addr").Value.String()
 	timeout := 250 * time.Millisecond
 
+	// Not all commands reference metrics.
+	var metricsPrefix string
+	if f := cmd.Flag("zk-metrics-prefix"); f != nil {
+		metricsPrefix = f.Value.String()
+	}
+
 	zk, err := kafkazk.NewHandler(&kafkazk.Config{
 		Connect:       zkAddr,
 		Prefix:        cmd.Parent().Flag("zk-prefix").Value.String(),
-		MetricsPrefix: cmd.Flag("zk-metrics-prefix").Value.String(),
+		MetricsPrefix: metricsPrefix,
 	})
 
 	if err != nil {
@@ -96,7 +124,7 @@ func initZooKeeper(cmd *cobra.Command) (kafkazk.Handler, error) {
 		os.Exit(1)
 	}
 
-	return zk, nil
+	return recordBundle(cmd, zk), nil
 }
 
 // containsRegex takes a topic name
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+var decommissionCmd = &cobra.Command{
+	Use:   "decommission",
+	Short: "Drain all partitions from one or more brokers",
+	Long: `decommission relocates every replica held by the brokers provided via
+the --brokers parameter to the remaining brokers in the cluster. All topics
+(or those matching the optional --topics parameter) are scanned in ZooKeeper;
+only partitions with a replica on a decommissioned broker are changed, and only
+the affected replicas are moved. Replacements are chosen under rack ID and
+storage constraints.`,
+	Run: decommission,
+}
+
+func init() {
+	rootCmd.AddCommand(decommissionCmd)
+
+	decommissionCmd.Flags().String("brokers", "", "Broker IDs to decommission (comma delim. list)")
+	decommissionCmd.Flags().String("topics", "", "Limit the scope to topics (comma delim. list) by lookup in ZooKeeper (default: all topics)")
+	decommissionCmd.Flags().String("out-path", "", "Path to write output map files to")
+	decommissionCmd.Flags().String("out-file", "", "If defined, write a combined map of all topics to a file")
+	decommissionCmd.Flags().String("placement", "storage", "Partition placement strategy: [count, storage, throughput]")
+	decommissionCmd.Flags().String("optimize", "distribution", "Optimization priority for the storage placement strategy: [distribution, storage]")
+	decommissionCmd.Flags().Bool("rack-leader-spread", false, "Spread partition leaders evenly across rack IDs")
+	decommissionCmd.Flags().Int("min-rack-ids", 0, "Minimum number of required of unique rack IDs per replica set (0 requires that all are unique)")
+	decommissionCmd.Flags().Float64("partition-size-factor", 1.0, "Factor by which to multiply partition sizes when using storage or throughput placement")
+	decommissionCmd.Flags().String("zk-metrics-prefix", "topicmappr", "ZooKeeper namespace prefix for Kafka metrics")
+	decommissionCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes)")
+
+	// Required.
+	decommissionCmd.MarkFlagRequired("brokers")
+}
+
+func decommission(cmd *cobra.Command, _ []string) {
+	p := cmd.Flag("placement").Value.String()
+	o := cmd.Flag("optimize").Value.String()
+
+	switch {
+	case p != "count" && p != "storage" && p != "throughput":
+		fmt.Println("\n[ERROR] --placement must be one of 'count', 'storage' or 'throughput'")
+		defaultsAndExit()
+	case o != "distribution" && o != "storage":
+		fmt.Println("\n[ERROR] --optimize must be either 'distribution' or 'storage'")
+		defaultsAndExit()
+	}
+
+	bootstrap(cmd)
+
+	// The --brokers flag references brokers being
+	// removed rather than the target broker list.
+	decommissioned := map[int]struct{}{}
+	for _, id := range Config.brokers {
+		decommissioned[id] = struct{}{}
+	}
+
+	// Default to all topics.
+	if len(Config.topics) == 0 {
+		Config.topics = []*regexp.Regexp{regexp.MustCompile(".*")}
+	}
+
+	// ZooKeeper init.
+	zk, err := initZooKeeper(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	defer zk.Close()
+
+	// Get broker and partition metadata. Partition
+	// metadata is always fetched to report the
+	// volume of data being moved.
+	checkMetaAge(cmd, zk)
+	brokerMeta := getBrokerMeta(cmd, zk, true)
+	partitionMeta := getPartitionMeta(cmd, zk)
+
+	// Get the current partition map for all
+	// referenced topics.
+	partitionMapAll, err := kafkazk.PartitionMapFromZK(Config.topics, zk)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Exclude any topics that are pending deletion.
+	pending := stripPendingDeletes(partitionMapAll, zk)
+
+	// Scope the input map to partitions with a
+	// replica on any decommissioned broker.
+	partitionMapIn := partitionsWithBrokers(partitionMapAll, decommissioned)
+	originalMap := partitionMapIn.Copy()
+
+	printTopics(partitionMapIn)
+	printExcludedTopics(pending)
+
+	if len(partitionMapIn.Partitions) == 0 {
+		fmt.Println("\nNo partitions found on the decommissioned brokers")
+		os.Exit(0)
+	}
+
+	// The target broker list is every registered
+	// broker except those being decommissioned.
+	Config.brokers = remainingBrokers(brokerMeta, decommissioned)
+
+	// Broker usage is scored on the map of all
+	// topics so that placements account for
+	// existing load.
+	brokers, bs := getBrokers(cmd, partitionMapAll, brokerMeta)
+	brokersOrig := brokers.Copy()
+
+	if bs.Changes() {
+		fmt.Printf("%s-\n", indent)
+	}
+
+	// Check if any referenced brokers are marked as having
+	// missing/partial metrics data.
+	ensureBrokerMetrics(cmd, brokers, brokerMeta)
+
+	fmt.Printf("\nAction:\n")
+	fmt.Printf("%sDecommissioning %d broker(s) from %d partition(s)\n",
+		indent, len(decommissioned), len(partitionMapIn.Partitions))
+
+	// Rebuild the affected partitions. Only the replicas held by
+	// brokers marked for replacement are changed.
+	partitionMapOut, errs := buildMap(cmd, partitionMapIn, partitionMeta, brokers, nil, nil, nil, getClusterMap(cmd, zk))
+
+	// Ensure that no decommissioned broker remains.
+	for _, partn := range partitionMapOut.Partitions {
+		for _, id := range partn.Replicas {
+			if _, exist := decommissioned[id]; exist {
+				errs = append(errs, fmt.Errorf("%s p%d: broker %d still assigned", partn.Topic, partn.Partition, id))
+			}
+		}
+	}
+
+	// Print map change results.
+	printMapChanges(originalMap, partitionMapOut)
+
+	// Print broker assignment statistics.
+	printBrokerAssignmentStats(cmd, originalMap, partitionMapOut, brokersOrig, brokers)
+
+	// Print broker throughput changes.
+	printBrokerThroughput(cmd, originalMap, partitionMapOut, partitionMeta)
+
+	// Print data movement per destination broker.
+	printDestinationVolume(originalMap, partitionMapOut, partitionMeta)
+
+	// Print error/warnings.
+	handleOverridableErrs(cmd, errs)
+
+	_, partitionMapOut = skipReassignmentNoOps(originalMap, partitionMapOut)
+
+	writeMaps(cmd, partitionMapOut, nil, originalMap)
+}
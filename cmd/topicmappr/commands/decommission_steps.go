@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+// partitionsWithBrokers takes a *PartitionMap and a set of broker IDs
+// and returns a new *PartitionMap with only the partitions that have
+// at least one replica on any of the referenced brokers.
+func partitionsWithBrokers(pm *kafkazk.PartitionMap, ids map[int]struct{}) *kafkazk.PartitionMap {
+	filtered := kafkazk.NewPartitionMap()
+
+	for _, partn := range pm.Copy().Partitions {
+		for _, id := range partn.Replicas {
+			if _, exist := ids[id]; exist {
+				filtered.Partitions = append(filtered.Partitions, partn)
+				break
+			}
+		}
+	}
+
+	return filtered
+}
+
+// remainingBrokers takes a BrokerMetaMap and a set of broker IDs being
+// removed and returns a sorted []int of all other registered broker IDs.
+func remainingBrokers(bm kafkazk.BrokerMetaMap, exclude map[int]struct{}) []int {
+	var ids []int
+
+	for id := range bm {
+		if _, excluded := exclude[id]; !excluded {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Ints(ids)
+
+	return ids
+}
+
+// brokerVolume holds the count and total
+// size of partitions moving to a broker.
+type brokerVolume struct {
+	partitions int
+	bytes      float64
+}
+
+// destinationVolume takes the original and new PartitionMaps along with
+// a PartitionMetaMap and returns a mapping of broker ID to the count and
+// size of partition replicas that the broker would receive.
+func destinationVolume(pm1, pm2 *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap) map[int]*brokerVolume {
+	volume := map[int]*brokerVolume{}
+
+	for i := range pm1.Partitions {
+		p1, p2 := pm1.Partitions[i], pm2.Partitions[i]
+
+		existing := map[int]struct{}{}
+		for _, id := range p1.Replicas {
+			existing[id] = struct{}{}
+		}
+
+		// Partitions missing metadata are counted
+		// but contribute no size.
+		size, _ := pmm.Size(p2)
+
+		for _, id := range p2.Replicas {
+			if _, exist := existing[id]; exist {
+				continue
+			}
+
+			if _, exist := volume[id]; !exist {
+				volume[id] = &brokerVolume{}
+			}
+
+			volume[id].partitions++
+			volume[id].bytes += size
+		}
+	}
+
+	return volume
+}
+
+// printDestinationVolume prints the count and size of
+// partition replicas that each broker would receive.
+func printDestinationVolume(pm1, pm2 *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap) {
+	volume := destinationVolume(pm1, pm2, pmm)
+
+	fmt.Println("\nData movement by destination broker:")
+
+	if len(volume) == 0 {
+		fmt.Printf("%s[none]\n", indent)
+		return
+	}
+
+	ids := []int{}
+	for id := range volume {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	var total float64
+	for _, id := range ids {
+		v := volume[id]
+		total += v.bytes
+		fmt.Printf("%sBroker %d: %.2fGB (%d partitions)\n",
+			indent, id, v.bytes/div, v.partitions)
+	}
+
+	fmt.Printf("%s-\n", indent)
+	fmt.Printf("%sTotal: %.2fGB\n", indent, total/div)
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func TestPartitionsWithBrokers(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm, _ := zk.GetPartitionMap("test_topic")
+
+	filtered := partitionsWithBrokers(pm, map[int]struct{}{1003: struct{}{}})
+
+	expected := []int{2, 3}
+
+	if len(filtered.Partitions) != len(expected) {
+		t.Fatalf("Expected %d partitions, got %d", len(expected), len(filtered.Partitions))
+	}
+
+	for i, p := range filtered.Partitions {
+		if p.Partition != expected[i] {
+			t.Errorf("Expected partition %d, got %d", expected[i], p.Partition)
+		}
+	}
+}
+
+func TestRemainingBrokers(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	bm, _ := zk.GetAllBrokerMeta(false)
+
+	ids := remainingBrokers(bm, map[int]struct{}{1001: struct{}{}, 1003: struct{}{}})
+
+	expected := []int{1002, 1004, 1005, 1007}
+
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, ids)
+	}
+
+	for i := range ids {
+		if ids[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, ids)
+		}
+	}
+}
+
+func TestDestinationVolume(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm1, _ := zk.GetPartitionMap("test_topic")
+	pmm, _ := zk.GetAllPartitionMeta()
+
+	pm2 := pm1.Copy()
+	pm2.Partitions[0].Replicas = []int{1005, 1002}
+	pm2.Partitions[2].Replicas = []int{1005, 1004, 1001}
+
+	volume := destinationVolume(pm1, pm2, pmm)
+
+	if len(volume) != 1 {
+		t.Fatalf("Expected 1 destination broker, got %d", len(volume))
+	}
+
+	if volume[1005].partitions != 2 {
+		t.Errorf("Expected 2 partitions, got %d", volume[1005].partitions)
+	}
+
+	if volume[1005].bytes != 3000.00 {
+		t.Errorf("Expected 3000.00 bytes, got %.2f", volume[1005].bytes)
+	}
+}
@@ -0,0 +1,339 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+// Bytes per MB as used for Kafka replication throttle rates.
+const throttleUnit = 1000000.00
+
+// brokerRatePrefix prefixes --throttle-cap-map keys
+// that set the rate of a broker ID rather than an
+// instance type.
+const brokerRatePrefix = "broker:"
+
+// throttleRates holds replication throttle
+// rates in bytes/s.
+type throttleRates struct {
+	// Applied to brokers not in the brokers map.
+	defaultRate float64
+	// Rates by instance type; see resolve.
+	instanceTypes map[string]float64
+	brokers       map[int]float64
+}
+
+// enabled returns whether any throttle rates are configured.
+func (t throttleRates) enabled() bool {
+	return t.defaultRate > 0 || len(t.instanceTypes) > 0 || len(t.brokers) > 0
+}
+
+// byInstanceType returns whether any rates are by instance type.
+func (t throttleRates) byInstanceType() bool {
+	return len(t.instanceTypes) > 0
+}
+
+// resolve takes a BrokerMetaMap and sets the rate of each broker with
+// an instance type in the instance type rates, unless the broker rate
+// is set explicitly. Brokers of other or unknown instance types use the
+// default rate, as in autothrottle.
+func (t throttleRates) resolve(bmm kafkazk.BrokerMetaMap) {
+	for id, m := range bmm {
+		if _, exist := t.brokers[id]; exist {
+			continue
+		}
+
+		if r, exist := t.instanceTypes[m.InstanceType]; exist && m.InstanceType != "" {
+			t.brokers[id] = r
+		}
+	}
+}
+
+// rate returns the throttle rate for a broker.
+func (t throttleRates) rate(id int) float64 {
+	if r, exist := t.brokers[id]; exist {
+		return r
+	}
+
+	return t.defaultRate
+}
+
+// getThrottleRates returns a throttleRates from the --throttle-rate and
+// --throttle-cap-map flags. The capacity map is the autothrottle --cap-map
+// JSON map of instance types to MB/s; broker instance types are resolved
+// from broker metadata with resolve. As an extension, keys of the form
+// "broker:<id>" set the rate of a single broker.
+func getThrottleRates(cmd *cobra.Command) (throttleRates, error) {
+	t := throttleRates{instanceTypes: map[string]float64{}, brokers: map[int]float64{}}
+
+	r, _ := cmd.Flags().GetFloat64("throttle-rate")
+	if r < 0 {
+		return t, fmt.Errorf("--throttle-rate must be >= 0")
+	}
+
+	t.defaultRate = r * throttleUnit
+
+	cm := cmd.Flag("throttle-cap-map").Value.String()
+	if cm == "" {
+		return t, nil
+	}
+
+	capMap := map[string]float64{}
+	if err := json.Unmarshal([]byte(cm), &capMap); err != nil {
+		return t, fmt.Errorf("invalid --throttle-cap-map: %s", err)
+	}
+
+	for k, v := range capMap {
+		if v <= 0 {
+			return t, fmt.Errorf("invalid --throttle-cap-map: '%s' rate must be > 0", k)
+		}
+
+		if !strings.HasPrefix(k, brokerRatePrefix) {
+			t.instanceTypes[k] = v * throttleUnit
+			continue
+		}
+
+		id, err := strconv.Atoi(strings.TrimPrefix(k, brokerRatePrefix))
+		if err != nil {
+			return t, fmt.Errorf("invalid --throttle-cap-map: broker ID '%s'", k)
+		}
+
+		t.brokers[id] = v * throttleUnit
+	}
+
+	return t, nil
+}
+
+// transferEstimate holds the bytes sent and received per broker
+// for a reassignment.
+type transferEstimate struct {
+	sent     map[int]float64
+	received map[int]float64
+	// Total bytes replicated.
+	bytes float64
+}
+
+// estimateTransfer takes the current and target PartitionMaps along with
+// a PartitionMetaMap and returns a transferEstimate. Partitions are matched
+// by topic and partition number; partitions in the target map not found
+// in the current map are skipped. Each replica added to a partition is
+// received by the new broker and sent by the current leader.
+func estimateTransfer(pm1, pm2 *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap) (transferEstimate, error) {
+	e := transferEstimate{
+		sent:     map[int]float64{},
+		received: map[int]float64{},
+	}
+
+	current := map[string]map[int][]int{}
+	for _, p := range pm1.Partitions {
+		if _, exist := current[p.Topic]; !exist {
+			current[p.Topic] = map[int][]int{}
+		}
+		current[p.Topic][p.Partition] = p.Replicas
+	}
+
+	for _, p := range pm2.Partitions {
+		replicas, exist := current[p.Topic][p.Partition]
+		if !exist || len(replicas) == 0 {
+			continue
+		}
+
+		existing := map[int]struct{}{}
+		for _, id := range replicas {
+			existing[id] = struct{}{}
+		}
+
+		var size float64
+		var sized bool
+
+		for _, id := range p.Replicas {
+			if _, exist := existing[id]; exist {
+				continue
+			}
+
+			// Only look up the size if there's
+			// data to be moved.
+			if !sized {
+				var err error
+				if size, err = pmm.Size(p); err != nil {
+					return e, err
+				}
+				sized = true
+			}
+
+			e.received[id] += size
+			e.sent[replicas[0]] += size
+			e.bytes += size
+		}
+	}
+
+	return e, nil
+}
+
+// duration returns the estimated time to complete the transfer under
+// the throttleRates along with the ID of the bottleneck broker. Brokers
+// send and receive concurrently under separate leader and follower
+// throttles; the duration for each broker is bounded by the greater
+// of the two.
+func (e transferEstimate) duration(t throttleRates) (time.Duration, int, error) {
+	ids := map[int]struct{}{}
+	for id := range e.sent {
+		ids[id] = struct{}{}
+	}
+	for id := range e.received {
+		ids[id] = struct{}{}
+	}
+
+	sorted := []int{}
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+
+	sort.Ints(sorted)
+
+	var max float64
+	var bottleneck int
+
+	for _, id := range sorted {
+		r := t.rate(id)
+		if r <= 0 {
+			return 0, 0, fmt.Errorf("no throttle rate specified for broker %d", id)
+		}
+
+		b := e.sent[id]
+		if e.received[id] > b {
+			b = e.received[id]
+		}
+
+		if s := b / r; s > max {
+			max, bottleneck = s, id
+		}
+	}
+
+	return time.Duration(max * float64(time.Second)), bottleneck, nil
+}
+
+// reassignmentPhase is a named step of a reassignment, from the
+// from map to the to map. Phases are executed sequentially.
+type reassignmentPhase struct {
+	name     string
+	from, to *kafkazk.PartitionMap
+}
+
+// reassignmentPhases returns the reassignmentPhases for a plan. A phased
+// reassignment has two phases, a batched reassignment has one phase per
+// batch and all others a single phase.
+func reassignmentPhases(pm1, pm2, phased *kafkazk.PartitionMap, batches []*kafkazk.PartitionMap) []reassignmentPhase {
+	switch {
+	case phased != nil:
+		return []reassignmentPhase{
+			{name: "phase1", from: pm1, to: phased},
+			{name: "phase2", from: phased, to: pm2},
+		}
+	case len(batches) > 0:
+		var phases []reassignmentPhase
+		for i, b := range batches {
+			phases = append(phases, reassignmentPhase{
+				name: fmt.Sprintf("batch%d", i+1),
+				from: pm1,
+				to:   b,
+			})
+		}
+		return phases
+	}
+
+	return []reassignmentPhase{{name: "reassignment", from: pm1, to: pm2}}
+}
+
+// printReassignmentEstimate prints the bytes sent and received per broker
+// and the estimated duration of each reassignmentPhase and in total, if
+// throttle rates were specified. Instance type rates are resolved from
+// the BrokerMetaMap.
+func printReassignmentEstimate(cmd *cobra.Command, phases []reassignmentPhase, pmm kafkazk.PartitionMetaMap, bmm kafkazk.BrokerMetaMap) {
+	t, err := getThrottleRates(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if !t.enabled() {
+		return
+	}
+
+	t.resolve(bmm)
+
+	fmt.Println("\nReassignment estimate:")
+
+	var total time.Duration
+	var totalBytes float64
+	sent, received := map[int]float64{}, map[int]float64{}
+
+	for _, p := range phases {
+		e, err := estimateTransfer(p.from, p.to, pmm)
+		if err != nil {
+			fmt.Printf("%s[ERROR] %s\n", indent, err)
+			os.Exit(1)
+		}
+
+		d, bottleneck, err := e.duration(t)
+		if err != nil {
+			fmt.Printf("%s[ERROR] %s\n", indent, err)
+			os.Exit(1)
+		}
+
+		total += d
+		totalBytes += e.bytes
+
+		for id, b := range e.sent {
+			sent[id] += b
+		}
+		for id, b := range e.received {
+			received[id] += b
+		}
+
+		if e.bytes == 0 {
+			fmt.Printf("%s%s: 0.00GB, no data movement\n", indent, p.name)
+			continue
+		}
+
+		fmt.Printf("%s%s: %.2fGB, bottleneck broker %d (%.2fMB/s), est. %s\n",
+			indent, p.name, e.bytes/div, bottleneck, t.rate(bottleneck)/throttleUnit, d.Round(time.Second))
+	}
+
+	// Per-broker totals.
+	ids := map[int]struct{}{}
+	for id := range sent {
+		ids[id] = struct{}{}
+	}
+	for id := range received {
+		ids[id] = struct{}{}
+	}
+
+	sorted := []int{}
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+
+	sort.Ints(sorted)
+
+	if len(sorted) > 0 {
+		fmt.Printf("%s-\n", indent)
+	}
+
+	for _, id := range sorted {
+		fmt.Printf("%sBroker %d - sent: %.2fGB, received: %.2fGB\n",
+			indent, id, sent[id]/div, received[id]/div)
+	}
+
+	fmt.Printf("%s-\n", indent)
+	fmt.Printf("%sTotal: %.2fGB, est. %s\n", indent, totalBytes/div, total.Round(time.Second))
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func TestEstimateTransfer(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm1, _ := zk.GetPartitionMap("test_topic")
+	pmm, _ := zk.GetAllPartitionMeta()
+
+	pm2 := pm1.Copy()
+	pm2.Partitions[0].Replicas = []int{1005, 1002}
+	pm2.Partitions[2].Replicas = []int{1005, 1004, 1002}
+
+	e, err := estimateTransfer(pm1, pm2, pmm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// p0 (1000) from leader 1001 to 1005; p2 (2000)
+	// from leader 1003 to 1005 and 1002.
+	expectedSent := map[int]float64{1001: 1000, 1003: 4000}
+	expectedReceived := map[int]float64{1005: 3000, 1002: 2000}
+
+	for id, b := range expectedSent {
+		if e.sent[id] != b {
+			t.Errorf("Expected broker %d sent %.2f, got %.2f", id, b, e.sent[id])
+		}
+	}
+
+	for id, b := range expectedReceived {
+		if e.received[id] != b {
+			t.Errorf("Expected broker %d received %.2f, got %.2f", id, b, e.received[id])
+		}
+	}
+
+	if e.bytes != 5000 {
+		t.Errorf("Expected 5000.00 bytes, got %.2f", e.bytes)
+	}
+
+	// Broker 1003 sends 4000 bytes at 1000 bytes/s.
+	tr := throttleRates{defaultRate: 1000, brokers: map[int]float64{1005: 500}}
+
+	d, bottleneck, err := e.duration(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Broker 1005 receives 3000 bytes at 500 bytes/s.
+	if bottleneck != 1005 {
+		t.Errorf("Expected bottleneck broker 1005, got %d", bottleneck)
+	}
+
+	if d != 6*time.Second {
+		t.Errorf("Expected duration 6s, got %s", d)
+	}
+
+	// No rate for any broker.
+	if _, _, err := e.duration(throttleRates{}); err == nil {
+		t.Error("Expected non-nil error")
+	}
+}
+
+func TestThrottleRatesResolve(t *testing.T) {
+	tr := throttleRates{
+		defaultRate:   100,
+		instanceTypes: map[string]float64{"i3.xlarge": 200},
+		brokers:       map[int]float64{1002: 300},
+	}
+
+	bmm := kafkazk.BrokerMetaMap{
+		1001: &kafkazk.BrokerMeta{InstanceType: "i3.xlarge"},
+		1002: &kafkazk.BrokerMeta{InstanceType: "i3.xlarge"},
+		1003: &kafkazk.BrokerMeta{InstanceType: "d2.xlarge"},
+		1004: &kafkazk.BrokerMeta{},
+	}
+
+	tr.resolve(bmm)
+
+	// Explicit broker rates take precedence; other
+	// and unknown instance types use the default.
+	expected := map[int]float64{1001: 200, 1002: 300, 1003: 100, 1004: 100}
+	for id, r := range expected {
+		if tr.rate(id) != r {
+			t.Errorf("Expected broker %d rate %.2f, got %.2f", id, r, tr.rate(id))
+		}
+	}
+}
+
+func TestReassignmentPhases(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm1, _ := zk.GetPartitionMap("test_topic")
+	pm2 := pm1.Copy()
+
+	if p := reassignmentPhases(pm1, pm2, nil, nil); len(p) != 1 {
+		t.Errorf("Expected 1 phase, got %d", len(p))
+	}
+
+	if p := reassignmentPhases(pm1, pm2, pm2.Copy(), nil); len(p) != 2 || p[1].from == pm1 {
+		t.Errorf("Unexpected phased reassignment phases")
+	}
+
+	batches := []*kafkazk.PartitionMap{pm2, pm2, pm2}
+	if p := reassignmentPhases(pm1, pm2, nil, batches); len(p) != 3 || p[2].name != "batch3" {
+		t.Errorf("Unexpected batched reassignment phases")
+	}
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var executeCmd = &cobra.Command{
+	Use:   "execute",
+	Short: "Execute a partition reassignment from one or more map files",
+	Long: `execute submits partition maps generated by topicmappr (or any other
+tool producing the standard Kafka reassignment format) to ZooKeeper and follows
+each reassignment until completion. Maps are provided as a comma delimited list
+of files via the --map-file parameter and are executed in order. Phased maps
+(those ending in -phase1.json) are automatically followed by their -phase2.json
+counterpart. The rollback maps written alongside generated maps can be executed to
+revert a reassignment; the rollback map metadata header is printed before execution.`,
+	Run: execute,
+}
+
+func init() {
+	rootCmd.AddCommand(executeCmd)
+
+	executeCmd.Flags().String("map-file", "", "Partition map file(s) to execute (comma delim. list)")
+	executeCmd.Flags().Int("interval", 5, "Reassignment progress polling interval (in seconds)")
+
+	// Required.
+	executeCmd.MarkFlagRequired("map-file")
+}
+
+func execute(cmd *cobra.Command, _ []string) {
+	mf, _ := cmd.Flags().GetString("map-file")
+	interval, _ := cmd.Flags().GetInt("interval")
+
+	switch {
+	case strings.TrimSpace(mf) == "":
+		fmt.Println("\n[ERROR] --map-file must not be empty")
+		defaultsAndExit()
+	case interval <= 0:
+		fmt.Println("\n[ERROR] --interval must be greater than 0")
+		defaultsAndExit()
+	}
+
+	// Get the ordered list of maps to execute.
+	files := mapFilePhases(strings.Split(mf, ","))
+	maps, meta := readMapFiles(files)
+
+	// Log dir assignments can only be applied
+	// through the Kafka admin API.
+	for i, pm := range maps {
+		if hasLogDirs(pm) {
+			fmt.Printf("\n[ERROR] %s assigns log dirs, which can't be applied through ZooKeeper; "+
+				"apply with kafka-reassign-partitions --bootstrap-server\n", files[i])
+			os.Exit(1)
+		}
+	}
+
+	// ZooKeeper init.
+	zk, err := initZooKeeper(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	defer zk.Close()
+
+	// Refuse to start if any reassignments
+	// are already in progress.
+	re, err := zk.ListReassignments()
+	if err != nil {
+		fmt.Printf("\n[ERROR] checking for reassignments in progress: %s\n", err)
+		os.Exit(1)
+	}
+
+	if len(re) > 0 {
+		fmt.Println("\nReassignments in progress:")
+		printReassignments(re)
+		fmt.Printf("\n%s[ERROR] a reassignment is already in progress\n", indent)
+		os.Exit(1)
+	}
+
+	// Execute each map, waiting for each
+	// reassignment to complete before
+	// moving on to the next.
+	for i, pm := range maps {
+		fmt.Printf("\nExecuting %s (%d of %d):\n", files[i], i+1, len(maps))
+
+		if m := meta[i]; m != nil {
+			fmt.Printf("%s%s map generated at %s by topicmappr %s: %s\n",
+				indent, m.Type, m.GeneratedAt, m.Version, strings.Join(m.Args, " "))
+		}
+
+		if err := zk.CreateReassignments(pm); err != nil {
+			fmt.Printf("%s[ERROR] %s\n", indent, err)
+			os.Exit(1)
+		}
+
+		followReassignment(zk, pm, interval)
+	}
+
+	fmt.Println("\nAll reassignments complete")
+}
@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+// mapFilePhases takes a list of map file paths and returns the ordered
+// list of files to execute. Each phase one map (as written with the
+// --phased-reassignment flag) is immediately followed by its phase two
+// counterpart, provided that the file exists. Files are only listed
+// once, at their first position.
+func mapFilePhases(files []string) []string {
+	var ordered []string
+	seen := map[string]bool{}
+
+	add := func(f string) {
+		if !seen[f] {
+			seen[f] = true
+			ordered = append(ordered, f)
+		}
+	}
+
+	for _, f := range files {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+
+		add(f)
+
+		if !strings.HasSuffix(f, "-phase1.json") {
+			continue
+		}
+
+		phase2 := strings.TrimSuffix(f, "-phase1.json") + "-phase2.json"
+		if _, err := os.Stat(phase2); err == nil {
+			add(phase2)
+		}
+	}
+
+	return ordered
+}
+
+// readMapFiles takes a list of map file paths and returns
+// a *PartitionMap and any *mapMetadata header for each.
+func readMapFiles(files []string) ([]*kafkazk.PartitionMap, []*mapMetadata) {
+	var maps []*kafkazk.PartitionMap
+	var meta []*mapMetadata
+
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		pm, err := kafkazk.PartitionMapFromString(string(data))
+		if err != nil {
+			fmt.Printf("%s: %s\n", f, err)
+			os.Exit(1)
+		}
+
+		if len(pm.Partitions) == 0 {
+			fmt.Printf("%s: map contains no partitions\n", f)
+			os.Exit(1)
+		}
+
+		maps = append(maps, pm)
+		meta = append(meta, readMapMetadata(data))
+	}
+
+	return maps, meta
+}
+
+// hasLogDirs returns whether any partition
+// in the *PartitionMap has a log dir set.
+func hasLogDirs(pm *kafkazk.PartitionMap) bool {
+	for _, p := range pm.Partitions {
+		if p.HasLogDirs() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pendingReassignments takes a *PartitionMap that was submitted for
+// reassignment and the current Reassignments. A mapping of topic
+// name to partition numbers that are still being reassigned is returned.
+func pendingReassignments(pm *kafkazk.PartitionMap, re kafkazk.Reassignments) map[string][]int {
+	pending := map[string][]int{}
+
+	for _, p := range pm.Partitions {
+		if _, exist := pending[p.Topic]; !exist {
+			pending[p.Topic] = []int{}
+		}
+
+		if _, inProgress := re[p.Topic][p.Partition]; inProgress {
+			pending[p.Topic] = append(pending[p.Topic], p.Partition)
+		}
+	}
+
+	for t := range pending {
+		sort.Ints(pending[t])
+	}
+
+	return pending
+}
+
+// maxReassignmentLookupFailures is the number of consecutive failed
+// reassignment lookups after which followReassignment gives up.
+const maxReassignmentLookupFailures = 5
+
+// followReassignment polls the in progress reassignments at the provided
+// interval (in seconds) until all partitions in the *PartitionMap are no
+// longer being reassigned. Progress is printed per topic and partition
+// as reassignments complete.
+// Failed lookups are retried up to maxReassignmentLookupFailures
+// consecutive times before exiting.
+func followReassignment(zk kafkazk.Handler, pm *kafkazk.PartitionMap, interval int) {
+	start := time.Now()
+
+	// Partitions per topic.
+	totals := map[string]int{}
+	// Final replica sets by topic, partition.
+	replicas := map[string]map[int][]int{}
+	for _, p := range pm.Partitions {
+		totals[p.Topic]++
+		if replicas[p.Topic] == nil {
+			replicas[p.Topic] = map[int][]int{}
+		}
+		replicas[p.Topic][p.Partition] = p.Replicas
+	}
+
+	// Track what's still pending from
+	// the previous poll. Every partition
+	// starts as pending.
+	previous := map[string]map[int]struct{}{}
+	for _, p := range pm.Partitions {
+		if previous[p.Topic] == nil {
+			previous[p.Topic] = map[int]struct{}{}
+		}
+		previous[p.Topic][p.Partition] = struct{}{}
+	}
+
+	topics := pm.Topics()
+
+	// Consecutive failed reassignment lookups.
+	var failures int
+
+	for {
+		time.Sleep(time.Duration(interval) * time.Second)
+
+		// A failed lookup is retried; it must not be
+		// mistaken for the reassignment completing.
+		re, err := zk.ListReassignments()
+		if err != nil {
+			failures++
+			if failures >= maxReassignmentLookupFailures {
+				fmt.Printf("%s[ERROR] reassignment status unknown after %d failed lookups: %s\n", indent, failures, err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("%s[WARN] reassignment lookup failed (%d of %d): %s\n", indent, failures, maxReassignmentLookupFailures, err)
+			continue
+		}
+
+		failures = 0
+		pending := pendingReassignments(pm, re)
+
+		fmt.Printf("%s-\n%sProgress (%s elapsed):\n", indent,
+			indent, time.Since(start).Round(time.Second))
+
+		var remaining int
+		for _, t := range topics {
+			current := map[int]struct{}{}
+			for _, p := range pending[t] {
+				current[p] = struct{}{}
+			}
+
+			// Print partitions that completed
+			// since the last poll.
+			var completed []int
+			for p := range previous[t] {
+				if _, exist := current[p]; !exist {
+					completed = append(completed, p)
+				}
+			}
+
+			sort.Ints(completed)
+
+			for _, p := range completed {
+				fmt.Printf("%s%s%s p%d complete: %v\n", indent, indent, t, p, replicas[t][p])
+			}
+
+			previous[t] = current
+			remaining += len(pending[t])
+
+			fmt.Printf("%s%s%s: %d/%d partitions complete\n", indent, indent,
+				t, totals[t]-len(pending[t]), totals[t])
+		}
+
+		if remaining == 0 {
+			fmt.Printf("%sReassignment complete in %s\n", indent, time.Since(start).Round(time.Second))
+			return
+		}
+	}
+}
+
+// printReassignments prints the topic, partitions
+// and replica sets of a Reassignments.
+func printReassignments(re kafkazk.Reassignments) {
+	topics := []string{}
+	for t := range re {
+		topics = append(topics, t)
+	}
+
+	sort.Strings(topics)
+
+	for _, t := range topics {
+		partitions := []int{}
+		for p := range re[t] {
+			partitions = append(partitions, p)
+		}
+
+		sort.Ints(partitions)
+
+		for _, p := range partitions {
+			fmt.Printf("%s%s p%d: %v\n", indent, t, p, re[t][p])
+		}
+	}
+}
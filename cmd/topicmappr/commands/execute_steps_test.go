@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func TestMapFilePhases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "topicmappr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	phase1 := filepath.Join(dir, "test_topic-phase1.json")
+	phase2 := filepath.Join(dir, "test_topic-phase2.json")
+	other := filepath.Join(dir, "other_topic.json")
+
+	for _, f := range []string{phase1, phase2, other} {
+		ioutil.WriteFile(f, []byte{}, 0644)
+	}
+
+	inputs := [][]string{
+		[]string{other},
+		[]string{phase1},
+		[]string{phase1, phase2},
+		[]string{phase1, other},
+		[]string{phase1, other, phase2},
+		[]string{other, phase1, other, phase1, phase2},
+	}
+
+	expected := [][]string{
+		[]string{other},
+		[]string{phase1, phase2},
+		[]string{phase1, phase2},
+		[]string{phase1, phase2, other},
+		[]string{phase1, phase2, other},
+		[]string{other, phase1, phase2},
+	}
+
+	for i, input := range inputs {
+		files := mapFilePhases(input)
+
+		if len(files) != len(expected[i]) {
+			t.Errorf("Expected %v, got %v", expected[i], files)
+			continue
+		}
+
+		for n := range files {
+			if files[n] != expected[i][n] {
+				t.Errorf("Expected %v, got %v", expected[i], files)
+				break
+			}
+		}
+	}
+}
+
+func TestPendingReassignments(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm, _ := zk.GetPartitionMap("mock")
+
+	pending := pendingReassignments(pm, zk.GetReassignments())
+
+	expected := []int{0, 1}
+
+	if len(pending["mock"]) != len(expected) {
+		t.Fatalf("Expected pending partitions %v, got %v", expected, pending["mock"])
+	}
+
+	for i := range expected {
+		if pending["mock"][i] != expected[i] {
+			t.Errorf("Expected pending partitions %v, got %v", expected, pending["mock"])
+		}
+	}
+
+	// No reassignments.
+	pending = pendingReassignments(pm, kafkazk.Reassignments{})
+
+	if len(pending["mock"]) != 0 {
+		t.Errorf("Expected no pending partitions, got %v", pending["mock"])
+	}
+}
@@ -0,0 +1,627 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+// planReview holds the state of an interactive plan review. Edits
+// are tracked against the planned map by partition index: excluded
+// partitions are reverted to their original replica set and pinned
+// brokers keep their original replica position.
+type planReview struct {
+	original *kafkazk.PartitionMap
+	planned  *kafkazk.PartitionMap
+	bm1      kafkazk.BrokerMap
+	bm2      kafkazk.BrokerMap
+	pmm      kafkazk.PartitionMetaMap
+	psf      float64
+	excluded map[int]bool
+	pins     map[int][]int
+}
+
+// newPlanReview takes the original and planned *PartitionMap (in the
+// same partition order), the original and planned BrokerMap, a
+// PartitionMetaMap (nil if metrics aren't used) and a partition size
+// factor and returns a *planReview without any edits.
+func newPlanReview(pm1, pm2 *kafkazk.PartitionMap, bm1, bm2 kafkazk.BrokerMap, pmm kafkazk.PartitionMetaMap, psf float64) *planReview {
+	if psf <= 0 {
+		psf = 1
+	}
+
+	return &planReview{
+		original: pm1,
+		planned:  pm2,
+		bm1:      bm1,
+		bm2:      bm2,
+		pmm:      pmm,
+		psf:      psf,
+		excluded: map[int]bool{},
+		pins:     map[int][]int{},
+	}
+}
+
+// lookup returns the indexes of the partitions of the topic, or
+// only the partition if partition is >= 0. An error is returned
+// if no partitions match.
+func (r *planReview) lookup(topic string, partition int) ([]int, error) {
+	var idx []int
+	for i, partn := range r.planned.Partitions {
+		if partn.Topic == topic && (partition < 0 || partn.Partition == partition) {
+			idx = append(idx, i)
+		}
+	}
+
+	if len(idx) == 0 {
+		if partition < 0 {
+			return nil, fmt.Errorf("topic %s not found in the plan", topic)
+		}
+		return nil, fmt.Errorf("%s p%d not found in the plan", topic, partition)
+	}
+
+	return idx, nil
+}
+
+// exclude reverts the partition (or all partitions of the
+// topic if partition is < 0) to its original replica set.
+func (r *planReview) exclude(topic string, partition int) error {
+	idx, err := r.lookup(topic, partition)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range idx {
+		r.excluded[i] = true
+	}
+
+	return nil
+}
+
+// include restores the planned replica set of the partition
+// (or all partitions of the topic if partition is < 0).
+func (r *planReview) include(topic string, partition int) error {
+	idx, err := r.lookup(topic, partition)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range idx {
+		delete(r.excluded, i)
+	}
+
+	return nil
+}
+
+// pin keeps the replica of the partition on the broker at its
+// original position. An error is returned if the broker doesn't
+// hold a replica of the partition in the original map.
+func (r *planReview) pin(topic string, partition, id int) error {
+	if partition < 0 {
+		return fmt.Errorf("invalid partition %d", partition)
+	}
+
+	idx, err := r.lookup(topic, partition)
+	if err != nil {
+		return err
+	}
+
+	i := idx[0]
+	pos := position(id, r.original.Partitions[i].Replicas)
+
+	switch {
+	case pos < 0:
+		return fmt.Errorf("broker %d doesn't hold a replica of %s p%d", id, topic, partition)
+	case pos >= len(r.planned.Partitions[i].Replicas):
+		return fmt.Errorf("replica %d of %s p%d is removed in the plan", pos, topic, partition)
+	case position(id, r.pins[i]) >= 0:
+		return nil
+	}
+
+	r.pins[i] = append(r.pins[i], id)
+
+	return nil
+}
+
+// unpin removes the pin of the broker (or all
+// pins if id is < 0) from the partition.
+func (r *planReview) unpin(topic string, partition, id int) error {
+	if partition < 0 {
+		return fmt.Errorf("invalid partition %d", partition)
+	}
+
+	idx, err := r.lookup(topic, partition)
+	if err != nil {
+		return err
+	}
+
+	i := idx[0]
+	if id < 0 {
+		delete(r.pins, i)
+		return nil
+	}
+
+	pos := position(id, r.pins[i])
+	if pos < 0 {
+		return fmt.Errorf("broker %d isn't pinned for %s p%d", id, topic, partition)
+	}
+
+	r.pins[i] = append(r.pins[i][:pos], r.pins[i][pos+1:]...)
+	if len(r.pins[i]) == 0 {
+		delete(r.pins, i)
+	}
+
+	return nil
+}
+
+// partition returns the edited partition at index i.
+func (r *planReview) partition(i int) kafkazk.Partition {
+	if r.excluded[i] {
+		return copyPartition(r.original.Partitions[i])
+	}
+
+	partn := copyPartition(r.planned.Partitions[i])
+
+	for _, id := range r.pins[i] {
+		pos := position(id, r.original.Partitions[i].Replicas)
+
+		// Swap the pinned broker into its original
+		// position if it's still in the replica set.
+		// Otherwise, it replaces the planned replica.
+		if cur := position(id, partn.Replicas); cur >= 0 {
+			partn.Replicas[pos], partn.Replicas[cur] = partn.Replicas[cur], partn.Replicas[pos]
+			if partn.HasLogDirs() {
+				partn.LogDirs[pos], partn.LogDirs[cur] = partn.LogDirs[cur], partn.LogDirs[pos]
+			}
+		} else {
+			partn.Replicas[pos] = id
+		}
+
+		// The pinned replica isn't moved.
+		if partn.HasLogDirs() {
+			partn.LogDirs[pos] = kafkazk.AnyLogDir
+		}
+	}
+
+	// Pinned replicas are reset to AnyLogDir above. If the pins
+	// cover every replica with an assigned log dir, no log dirs
+	// remain and they're dropped from the partition.
+	if partn.HasLogDirs() {
+		var assigned bool
+		for i := range partn.Replicas {
+			assigned = assigned || partn.LogDir(i) != kafkazk.AnyLogDir
+		}
+
+		if !assigned {
+			partn.LogDirs = nil
+		}
+	}
+
+	return partn
+}
+
+// partitionMap returns the edited *PartitionMap.
+func (r *planReview) partitionMap() *kafkazk.PartitionMap {
+	pm := kafkazk.NewPartitionMap()
+	for i := range r.planned.Partitions {
+		pm.Partitions = append(pm.Partitions, r.partition(i))
+	}
+
+	return pm
+}
+
+// edited returns a *PartitionMap of the
+// excluded and pinned partitions.
+func (r *planReview) edited() *kafkazk.PartitionMap {
+	pm := kafkazk.NewPartitionMap()
+	for i := range r.planned.Partitions {
+		if r.excluded[i] || len(r.pins[i]) > 0 {
+			pm.Partitions = append(pm.Partitions, r.partition(i))
+		}
+	}
+
+	return pm
+}
+
+// violations returns an error for each constraint of the plan broken by
+// edits, which restore original replicas: replicas on brokers marked for
+// replacement, rack ID violations (see rackIDViolations) and placement
+// policy violations.
+func (r *planReview) violations(minRackIDs int, policies topicPolicies, pp kafkazk.PlacementPolicies) errors {
+	var errs errors
+
+	pm := r.edited()
+
+	for _, partn := range pm.Partitions {
+		for _, id := range partn.Replicas {
+			if b, exist := r.bm2[id]; exist && b.Replace {
+				errs = append(errs, fmt.Errorf("%s p%d: broker %d is marked for replacement",
+					partn.Topic, partn.Partition, id))
+			}
+		}
+	}
+
+	errs = append(errs, rackIDViolations(pm, r.bm2, minRackIDs, pp)...)
+	errs = append(errs, policies.violations(pm, r.bm2, pp)...)
+
+	return errs
+}
+
+// brokerMap returns the planned BrokerMap with storage free
+// adjusted for the replicas changed by edits.
+func (r *planReview) brokerMap() kafkazk.BrokerMap {
+	bm := r.bm2.Copy()
+	if r.pmm == nil {
+		return bm
+	}
+
+	for i, planned := range r.planned.Partitions {
+		edited := r.partition(i)
+
+		size, err := r.pmm.Size(planned)
+		if err != nil {
+			continue
+		}
+
+		size *= r.psf
+
+		for _, id := range planned.Replicas {
+			if b, exist := bm[id]; exist && notInReplicaSet(id, edited.Replicas) {
+				b.StorageFree += size
+			}
+		}
+
+		for _, id := range edited.Replicas {
+			if b, exist := bm[id]; exist && notInReplicaSet(id, planned.Replicas) {
+				b.StorageFree -= size
+			}
+		}
+	}
+
+	return bm
+}
+
+// status returns the edit status of the partition at index i.
+func (r *planReview) status(i int) string {
+	switch {
+	case r.excluded[i]:
+		return "[excluded]"
+	case len(r.pins[i]) > 0:
+		return fmt.Sprintf("[pinned: %v]", r.pins[i])
+	}
+
+	return ""
+}
+
+// printTopics prints the number of changed
+// and excluded partitions of each topic.
+func (r *planReview) printTopics() {
+	var topics []string
+	changed, excluded, total := map[string]int{}, map[string]int{}, map[string]int{}
+
+	for i, partn := range r.original.Partitions {
+		if _, exist := total[partn.Topic]; !exist {
+			topics = append(topics, partn.Topic)
+		}
+
+		total[partn.Topic]++
+		if !partn.Equal(r.partition(i)) {
+			changed[partn.Topic]++
+		}
+		if r.excluded[i] {
+			excluded[partn.Topic]++
+		}
+	}
+
+	sort.Strings(topics)
+
+	fmt.Println("\nTopics:")
+	for _, t := range topics {
+		fmt.Printf("%s%s - changed: %d, excluded: %d, total: %d\n",
+			indent, t, changed[t], excluded[t], total[t])
+	}
+}
+
+// printPartitions prints the original and edited replica sets of
+// the partitions at the indexes that are changed or edited.
+func (r *planReview) printPartitions(idx []int) {
+	var n int
+	for _, i := range idx {
+		partn, edited := r.original.Partitions[i], r.partition(i)
+		if partn.Equal(edited) && r.status(i) == "" {
+			continue
+		}
+
+		n++
+		line := fmt.Sprintf("%s p%d: %v -> %v %s %s", partn.Topic, partn.Partition,
+			partn.Replicas, edited.Replicas, whatChanged(partn.Replicas, edited.Replicas), r.status(i))
+		fmt.Printf("%s%s\n", indent, strings.TrimSpace(line))
+	}
+
+	if n == 0 {
+		fmt.Printf("%s[none]\n", indent)
+	}
+}
+
+// printTopic prints the changes of the topic.
+func (r *planReview) printTopic(topic string) error {
+	idx, err := r.lookup(topic, -1)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%s changes:\n", topic)
+	r.printPartitions(idx)
+
+	return nil
+}
+
+// printBrokers prints the number of replicas
+// moved to and from each broker.
+func (r *planReview) printBrokers() {
+	in, out := map[int]int{}, map[int]int{}
+
+	for i, partn := range r.original.Partitions {
+		edited := r.partition(i)
+		for _, id := range partn.Replicas {
+			if notInReplicaSet(id, edited.Replicas) {
+				out[id]++
+			}
+		}
+		for _, id := range edited.Replicas {
+			if notInReplicaSet(id, partn.Replicas) {
+				in[id]++
+			}
+		}
+	}
+
+	var ids []int
+	for id := range in {
+		ids = append(ids, id)
+	}
+	for id := range out {
+		if _, exist := in[id]; !exist {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Ints(ids)
+
+	fmt.Println("\nBroker replica changes:")
+	for _, id := range ids {
+		fmt.Printf("%sBroker %d - in: %d, out: %d\n", indent, id, in[id], out[id])
+	}
+
+	if len(ids) == 0 {
+		fmt.Printf("%s[none]\n", indent)
+	}
+}
+
+// printBroker prints the changes moving
+// replicas to or from the broker.
+func (r *planReview) printBroker(id int) {
+	var idx []int
+	for i, partn := range r.original.Partitions {
+		edited := r.partition(i)
+		if notInReplicaSet(id, partn.Replicas) != notInReplicaSet(id, edited.Replicas) {
+			idx = append(idx, i)
+		}
+	}
+
+	fmt.Printf("\nBroker %d changes:\n", id)
+	r.printPartitions(idx)
+}
+
+// printStats prints the broker assignment stats of the edited plan.
+func (r *planReview) printStats(cmd *cobra.Command) {
+	errs := printBrokerAssignmentStats(cmd, r.original, r.partitionMap(), r.bm1, r.brokerMap())
+
+	if len(errs) > 0 {
+		fmt.Println("\nWARN:")
+		sort.Sort(errs)
+		for _, err := range errs {
+			fmt.Printf("%s%s\n", indent, err)
+		}
+	}
+}
+
+const planReviewHelp = `
+Commands:
+  topics                              list topics with changed and excluded partition counts
+  topic <topic>                       show the changes of a topic
+  brokers                             list replicas moved to and from each broker
+  broker <id>                         show the changes moving replicas to or from a broker
+  changes                             show all partition map changes
+  stats                               show the broker distribution of the edited plan
+  exclude <topic> [partition]         keep the original replica set of a topic or partition
+  include <topic> [partition]         restore the planned replica set of a topic or partition
+  pin <topic> <partition> <id>        keep the replica on broker <id> in its original position
+  unpin <topic> <partition> [id]      remove the pin of broker <id> (or all pins)
+  write                               write the edited plan and exit the review
+  quit                                exit without writing maps`
+
+// run reads review commands from the io.Reader until the plan is
+// written or the review is quit and returns whether to write the plan.
+// The broker stats are printed after each edit.
+func (r *planReview) run(cmd *cobra.Command, in io.Reader) bool {
+	fmt.Println("\nInteractive review:")
+	fmt.Printf("%sEdit the plan before writing maps; 'help' lists commands\n", indent)
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Print("\n> ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return false
+		}
+
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			continue
+		}
+
+		var err error
+		var edited bool
+
+		switch args[0] {
+		case "help":
+			fmt.Println(planReviewHelp)
+		case "topics":
+			r.printTopics()
+		case "topic":
+			if len(args) != 2 {
+				err = fmt.Errorf("usage: topic <topic>")
+				break
+			}
+			err = r.printTopic(args[1])
+		case "brokers":
+			r.printBrokers()
+		case "broker":
+			if len(args) != 2 {
+				err = fmt.Errorf("usage: broker <id>")
+				break
+			}
+
+			var id int
+			if id, err = reviewInt(args[1]); err == nil {
+				r.printBroker(id)
+			}
+		case "changes":
+			printMapChanges(r.original, r.partitionMap())
+		case "stats":
+			r.printStats(cmd)
+		case "exclude", "include":
+			if len(args) < 2 || len(args) > 3 {
+				err = fmt.Errorf("usage: %s <topic> [partition]", args[0])
+				break
+			}
+
+			p := -1
+			if len(args) == 3 {
+				if p, err = reviewInt(args[2]); err != nil {
+					break
+				}
+			}
+
+			if args[0] == "exclude" {
+				err = r.exclude(args[1], p)
+			} else {
+				err = r.include(args[1], p)
+			}
+			edited = err == nil
+		case "pin", "unpin":
+			min := 4
+			if args[0] == "unpin" {
+				min = 3
+			}
+
+			if len(args) < min || len(args) > 4 {
+				err = fmt.Errorf("usage: pin <topic> <partition> <id>, unpin <topic> <partition> [id]")
+				break
+			}
+
+			var p int
+			id := -1
+			if p, err = reviewInt(args[2]); err != nil {
+				break
+			}
+			if len(args) == 4 {
+				if id, err = reviewInt(args[3]); err != nil {
+					break
+				}
+			}
+
+			if args[0] == "pin" {
+				err = r.pin(args[1], p, id)
+			} else {
+				err = r.unpin(args[1], p, id)
+			}
+			edited = err == nil
+		case "write":
+			return true
+		case "quit", "exit":
+			return false
+		default:
+			err = fmt.Errorf("unknown command '%s'; 'help' lists commands", args[0])
+		}
+
+		if err != nil {
+			fmt.Printf("%s%s\n", indent, err)
+		}
+
+		if edited {
+			r.printStats(cmd)
+		}
+	}
+}
+
+// reviewInt parses a partition
+// or broker ID review argument.
+func reviewInt(s string) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number '%s'", s)
+	}
+
+	return v, nil
+}
+
+// copyPartition returns a copy of the partition.
+func copyPartition(p kafkazk.Partition) kafkazk.Partition {
+	pm := kafkazk.NewPartitionMap()
+	pm.Partitions = append(pm.Partitions, p)
+
+	return pm.Copy().Partitions[0]
+}
+
+// position returns the index of the
+// broker in the replica set, or -1.
+func position(id int, replicas []int) int {
+	for i, r := range replicas {
+		if r == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// reviewPlan starts an interactive review of the planned *PartitionMap
+// if --interactive is set and returns the edited map and BrokerMap. The
+// original and planned BrokerMaps and the PartitionMetaMap are used for
+// the broker stats. Any replacement, rack ID or policy violations of
+// edited partitions are handled as overridable errors. The command
+// exits without writing maps if the review is quit.
+func reviewPlan(cmd *cobra.Command, pm1, pm2 *kafkazk.PartitionMap, bm1, bm2 kafkazk.BrokerMap, pmm kafkazk.PartitionMetaMap, policies topicPolicies, pp kafkazk.PlacementPolicies) (*kafkazk.PartitionMap, kafkazk.BrokerMap) {
+	if i, _ := cmd.Flags().GetBool("interactive"); !i {
+		return pm2, bm2
+	}
+
+	psf, _ := cmd.Flags().GetFloat64("partition-size-factor")
+	r := newPlanReview(pm1, pm2, bm1, bm2, pmm, psf)
+
+	if !r.run(cmd, os.Stdin) {
+		fmt.Println("\nReview quit; no maps written")
+		os.Exit(0)
+	}
+
+	pm := r.partitionMap()
+	printMapChanges(pm1, pm)
+
+	// Edits may break constraints the
+	// plan was validated against.
+	if len(r.excluded) > 0 || len(r.pins) > 0 {
+		mrrid, _ := cmd.Flags().GetInt("min-rack-ids")
+		handleOverridableErrs(cmd, r.violations(mrrid, policies, pp))
+	}
+
+	return pm, r.brokerMap()
+}
@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+func testPlanReview() *planReview {
+	pm1, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":1,"replicas":[1002,1001]},
+		{"topic":"test_topic2","partition":0,"replicas":[1001,1002]}]}`)
+
+	pm2, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1003,1002],"log_dirs":["/data1","any"]},
+		{"topic":"test_topic","partition":1,"replicas":[1001,1003]},
+		{"topic":"test_topic2","partition":0,"replicas":[1003,1002]}]}`)
+
+	pmm := kafkazk.NewPartitionMetaMap()
+	pmm["test_topic"] = map[int]*kafkazk.PartitionMeta{
+		0: &kafkazk.PartitionMeta{Size: 10 * div},
+		1: &kafkazk.PartitionMeta{Size: 20 * div},
+	}
+	pmm["test_topic2"] = map[int]*kafkazk.PartitionMeta{
+		0: &kafkazk.PartitionMeta{Size: 30 * div},
+	}
+
+	bm1 := kafkazk.BrokerMap{
+		1001: &kafkazk.Broker{ID: 1001, StorageFree: 100 * div},
+		1002: &kafkazk.Broker{ID: 1002, StorageFree: 100 * div},
+		1003: &kafkazk.Broker{ID: 1003, StorageFree: 100 * div},
+	}
+
+	// The planned storage moves 10GB, 20GB and
+	// 30GB from 1001 and 1002 to 1003.
+	bm2 := bm1.Copy()
+	bm2[1001].StorageFree += 40 * div
+	bm2[1002].StorageFree += 20 * div
+	bm2[1003].StorageFree -= 60 * div
+
+	return newPlanReview(pm1, pm2, bm1, bm2, pmm, 1)
+}
+
+func TestPlanReviewExclude(t *testing.T) {
+	r := testPlanReview()
+
+	if err := r.exclude("test_topic", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.exclude("test_topic2", -1); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range []error{r.exclude("test_topic", 5), r.exclude("test_topic3", -1)} {
+		if err == nil {
+			t.Error("Expected error for unknown partition")
+		}
+	}
+
+	pm := r.partitionMap()
+	expected := [][]int{{1001, 1002}, {1001, 1003}, {1001, 1002}}
+
+	for i, partn := range pm.Partitions {
+		if !partn.Equal(kafkazk.Partition{Topic: partn.Topic, Partition: partn.Partition, Replicas: expected[i]}) {
+			t.Errorf("Expected %v, got %v", expected[i], partn)
+		}
+	}
+
+	// Only the 20GB test_topic p1 move from 1002 to 1003 remains.
+	bm := r.brokerMap()
+	for id, free := range map[int]float64{1001: 100, 1002: 120, 1003: 80} {
+		if bm[id].StorageFree != free*div {
+			t.Errorf("Expected broker %d storage free %.2f, got %.2f", id, free, bm[id].StorageFree/div)
+		}
+	}
+
+	if err := r.include("test_topic2", -1); err != nil {
+		t.Fatal(err)
+	}
+
+	if replicas := r.partitionMap().Partitions[2].Replicas; replicas[0] != 1003 {
+		t.Errorf("Expected planned replicas [1003 1002], got %v", replicas)
+	}
+}
+
+func TestPlanReviewPin(t *testing.T) {
+	r := testPlanReview()
+
+	// 1001 replaces the planned 1003 at the leader position.
+	if err := r.pin("test_topic", 0, 1001); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1001 is swapped back into the follower position.
+	if err := r.pin("test_topic", 1, 1001); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.pin("test_topic", 0, 1003); err == nil {
+		t.Error("Expected error for broker not in the original replica set")
+	}
+
+	if err := r.pin("test_topic", -1, 1002); err == nil {
+		t.Error("Expected error for negative partition")
+	}
+
+	pm := r.partitionMap()
+	expected := [][]int{{1001, 1002}, {1003, 1001}, {1003, 1002}}
+
+	for i, partn := range pm.Partitions {
+		for j := range expected[i] {
+			if partn.Replicas[j] != expected[i][j] {
+				t.Errorf("Expected %v, got %v", expected[i], partn.Replicas)
+				break
+			}
+		}
+	}
+
+	// No log dirs remain assigned.
+	if pm.Partitions[0].HasLogDirs() {
+		t.Errorf("Expected no log dirs, got %v", pm.Partitions[0].LogDirs)
+	}
+
+	if err := r.unpin("test_topic", 1, 1002); err == nil {
+		t.Error("Expected error for broker not pinned")
+	}
+
+	if err := r.unpin("test_topic", -1, -1); err == nil {
+		t.Error("Expected error for negative partition")
+	}
+
+	if err := r.unpin("test_topic", 0, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	if partn := r.partitionMap().Partitions[0]; partn.Replicas[0] != 1003 || partn.LogDir(0) != "/data1" {
+		t.Errorf("Expected planned replicas and log dirs, got %v", partn)
+	}
+}
+
+func TestPlanReviewRun(t *testing.T) {
+	r := testPlanReview()
+	cmd := &cobra.Command{}
+
+	in := "topics\nexclude test_topic\npin test_topic2 0 1001\npin test_topic2 x 1001\nwrite\n"
+	if !r.run(cmd, strings.NewReader(in)) {
+		t.Error("Expected write")
+	}
+
+	if len(r.excluded) != 2 || len(r.pins) != 1 {
+		t.Errorf("Unexpected edits: excluded %v, pins %v", r.excluded, r.pins)
+	}
+
+	// EOF quits the review.
+	if r.run(cmd, strings.NewReader("stats\n")) {
+		t.Error("Expected quit")
+	}
+}
+
+func TestPlanReviewViolations(t *testing.T) {
+	r := testPlanReview()
+
+	if errs := r.violations(0, nil, nil); len(errs) != 0 {
+		t.Errorf("Expected no violations without edits, got %v", errs)
+	}
+
+	// 1001 is being replaced and shares
+	// a rack ID with 1002.
+	r.bm2[1001].Replace = true
+	r.bm2[1001].Locality, r.bm2[1002].Locality, r.bm2[1003].Locality = "a", "a", "b"
+
+	// test_topic2 is pinned to 1002 and 1003.
+	policies := topicPolicies{&topicPolicy{Topics: "test_topic2", Brokers: []int{1002, 1003}}}
+	policies[0].topicsRegex = regexpMustCompile(t, policies[0].Topics)
+	pp, _ := policies.placementPolicies(r.planned, nil)
+
+	// [1001 1002] replacement, racks, reserved 1002.
+	r.pin("test_topic", 0, 1001)
+	// [1001 1002] replacement, racks, policy.
+	r.exclude("test_topic2", -1)
+
+	if errs := r.violations(0, policies, pp); len(errs) != 6 {
+		t.Errorf("Expected 6 violations, got %d: %v", len(errs), errs)
+	}
+}
@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+// logDirState tracks the storage free and total
+// of each log dir of each broker for log dir
+// placement, keyed by broker ID.
+type logDirState map[int]kafkazk.LogDirMetaMap
+
+// newLogDirState takes a BrokerMetaMap and returns a logDirState
+// populated with a copy of the log dir metrics of each broker.
+func newLogDirState(bmm kafkazk.BrokerMetaMap) logDirState {
+	s := logDirState{}
+	for id, m := range bmm {
+		if len(m.LogDirs) > 0 {
+			s[id] = m.LogDirs.Copy()
+		}
+	}
+
+	return s
+}
+
+// usesTotals returns whether all log dirs
+// of the broker have a storage total.
+func (s logDirState) usesTotals(id int) bool {
+	for _, m := range s[id] {
+		if m.StorageTotal <= 0 {
+			return false
+		}
+	}
+
+	return len(s[id]) > 0
+}
+
+// value returns the placement value of a broker log dir where lower
+// values are preferred: the storage used percentage if all log dirs
+// of the broker have a storage total, otherwise negative storage free.
+func (s logDirState) value(id int, dir string) float64 {
+	m := s[id][dir]
+	if s.usesTotals(id) {
+		return (m.StorageTotal - m.StorageFree) / m.StorageTotal * 100
+	}
+
+	return -m.StorageFree
+}
+
+// best returns the log dir of the broker with the lowest value.
+// Ties are broken by log dir path. AnyLogDir is returned if the
+// broker has no log dir metrics.
+func (s logDirState) best(id int) string {
+	dirs := make([]string, 0, len(s[id]))
+	for dir := range s[id] {
+		dirs = append(dirs, dir)
+	}
+
+	if len(dirs) == 0 {
+		return kafkazk.AnyLogDir
+	}
+
+	sort.Strings(dirs)
+
+	best := dirs[0]
+	for _, dir := range dirs[1:] {
+		if s.value(id, dir) < s.value(id, best) {
+			best = dir
+		}
+	}
+
+	return best
+}
+
+// assignLogDirs takes the original and updated *PartitionMap, a
+// PartitionMetaMap and a BrokerMetaMap and sets a log dir for each
+// replica newly placed on a broker in the updated map. Replicas are
+// assigned, largest partitions first, to the broker log dir with the
+// lowest storage used percentage (or most storage free if any log dir
+// lacks a storage total), accounting for each replica assigned. Replicas
+// already on a broker are left as AnyLogDir. Log dirs are only set on
+// partitions with at least one log dir assigned. An error is returned
+// for each broker without log dir metrics and each partition without
+// a size.
+func assignLogDirs(pm1, pm2 *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap, bmm kafkazk.BrokerMetaMap) errors {
+	var errs errors
+
+	s := newLogDirState(bmm)
+
+	current := map[string]map[int][]int{}
+	for _, partn := range pm1.Partitions {
+		if _, exist := current[partn.Topic]; !exist {
+			current[partn.Topic] = map[int][]int{}
+		}
+		current[partn.Topic][partn.Partition] = partn.Replicas
+	}
+
+	// Assign the largest partitions first.
+	order := make([]int, len(pm2.Partitions))
+	sizes := make([]float64, len(pm2.Partitions))
+	for i, partn := range pm2.Partitions {
+		order[i] = i
+		size, err := pmm.Size(partn)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s p%d: no size metrics; log dirs assigned by storage free only", partn.Topic, partn.Partition))
+		}
+		sizes[i] = size
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return sizes[order[i]] > sizes[order[j]]
+	})
+
+	missing := map[int]struct{}{}
+
+	for _, i := range order {
+		partn := pm2.Partitions[i]
+		dirs := make([]string, len(partn.Replicas))
+		var assigned bool
+
+		for n, id := range partn.Replicas {
+			dirs[n] = kafkazk.AnyLogDir
+
+			if !notInReplicaSet(id, current[partn.Topic][partn.Partition]) {
+				continue
+			}
+
+			dir := s.best(id)
+			if dir == kafkazk.AnyLogDir {
+				missing[id] = struct{}{}
+				continue
+			}
+
+			s[id][dir].StorageFree -= sizes[i]
+			dirs[n] = dir
+			assigned = true
+		}
+
+		if assigned {
+			pm2.Partitions[i].LogDirs = dirs
+		}
+	}
+
+	var ids []int
+	for id := range missing {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		errs = append(errs, fmt.Errorf("Broker %d has no log dir metrics; log dirs left as '%s'", id, kafkazk.AnyLogDir))
+	}
+
+	return errs
+}
+
+// usesLogDirMetrics returns whether the command assigns log dirs,
+// which requires per log dir broker metrics and partition sizes but
+// not storage placement.
+func usesLogDirMetrics(cmd *cobra.Command) bool {
+	ld, _ := cmd.Flags().GetBool("log-dirs")
+	return ld
+}
+
+// printLogDirAssignments prints the log dir of each replica
+// for all partitions in the *PartitionMap with log dirs set.
+func printLogDirAssignments(pm *kafkazk.PartitionMap) {
+	fmt.Println("\nLog dir assignments:")
+
+	var n int
+	for _, partn := range pm.Partitions {
+		if !partn.HasLogDirs() {
+			continue
+		}
+
+		n++
+		fmt.Printf("%s%s p%d:", indent, partn.Topic, partn.Partition)
+		for i, id := range partn.Replicas {
+			fmt.Printf(" %d:%s", id, partn.LogDir(i))
+		}
+		fmt.Println()
+	}
+
+	if n == 0 {
+		fmt.Printf("%s[none]\n", indent)
+	}
+}
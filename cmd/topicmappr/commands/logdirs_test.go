@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func TestAssignLogDirs(t *testing.T) {
+	pm1, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":1,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":2,"replicas":[1001,1002]}]}`)
+
+	pm2, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1003,1002]},
+		{"topic":"test_topic","partition":1,"replicas":[1003,1004]},
+		{"topic":"test_topic","partition":2,"replicas":[1001,1002]}]}`)
+
+	pmm := kafkazk.NewPartitionMetaMap()
+	pmm["test_topic"] = map[int]*kafkazk.PartitionMeta{
+		0: &kafkazk.PartitionMeta{Size: 300},
+		1: &kafkazk.PartitionMeta{Size: 200},
+		2: &kafkazk.PartitionMeta{Size: 100},
+	}
+
+	bmm := kafkazk.BrokerMetaMap{
+		1001: &kafkazk.BrokerMeta{},
+		1002: &kafkazk.BrokerMeta{},
+		1003: &kafkazk.BrokerMeta{
+			LogDirs: kafkazk.LogDirMetaMap{
+				"/data1": &kafkazk.LogDirMeta{StorageFree: 1000},
+				"/data2": &kafkazk.LogDirMeta{StorageFree: 900},
+			},
+		},
+		1004: &kafkazk.BrokerMeta{},
+	}
+
+	errs := assignLogDirs(pm1, pm2, pmm, bmm)
+
+	// p0 is placed first on /data1, leaving it with 700
+	// free; p1 is then placed on /data2. 1004 has no log
+	// dir metrics.
+	expected := [][]string{
+		{"/data1", kafkazk.AnyLogDir},
+		{"/data2", kafkazk.AnyLogDir},
+		nil,
+	}
+
+	for i, partn := range pm2.Partitions {
+		if len(partn.LogDirs) != len(expected[i]) {
+			t.Errorf("p%d: expected log dirs %v, got %v", i, expected[i], partn.LogDirs)
+			continue
+		}
+
+		for n := range partn.LogDirs {
+			if partn.LogDirs[n] != expected[i][n] {
+				t.Errorf("p%d: expected log dirs %v, got %v", i, expected[i], partn.LogDirs)
+			}
+		}
+	}
+
+	if len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %v", errs)
+	}
+
+	// The broker metadata isn't modified.
+	if f := bmm[1003].LogDirs["/data1"].StorageFree; f != 1000 {
+		t.Errorf("Unexpected broker metadata modification")
+	}
+
+	// With storage totals, the lowest storage used percentage
+	// is preferred over the most storage free.
+	bmm[1003].LogDirs["/data1"].StorageTotal = 4000
+	bmm[1003].LogDirs["/data2"].StorageTotal = 1000
+
+	s := newLogDirState(bmm)
+	if dir := s.best(1003); dir != "/data2" {
+		t.Errorf("Expected log dir /data2, got %s", dir)
+	}
+}
@@ -1,8 +1,10 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/DataDog/kafka-kit/kafkazk"
@@ -60,6 +62,48 @@ func ensureBrokerMetrics(cmd *cobra.Command, bm kafkazk.BrokerMap, bmm kafkazk.B
 	}
 }
 
+// getStorageCapacities returns a map of broker IDs to storage capacities
+// in bytes from the --storage-capacity-map flag, which is a JSON map of
+// broker IDs to GB. A nil map is returned if the flag isn't set.
+func getStorageCapacities(cmd *cobra.Command) (map[int]float64, error) {
+	cm := cmd.Flag("storage-capacity-map").Value.String()
+	if cm == "" {
+		return nil, nil
+	}
+
+	capMap := map[string]float64{}
+	if err := json.Unmarshal([]byte(cm), &capMap); err != nil {
+		return nil, fmt.Errorf("invalid --storage-capacity-map: %s", err)
+	}
+
+	c := map[int]float64{}
+	for k, v := range capMap {
+		id, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --storage-capacity-map: broker ID '%s'", k)
+		}
+
+		if v <= 0 {
+			return nil, fmt.Errorf("invalid --storage-capacity-map: broker %d capacity must be > 0", id)
+		}
+
+		c[id] = v * div
+	}
+
+	return c, nil
+}
+
+// setStorageCapacities sets the StorageTotal of each broker in the
+// BrokerMetaMap found in the map of broker IDs to capacities in bytes,
+// overriding any capacities from broker metrics.
+func setStorageCapacities(bmm kafkazk.BrokerMetaMap, c map[int]float64) {
+	for id, v := range c {
+		if m, exist := bmm[id]; exist {
+			m.StorageTotal = v
+		}
+	}
+}
+
 // getPartitionMeta returns a map of topic, partition metadata
 // persisted in ZooKeeper (via an external mechanism*). This is
 // primarily partition size metrics data used for the storage
@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+// moveCost describes the cost of relocating a replica of a
+// partition. Costs are in GB-equivalents so that they're
+// comparable with storage offloaded.
+type moveCost interface {
+	// cost returns the cost of relocating the replica of
+	// the partition; leader is whether the replica is the
+	// partition leader.
+	cost(partn kafkazk.Partition, leader bool) float64
+	// String describes the cost model.
+	String() string
+}
+
+// moveCostDimensions lists the valid weightedMoveCost dimensions.
+var moveCostDimensions = []string{"leader", "size", "throughput"}
+
+// weightedMoveCost is a moveCost summing weighted dimensions. The size
+// dimension is the GB moved. The throughput dimension is the partition
+// bytes in rate relative to the mean (the recent data that must catch up
+// during the move), where the mean rate costs the mean partition size.
+// The leader dimension costs the mean partition size for leader replicas.
+// A size weight of 1 alone is equal to the GB moved.
+type weightedMoveCost struct {
+	weights     map[string]float64
+	pmm         kafkazk.PartitionMetaMap
+	meanSize    float64 // In GB.
+	meanBytesIn float64 // In bytes/s.
+}
+
+// parseMoveCostWeights parses a comma delimited list of
+// dimension=weight pairs, e.g. "size=1,throughput=0.5,leader=0.2".
+func parseMoveCostWeights(s string) (map[string]float64, error) {
+	w := map[string]float64{}
+
+	for _, kv := range strings.Split(s, ",") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+
+		parts := strings.Split(kv, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid weight '%s'", kv)
+		}
+
+		dim := strings.TrimSpace(parts[0])
+		v, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight '%s'", kv)
+		}
+
+		var valid bool
+		for _, d := range moveCostDimensions {
+			valid = valid || d == dim
+		}
+
+		switch _, exist := w[dim]; {
+		case !valid:
+			return nil, fmt.Errorf("unknown move cost dimension '%s'", dim)
+		case exist:
+			return nil, fmt.Errorf("duplicate weight '%s'", dim)
+		case v < 0:
+			return nil, fmt.Errorf("weight '%s' must be >= 0", dim)
+		}
+
+		w[dim] = v
+	}
+
+	// Size is required to relate costs
+	// to the storage offloaded.
+	if w["size"] == 0 {
+		return nil, fmt.Errorf("weight 'size' must be > 0")
+	}
+
+	return w, nil
+}
+
+// newWeightedMoveCost takes dimension weights, a *PartitionMap and a
+// PartitionMetaMap and returns a *weightedMoveCost with the means of
+// the partitions in the map.
+func newWeightedMoveCost(w map[string]float64, pm *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap) *weightedMoveCost {
+	c := &weightedMoveCost{weights: w, pmm: pmm}
+
+	var n float64
+	for _, partn := range pm.Partitions {
+		size, err := pmm.Size(partn)
+		if err != nil {
+			continue
+		}
+
+		c.meanSize += size / div
+		c.meanBytesIn += pmm[partn.Topic][partn.Partition].BytesIn
+		n++
+	}
+
+	if n > 0 {
+		c.meanSize /= n
+		c.meanBytesIn /= n
+	}
+
+	return c
+}
+
+// cost implements moveCost.
+func (c *weightedMoveCost) cost(partn kafkazk.Partition, leader bool) float64 {
+	size, _ := c.pmm.Size(partn)
+	cost := c.weights["size"] * size / div
+
+	if c.meanBytesIn > 0 {
+		if m, exist := c.pmm[partn.Topic][partn.Partition]; exist {
+			cost += c.weights["throughput"] * m.BytesIn / c.meanBytesIn * c.meanSize
+		}
+	}
+
+	if leader {
+		cost += c.weights["leader"] * c.meanSize
+	}
+
+	return cost
+}
+
+// String implements moveCost.
+func (c *weightedMoveCost) String() string {
+	var dims []string
+	for d, v := range c.weights {
+		if v > 0 {
+			dims = append(dims, fmt.Sprintf("%s=%.2f", d, v))
+		}
+	}
+
+	sort.Strings(dims)
+
+	return strings.Join(dims, ",")
+}
+
+// getMoveCost returns the moveCost from the --move-cost flag for the
+// *PartitionMap and PartitionMetaMap. A nil moveCost is returned if the
+// flag is unset. An error is returned if the flag is invalid or the
+// throughput dimension is weighted without partition throughput metrics.
+func getMoveCost(cmd *cobra.Command, pm *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap) (moveCost, error) {
+	f := cmd.Flag("move-cost")
+	if f == nil || f.Value.String() == "" {
+		return nil, nil
+	}
+
+	w, err := parseMoveCostWeights(f.Value.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid --move-cost: %s", err)
+	}
+
+	c := newWeightedMoveCost(w, pm, pmm)
+	if w["throughput"] > 0 && c.meanBytesIn == 0 {
+		return nil, fmt.Errorf("--move-cost throughput requires partition bytes in metrics")
+	}
+
+	return c, nil
+}
+
+// costPerGB returns the moveCost of relocating the replica of the
+// partition on the broker per GB of storage offloaded. If no moveCost
+// is configured, all relocations have an equal cost per GB.
+func costPerGB(c moveCost, partn kafkazk.Partition, id int, size float64) float64 {
+	if c == nil || size <= 0 {
+		return 1
+	}
+
+	return c.cost(partn, partn.Replicas[0] == id) / (size / div)
+}
+
+// relocationCost returns the total moveCost of the relocations and
+// swaps, with leaders as of the original *PartitionMap. The GB moved
+// is returned if no moveCost is configured.
+func relocationCost(c moveCost, pm *kafkazk.PartitionMap, relos map[int][]relocation, swaps map[int][]swap, pmm kafkazk.PartitionMetaMap) float64 {
+	leaders := map[string]map[int]int{}
+	for _, partn := range pm.Partitions {
+		if _, exist := leaders[partn.Topic]; !exist {
+			leaders[partn.Topic] = map[int]int{}
+		}
+		leaders[partn.Topic][partn.Partition] = partn.Replicas[0]
+	}
+
+	replicaCost := func(partn kafkazk.Partition, id int) float64 {
+		if c == nil {
+			size, _ := pmm.Size(partn)
+			return size / div
+		}
+		return c.cost(partn, leaders[partn.Topic][partn.Partition] == id)
+	}
+
+	var total float64
+	for id, rs := range relos {
+		for _, r := range rs {
+			total += replicaCost(r.partition, id)
+		}
+	}
+
+	for id, ss := range swaps {
+		for _, s := range ss {
+			total += replicaCost(s.partition, id) + replicaCost(s.peerPartition, s.peer)
+		}
+	}
+
+	return total
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"math"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func testMoveCostInput() (*kafkazk.PartitionMap, kafkazk.PartitionMetaMap) {
+	pm, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001]},
+		{"topic":"test_topic","partition":1,"replicas":[1001]},
+		{"topic":"test_topic","partition":2,"replicas":[1002]}]}`)
+
+	// Mean size 40GB, mean bytes in 10/3 bytes/s.
+	pmm := kafkazk.NewPartitionMetaMap()
+	pmm["test_topic"] = map[int]*kafkazk.PartitionMeta{
+		0: &kafkazk.PartitionMeta{Size: 60 * div, BytesIn: 10},
+		1: &kafkazk.PartitionMeta{Size: 50 * div},
+		2: &kafkazk.PartitionMeta{Size: 10 * div},
+	}
+
+	return pm, pmm
+}
+
+func TestParseMoveCostWeights(t *testing.T) {
+	w, err := parseMoveCostWeights("size=1, throughput=0.5,leader=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w["size"] != 1 || w["throughput"] != 0.5 || w["leader"] != 2 {
+		t.Errorf("Unexpected weights %v", w)
+	}
+
+	for _, s := range []string{"size", "size=a", "size=1,size=2", "bytes=1", "size=-1", "leader=1", ""} {
+		if _, err := parseMoveCostWeights(s); err == nil {
+			t.Errorf("Expected error for '%s'", s)
+		}
+	}
+}
+
+func TestWeightedMoveCost(t *testing.T) {
+	pm, pmm := testMoveCostInput()
+
+	c := newWeightedMoveCost(map[string]float64{"size": 1, "throughput": 1, "leader": 0.5}, pm, pmm)
+
+	// 60GB plus 3x the mean bytes in times the mean size.
+	if cost := c.cost(pm.Partitions[0], false); cost != 180 {
+		t.Errorf("Expected cost 180, got %.2f", cost)
+	}
+
+	// 50GB plus half the mean size.
+	if cost := c.cost(pm.Partitions[1], true); cost != 70 {
+		t.Errorf("Expected cost 70, got %.2f", cost)
+	}
+
+	if s := c.String(); s != "leader=0.50,size=1.00,throughput=1.00" {
+		t.Errorf("Unexpected string %s", s)
+	}
+
+	// Without a moveCost, all relocations
+	// have an equal cost per GB.
+	if cost := costPerGB(nil, pm.Partitions[0], 1001, 60*div); cost != 1 {
+		t.Errorf("Expected cost per GB 1, got %.2f", cost)
+	}
+
+	// 1001 is the p0 leader; 1003 isn't in the replica set.
+	if cost := costPerGB(c, pm.Partitions[0], 1001, 60*div); cost != 200.0/60 {
+		t.Errorf("Expected cost per GB 3.33, got %.2f", cost)
+	}
+
+	if cost := costPerGB(c, pm.Partitions[0], 1003, 60*div); cost != 3 {
+		t.Errorf("Expected cost per GB 3, got %.2f", cost)
+	}
+
+	relos := map[int][]relocation{1001: {{partition: pm.Partitions[1], destination: 1002}}}
+	if cost := relocationCost(nil, pm, relos, nil, pmm); cost != 50 {
+		t.Errorf("Expected cost 50, got %.2f", cost)
+	}
+}
+
+func TestPlanRelocationsForBrokerMoveCost(t *testing.T) {
+	pm, pmm := testMoveCostInput()
+
+	params := func(mc moveCost) planRelocationsForBrokerParams {
+		bm := kafkazk.BrokerMapFromPartitionMap(pm, kafkazk.BrokerMetaMap{}, false)
+		bm[1001].StorageFree, bm[1001].Locality = 50*div, "a"
+		bm[1002].StorageFree, bm[1002].Locality = 200*div, "b"
+
+		return planRelocationsForBrokerParams{
+			sourceID:               1001,
+			relos:                  map[int][]relocation{},
+			mappings:               pm.Mappings(),
+			brokers:                bm,
+			partitionMeta:          pmm,
+			plan:                   relocationPlan{},
+			topPartitionsLimit:     10,
+			partitionSizeThreshold: 512,
+			offloadTargetsMap:      map[int]struct{}{1001: struct{}{}},
+			tolerance:              0.50,
+			moveCost:               mc,
+		}
+	}
+
+	// Without a move cost, the largest partition is relocated.
+	p := params(nil)
+	if n := planRelocationsForBroker(rebalanceCmd, p); n != 1 || p.relos[1001][0].partition.Partition != 0 {
+		t.Errorf("Expected relocation of p0, got %v", p.relos)
+	}
+
+	// The cold p1 has the lowest cost per GB.
+	mc := newWeightedMoveCost(map[string]float64{"size": 1, "throughput": 1}, pm, pmm)
+
+	p = params(mc)
+	if n := planRelocationsForBroker(rebalanceCmd, p); n != 1 || p.relos[1001][0].partition.Partition != 1 {
+		t.Errorf("Expected relocation of p1, got %v", p.relos)
+	}
+
+	// The partition limit applies after ranking by cost.
+	p = params(mc)
+	p.topPartitionsLimit = 1
+	if n := planRelocationsForBroker(rebalanceCmd, p); n != 1 || p.relos[1001][0].partition.Partition != 1 {
+		t.Errorf("Expected relocation of p1, got %v", p.relos)
+	}
+}
+
+func TestSearchStateMoveCost(t *testing.T) {
+	pm, bm, pmm := testSearchInput()
+
+	// Leader moves cost the mean size (183.33GB) extra.
+	mc := newWeightedMoveCost(map[string]float64{"size": 1, "leader": 1}, pm, pmm)
+	s := newSearchState(pm, bm, pmm, searchParams{moveCost: mc})
+
+	s.apply(searchMove{partition: 0, position: 0, from: 1001, to: 1003})
+	if math.Abs(s.moved-283.33) > 0.01 {
+		t.Errorf("Expected moved 283.33, got %.2f", s.moved)
+	}
+
+	s.apply(searchMove{partition: 0, position: 1, from: 1002, to: 1004})
+	if math.Abs(s.moved-383.33) > 0.01 {
+		t.Errorf("Expected moved 383.33, got %.2f", s.moved)
+	}
+
+	s.undo(searchMove{partition: 0, position: 0, from: 1001, to: 1003})
+	if math.Abs(s.moved-100) > 0.01 {
+		t.Errorf("Expected moved 100, got %.2f", s.moved)
+	}
+}
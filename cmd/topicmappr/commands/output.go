@@ -93,53 +93,46 @@ func printBrokerAssignmentStats(cmd *cobra.Command, pm1, pm2 *kafkazk.PartitionM
 			indent, use.ID, use.Leader, use.Follower, use.Leader+use.Follower)
 	}
 
+	// Per-rack leader info.
+	printRackLeaders(pm1, pm2, bm1, bm2)
+
 	// If we're using the storage placement strategy,
 	// write anticipated storage changes.
 	psf, _ := cmd.Flags().GetFloat64("partition-size-factor")
 
-	if cmd.Use == "rebalance" || cmd.Flag("placement").Value.String() == "storage" {
-		fmt.Println("\nStorage free change estimations:")
-		if psf != 1.0 && cmd.Use != "rebalance" {
-			fmt.Printf("%sPartition size factor of %.2f applied\n", indent, psf)
+	if usesStorageMetrics(cmd) {
+		mb1, mb2 := storageStatsBrokers(pm1, bm1, bm2)
+
+		// Scale-ups, partition additions and replication changes
+		// may place replicas on brokers that hold no partitions in
+		// the input map. Include them in the before stats so that
+		// both sides cover the same brokers.
+		switch cmd.Use {
+		case "scale-up", "add-partitions", "change-rf":
+			mb1 = bm1.Filter(func(b *kafkazk.Broker) bool {
+				_, exist := mb2[b.ID]
+				return exist
+			})
 		}
 
-		// Get filtered BrokerMaps. For the 'before' broker statistics, we want
-		// all brokers in the original BrokerMap that were also in the input PartitionMap.
-		// For the 'after' broker statistics, we want brokers that were not marked
-		// for replacement. We don't necessarily want to exclude brokers in the output
-		// that aren't mapped in the output PartitionMap. It's possible that a broker is
-		// not mapped to any of the input topics but is still holding data for other topics.
-		// It's ideal to still include that broker's storage metrics since it was a provided
-		// input and wasn't marked for replacement (generally, users are doing storage placements
-		// particularly to balance out the storage of the input broker list).
-
-		// Filter function for brokers where the Replaced
-		// field is false.
-		nonReplaced := func(b *kafkazk.Broker) bool {
-			if b.Replace {
-				return false
-			}
-			return true
-		}
+		// If the storage capacity of all brokers is known,
+		// changes are reported as storage used percentages.
+		byUsed := mb1.HasStorageTotal() && mb2.HasStorageTotal()
 
-		// Get all IDs in PartitionMap.
-		mappedIDs := map[int]struct{}{}
-		for _, partn := range pm1.Partitions {
-			for _, id := range partn.Replicas {
-				mappedIDs[id] = struct{}{}
-			}
+		switch byUsed {
+		case true:
+			fmt.Println("\nStorage used change estimations:")
+		case false:
+			fmt.Println("\nStorage free change estimations:")
 		}
 
-		// Filter function for brokers that are in the
-		// partition map.
-		mapped := func(b *kafkazk.Broker) bool {
-			if _, exist := mappedIDs[b.ID]; exist {
-				return true
-			}
-			return false
+		if cmd.Flag("partition-size-factor") != nil && psf != 1.0 {
+			fmt.Printf("%sPartition size factor of %.2f applied\n", indent, psf)
 		}
 
-		mb1, mb2 := bm1.Filter(mapped), bm2.Filter(nonReplaced)
+		if byUsed {
+			return append(errs, printStorageUsedChanges(bm1, bm2, mb1, mb2)...)
+		}
 
 		// Range before/after.
 		r1, r2 := mb1.StorageRange(), mb2.StorageRange()
@@ -201,6 +194,244 @@ func printBrokerAssignmentStats(cmd *cobra.Command, pm1, pm2 *kafkazk.PartitionM
 	return errs
 }
 
+// storageStatsBrokers returns the filtered before and after BrokerMaps
+// used for storage statistics. For the 'before' broker statistics, we want
+// all brokers in the original BrokerMap that were also in the input PartitionMap.
+// For the 'after' broker statistics, we want brokers that were not marked
+// for replacement. We don't necessarily want to exclude brokers in the output
+// that aren't mapped in the output PartitionMap. It's possible that a broker is
+// not mapped to any of the input topics but is still holding data for other topics.
+// It's ideal to still include that broker's storage metrics since it was a provided
+// input and wasn't marked for replacement (generally, users are doing storage placements
+// particularly to balance out the storage of the input broker list).
+func storageStatsBrokers(pm1 *kafkazk.PartitionMap, bm1, bm2 kafkazk.BrokerMap) (kafkazk.BrokerMap, kafkazk.BrokerMap) {
+	// Filter function for brokers where the Replaced
+	// field is false.
+	nonReplaced := func(b *kafkazk.Broker) bool {
+		if b.Replace {
+			return false
+		}
+		return true
+	}
+
+	// Get all IDs in PartitionMap.
+	mappedIDs := map[int]struct{}{}
+	for _, partn := range pm1.Partitions {
+		for _, id := range partn.Replicas {
+			mappedIDs[id] = struct{}{}
+		}
+	}
+
+	// Filter function for brokers that are in the
+	// partition map.
+	mapped := func(b *kafkazk.Broker) bool {
+		if _, exist := mappedIDs[b.ID]; exist {
+			return true
+		}
+		return false
+	}
+
+	return bm1.Filter(mapped), bm2.Filter(nonReplaced)
+}
+
+// printStorageUsedChanges prints the before and after storage used
+// percentage statistics for the filtered mb1 and mb2 BrokerMaps and
+// the storage used percentage of each broker in bm1 and bm2. An error
+// is returned if the storage used range increased.
+func printStorageUsedChanges(bm1, bm2, mb1, mb2 kafkazk.BrokerMap) errors {
+	var errs errors
+
+	// Range spread before/after.
+	rs1, rs2 := mb1.StorageRangeSpread(), mb2.StorageRangeSpread()
+	fmt.Printf("%srange spread: %.2f%% -> %.2f%%\n", indent, rs1, rs2)
+	if rs2 > rs1 {
+		errs = append(errs, fmt.Errorf("broker storage used range increased"))
+	}
+
+	// Std dev before/after.
+	sd1, sd2 := mb1.StorageUsedStdDev(), mb2.StorageUsedStdDev()
+	fmt.Printf("%sstd. deviation: %.2f%% -> %.2f%%\n", indent, sd1, sd2)
+
+	// Storage used min/max before/after.
+	min1, max1 := mb1.StorageUsedMinMax()
+	min2, max2 := mb2.StorageUsedMinMax()
+	fmt.Printf("%smin-max: %.2f%%, %.2f%% -> %.2f%%, %.2f%%\n",
+		indent, min1, max1, min2, max2)
+
+	fmt.Printf("%s-\n", indent)
+
+	ids := []int{}
+	for id := range bm1 {
+		if _, exist := bm2[id]; exist && id != kafkazk.StubBrokerID {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		// Indicate if the broker
+		// is a replacement.
+		var replace string
+		if bm2[id].Replace {
+			replace = "*marked for replacement"
+		}
+
+		diff := bm2[id].StorageFree - bm1[id].StorageFree
+
+		fmt.Printf("%sBroker %d: %.2f%% -> %.2f%% of %.2fGB (%+.2fGB free) %s\n",
+			indent, id, bm1[id].StorageUsedPercent(), bm2[id].StorageUsedPercent(),
+			bm2[id].StorageTotal/div, diff/div, replace)
+	}
+
+	return errs
+}
+
+// brokerThroughput takes a PartitionMap and PartitionMetaMap and returns
+// the sum of the throughput of all partitions held by each broker.
+func brokerThroughput(pm *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap) map[int]float64 {
+	t := map[int]float64{}
+
+	for _, partn := range pm.Partitions {
+		tp, _ := pmm.Throughput(partn)
+		for _, id := range partn.Replicas {
+			if id != kafkazk.StubBrokerID {
+				t[id] += tp
+			}
+		}
+	}
+
+	return t
+}
+
+// printBrokerThroughput prints the before and after partition throughput
+// held by each broker if the throughput placement strategy was selected.
+func printBrokerThroughput(cmd *cobra.Command, pm1, pm2 *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap) {
+	if cmd.Flag("placement").Value.String() != "throughput" {
+		return
+	}
+
+	t1, t2 := brokerThroughput(pm1, pmm), brokerThroughput(pm2, pmm)
+
+	seen := map[int]struct{}{}
+	for _, t := range []map[int]float64{t1, t2} {
+		for id := range t {
+			seen[id] = struct{}{}
+		}
+	}
+
+	ids := []int{}
+	for id := range seen {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	fmt.Println("\nThroughput change estimations:")
+
+	for _, id := range ids {
+		fmt.Printf("%sBroker %d: %.2f -> %.2f MB/s\n",
+			indent, id, t1[id]/throttleUnit, t2[id]/throttleUnit)
+	}
+}
+
+// rackLeaders takes a PartitionMap and BrokerMap and returns
+// the number of partition leaders per broker rack ID. All rack
+// IDs in the BrokerMap are included, with 0 if they hold no leaders.
+func rackLeaders(pm *kafkazk.PartitionMap, bm kafkazk.BrokerMap) map[string]int {
+	leaders := map[string]int{}
+
+	for _, b := range bm {
+		if b.ID != kafkazk.StubBrokerID && b.Locality != "" {
+			leaders[b.Locality] = 0
+		}
+	}
+
+	for _, partn := range pm.Partitions {
+		if len(partn.Replicas) == 0 {
+			continue
+		}
+
+		if b, exist := bm[partn.Replicas[0]]; exist {
+			leaders[b.Locality]++
+		}
+	}
+
+	return leaders
+}
+
+// printRackLeaders prints the before and after number of partition
+// leaders per rack ID. Nothing is printed if no brokers have a rack ID.
+func printRackLeaders(pm1, pm2 *kafkazk.PartitionMap, bm1, bm2 kafkazk.BrokerMap) {
+	l1, l2 := rackLeaders(pm1, bm1), rackLeaders(pm2, bm2)
+
+	racks := []string{}
+	seen := map[string]struct{}{}
+	for _, l := range []map[string]int{l1, l2} {
+		for r := range l {
+			if _, exist := seen[r]; !exist {
+				seen[r] = struct{}{}
+				racks = append(racks, r)
+			}
+		}
+	}
+
+	// Skip if there's no rack info.
+	if len(racks) == 0 || (len(racks) == 1 && racks[0] == "") {
+		return
+	}
+
+	sort.Strings(racks)
+
+	fmt.Printf("%s-\n", indent)
+
+	for _, r := range racks {
+		name := r
+		if name == "" {
+			name = "[none]"
+		}
+
+		fmt.Printf("%sRack %s - leaders: %d -> %d\n", indent, name, l1[r], l2[r])
+	}
+}
+
+// usesStorageMetrics returns whether the command operates on
+// broker storage metrics, either inherently or because the storage
+// or throughput placement strategy (or weights) were selected.
+func usesStorageMetrics(cmd *cobra.Command) bool {
+	switch cmd.Use {
+	case "rebalance", "scale-up":
+		return true
+	}
+
+	if w, _ := getPlacementWeights(cmd); w != nil {
+		return w["storage"] > 0 || w["throughput"] > 0
+	}
+
+	if f := cmd.Flag("placement"); f != nil {
+		switch f.Value.String() {
+		case "storage", "throughput":
+			return true
+		}
+	}
+
+	return false
+}
+
+// usesThroughputMetrics returns whether throughput is a placement
+// dimension, either via the throughput placement strategy or weights.
+func usesThroughputMetrics(cmd *cobra.Command) bool {
+	if w, _ := getPlacementWeights(cmd); w != nil {
+		return w["throughput"] > 0
+	}
+
+	if f := cmd.Flag("placement"); f != nil {
+		return f.Value.String() == "throughput"
+	}
+
+	return false
+}
+
 // skipReassignmentNoOps removes no-op partition map changes
 // from the input and final output PartitionMap
 func skipReassignmentNoOps(pm1, pm2 *kafkazk.PartitionMap) (*kafkazk.PartitionMap, *kafkazk.PartitionMap) {
@@ -217,8 +448,9 @@ func skipReassignmentNoOps(pm1, pm2 *kafkazk.PartitionMap) (*kafkazk.PartitionMa
 	return prunedInputPartitionMap, prunedOutputPartitionMap
 }
 
-// writeMaps takes a PartitionMap and writes out files.
-func writeMaps(cmd *cobra.Command, pm *kafkazk.PartitionMap, phasedPM *kafkazk.PartitionMap) {
+// writeMaps takes a PartitionMap and writes out files. If the original
+// input PartitionMap is provided, rollback maps are also written.
+func writeMaps(cmd *cobra.Command, pm *kafkazk.PartitionMap, phasedPM *kafkazk.PartitionMap, originalMap *kafkazk.PartitionMap) {
 	if len(pm.Partitions) == 0 {
 		fmt.Println("\nNo partition reassignments, skipping map generation")
 		return
@@ -284,6 +516,57 @@ func writeMaps(cmd *cobra.Command, pm *kafkazk.PartitionMap, phasedPM *kafkazk.P
 			fmt.Printf("%s%s%s.json\n", indent, outPath, t)
 		}
 	}
+
+	if originalMap != nil {
+		writeRollbackMaps(cmd, originalMap, pm)
+	}
+}
+
+// writeBatches takes an ordered list of batch PartitionMaps and writes
+// per-topic maps for each, suffixed with the batch number. If --out-file
+// is set, a combined map is also written for each batch.
+func writeBatches(cmd *cobra.Command, batches []*kafkazk.PartitionMap) {
+	if len(batches) == 0 {
+		return
+	}
+
+	outPath := cmd.Flag("out-path").Value.String()
+	outFile := cmd.Flag("out-file").Value.String()
+
+	fmt.Println("\nBatched partition maps:")
+
+	for i, b := range batches {
+		batchSuffix := fmt.Sprintf("-batch%d", i+1)
+
+		// Write the combined batch map if set.
+		if outFile != "" {
+			fullPath := fmt.Sprintf("%s%s%s", outPath, outFile, batchSuffix)
+			err := kafkazk.WriteMap(b, fullPath)
+			if err != nil {
+				fmt.Printf("%s%s", indent, err)
+			} else {
+				fmt.Printf("%s%s.json [combined map]\n", indent, fullPath)
+			}
+		}
+
+		// Break the batch up by topic.
+		for _, t := range b.Topics() {
+			tm := kafkazk.NewPartitionMap()
+			for _, p := range b.Partitions {
+				if p.Topic == t {
+					tm.Partitions = append(tm.Partitions, p)
+				}
+			}
+
+			fullPath := fmt.Sprintf("%s%s%s", outPath, t, batchSuffix)
+			err := kafkazk.WriteMap(tm, fullPath)
+			if err != nil {
+				fmt.Printf("%s%s", indent, err)
+			} else {
+				fmt.Printf("%s%s.json\n", indent, fullPath)
+			}
+		}
+	}
 }
 
 // handleOverridableErrs handles errors that can be optionally ignored
@@ -2,6 +2,8 @@ package commands
 
 import (
 	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
 )
 
 func TestWhatChanged(t *testing.T) {
@@ -32,3 +34,52 @@ func TestWhatChanged(t *testing.T) {
 		}
 	}
 }
+
+func TestRackLeaders(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm, _ := zk.GetPartitionMap("test_topic")
+	bmm, _ := zk.GetAllBrokerMeta(false)
+	bm := kafkazk.BrokerMapFromPartitionMap(pm, bmm, false)
+	bm[1005] = &kafkazk.Broker{ID: 1005, Locality: "c"}
+
+	leaders := rackLeaders(pm, bm)
+
+	// Leaders 1001 (a), 1002 (b), 1003 (none), 1004 (a).
+	// 1005 (c) holds no leaders.
+	expected := map[string]int{"a": 2, "b": 1, "": 1, "c": 0}
+
+	if len(leaders) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, leaders)
+	}
+
+	for r, n := range expected {
+		if leaders[r] != n {
+			t.Errorf("Expected %d leaders for rack '%s', got %d", n, r, leaders[r])
+		}
+	}
+}
+
+func TestBrokerThroughput(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm, _ := zk.GetPartitionMap("test_topic")
+	pmm, _ := zk.GetAllPartitionMeta()
+
+	for i, p := range pmm["test_topic"] {
+		p.BytesIn = float64(i + 1)
+	}
+
+	pm.Partitions[0].Replicas = []int{1001, kafkazk.StubBrokerID}
+
+	tp := brokerThroughput(pm, pmm)
+	expected := map[int]float64{1001: 6, 1002: 6, 1003: 7, 1004: 7}
+
+	if len(tp) != len(expected) {
+		t.Fatalf("Expected %d brokers, got %d", len(expected), len(tp))
+	}
+
+	for id, e := range expected {
+		if tp[id] != e {
+			t.Errorf("Expected broker %d throughput %.0f, got %.0f", id, e, tp[id])
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// topicPolicy describes placement constraints for all
+// topics matching the Topics regex. Policies are read
+// from a YAML or JSON file in the form:
+//
+//	policies:
+//	  - topics: "^payments.*"
+//	    brokers: [1001, 1002, 1003]
+//	    min-rack-ids: 2
+//	    not-colocated-with: ["^audit.*"]
+//	    replication: 3
+type topicPolicy struct {
+	Topics string `yaml:"topics"`
+	// Pinned broker pool.
+	Brokers []int `yaml:"brokers"`
+	// Minimum unique rack IDs per replica set.
+	MinRackIDs *int `yaml:"min-rack-ids"`
+	// Topic regexes whose brokers are not eligible.
+	NotColocatedWith []string `yaml:"not-colocated-with"`
+	// Replication factor override.
+	Replication int `yaml:"replication"`
+
+	topicsRegex    *regexp.Regexp
+	colocatedRegex []*regexp.Regexp
+}
+
+// topicPolicies is an ordered list of topicPolicy. The
+// first policy that matches a topic is applied.
+type topicPolicies []*topicPolicy
+
+// readPolicyFile reads and validates topicPolicies from a file.
+func readPolicyFile(path string) (topicPolicies, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Policies topicPolicies `yaml:"policies"`
+	}
+
+	// JSON is parsed as YAML.
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("Error parsing policy file: %s", err)
+	}
+
+	for i, p := range file.Policies {
+		switch {
+		case p.Topics == "":
+			return nil, fmt.Errorf("policy %d: topics must be specified", i)
+		case p.MinRackIDs != nil && *p.MinRackIDs < 0:
+			return nil, fmt.Errorf("policy %d: min-rack-ids must be >= 0", i)
+		case p.Replication < 0:
+			return nil, fmt.Errorf("policy %d: replication must be >= 0", i)
+		case p.Replication > 0 && len(p.Brokers) > 0 && p.Replication > len(p.Brokers):
+			return nil, fmt.Errorf("policy %d: replication exceeds the number of pinned brokers", i)
+		}
+
+		if p.topicsRegex, err = regexp.Compile(p.Topics); err != nil {
+			return nil, fmt.Errorf("policy %d: invalid topics regex: %s", i, err)
+		}
+
+		for _, t := range p.NotColocatedWith {
+			r, err := regexp.Compile(t)
+			if err != nil {
+				return nil, fmt.Errorf("policy %d: invalid not-colocated-with regex: %s", i, err)
+			}
+			p.colocatedRegex = append(p.colocatedRegex, r)
+		}
+	}
+
+	return file.Policies, nil
+}
+
+// get returns the first *topicPolicy that matches
+// the topic name, or nil if none match.
+func (t topicPolicies) get(topic string) *topicPolicy {
+	for _, p := range t {
+		if p.topicsRegex.MatchString(topic) {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// placementPolicies takes a *PartitionMap and a kafkazk.Handler and
+// returns kafkazk.PlacementPolicies for all topics in the map with a
+// matching policy. Brokers holding partitions of any topic matching a
+// not-colocated-with regex (other than the topic itself) are excluded;
+// the current placements are looked up in ZooKeeper. Pinned broker pools
+// are reserved for the topics of their policy: all other topics, with or
+// without a policy, have the brokers of pools not their own reserved.
+func (t topicPolicies) placementPolicies(pm *kafkazk.PartitionMap, zk kafkazk.Handler) (kafkazk.PlacementPolicies, error) {
+	pp := kafkazk.PlacementPolicies{}
+
+	pinned := map[int]bool{}
+	for _, p := range t {
+		for _, id := range p.Brokers {
+			pinned[id] = true
+		}
+	}
+
+	for _, topic := range pm.Topics() {
+		p := t.get(topic)
+
+		policy := &kafkazk.PlacementPolicy{
+			Brokers:         map[int]bool{},
+			ExcludedBrokers: map[int]bool{},
+			ReservedBrokers: map[int]bool{},
+		}
+
+		if p != nil {
+			policy.MinUniqueRackIDs = p.MinRackIDs
+			for _, id := range p.Brokers {
+				policy.Brokers[id] = true
+			}
+		}
+
+		for id := range pinned {
+			if !policy.Brokers[id] {
+				policy.ReservedBrokers[id] = true
+			}
+		}
+
+		if p == nil {
+			if len(policy.ReservedBrokers) > 0 {
+				pp[topic] = policy
+			}
+			continue
+		}
+
+		if len(p.colocatedRegex) > 0 {
+			if zk == nil {
+				return nil, fmt.Errorf("not-colocated-with policies require a ZooKeeper connection")
+			}
+
+			colocated, err := zk.GetTopics(p.colocatedRegex)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, ct := range colocated {
+				if ct == topic {
+					continue
+				}
+
+				m, err := zk.GetPartitionMap(ct)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, partn := range m.Partitions {
+					for _, id := range partn.Replicas {
+						policy.ExcludedBrokers[id] = true
+					}
+				}
+			}
+		}
+
+		pp[topic] = policy
+	}
+
+	return pp, nil
+}
+
+// pinnedBrokers returns a sorted []int of all broker IDs
+// pinned by policies matching topics in the *PartitionMap.
+func (t topicPolicies) pinnedBrokers(pm *kafkazk.PartitionMap) []int {
+	ids := map[int]struct{}{}
+
+	for _, topic := range pm.Topics() {
+		if p := t.get(topic); p != nil {
+			for _, id := range p.Brokers {
+				ids[id] = struct{}{}
+			}
+		}
+	}
+
+	var pinned []int
+	for id := range ids {
+		pinned = append(pinned, id)
+	}
+
+	sort.Ints(pinned)
+
+	return pinned
+}
+
+// setReplication applies replication factor
+// overrides to the *PartitionMap.
+func (t topicPolicies) setReplication(pm *kafkazk.PartitionMap) {
+	for _, topic := range pm.Topics() {
+		if p := t.get(topic); p != nil && p.Replication > 0 {
+			pm.SetTopicReplication(topic, p.Replication)
+		}
+	}
+}
+
+// violations takes a *PartitionMap and BrokerMap along with the
+// kafkazk.PlacementPolicies for the map and returns an error for each
+// partition that violates a policy.
+func (t topicPolicies) violations(pm *kafkazk.PartitionMap, bm kafkazk.BrokerMap, pp kafkazk.PlacementPolicies) errors {
+	var errs errors
+
+	// Rack spread can only be checked
+	// if rack IDs are known.
+	var hasRacks bool
+	for _, b := range bm {
+		if b.Locality != "" {
+			hasRacks = true
+			break
+		}
+	}
+
+	for _, partn := range pm.Partitions {
+		p := t.get(partn.Topic)
+		policy := pp.Get(partn.Topic)
+
+		if p == nil && policy == nil {
+			continue
+		}
+
+		// Replication factor.
+		if p != nil && p.Replication > 0 && len(partn.Replicas) != p.Replication {
+			errs = append(errs, fmt.Errorf("%s p%d: replication factor %d, policy requires %d",
+				partn.Topic, partn.Partition, len(partn.Replicas), p.Replication))
+		}
+
+		// Broker eligibility.
+		for _, id := range partn.Replicas {
+			switch {
+			case policy == nil:
+			case len(policy.Brokers) > 0 && !policy.Brokers[id]:
+				errs = append(errs, fmt.Errorf("%s p%d: broker %d not in pinned broker pool",
+					partn.Topic, partn.Partition, id))
+			case policy.ExcludedBrokers[id]:
+				errs = append(errs, fmt.Errorf("%s p%d: broker %d holds a not-colocated-with topic",
+					partn.Topic, partn.Partition, id))
+			case policy.ReservedBrokers[id]:
+				errs = append(errs, fmt.Errorf("%s p%d: broker %d reserved by another policy's pinned broker pool",
+					partn.Topic, partn.Partition, id))
+			}
+		}
+
+		// Rack spread.
+		if p == nil || p.MinRackIDs == nil || !hasRacks {
+			continue
+		}
+
+		racks := map[string]struct{}{}
+		for _, id := range partn.Replicas {
+			if b, exist := bm[id]; exist && b.Locality != "" {
+				racks[b.Locality] = struct{}{}
+			}
+		}
+
+		// A min-rack-ids of 0 requires that
+		// all rack IDs are unique.
+		min := *p.MinRackIDs
+		if min == 0 {
+			min = len(partn.Replicas)
+		}
+
+		if len(racks) < min {
+			errs = append(errs, fmt.Errorf("%s p%d: %d unique rack IDs, policy requires %d",
+				partn.Topic, partn.Partition, len(racks), min))
+		}
+	}
+
+	return errs
+}
+
+// getPolicies reads the --policy-file if set. A nil
+// topicPolicies is returned if no policy file is set.
+func getPolicies(cmd *cobra.Command) topicPolicies {
+	path := cmd.Flag("policy-file").Value.String()
+	if path == "" {
+		return nil
+	}
+
+	policies, err := readPolicyFile(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	return policies
+}
+
+// printViolations prints violations of the
+// named kind found in the current map.
+func printViolations(kind string, e errors) {
+	fmt.Printf("\n%s violations in current map:\n", kind)
+
+	if len(e) == 0 {
+		fmt.Printf("%s[none]\n", indent)
+		return
+	}
+
+	sort.Sort(e)
+	for _, err := range e {
+		fmt.Printf("%s%s\n", indent, err)
+	}
+}
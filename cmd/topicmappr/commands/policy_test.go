@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func writeTestPolicyFile(t *testing.T, s string) string {
+	f, err := ioutil.TempFile("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.WriteString(s)
+	f.Close()
+
+	return f.Name()
+}
+
+func TestReadPolicyFile(t *testing.T) {
+	files := []string{
+		`policies:
+  - topics: "^test_topic$"
+    brokers: [1001, 1002]
+    min-rack-ids: 2
+    replication: 2
+  - topics: ".*"
+    not-colocated-with: ["^test_topic2$"]`,
+		`{"policies": [
+  {"topics": "^test_topic$", "brokers": [1001, 1002], "min-rack-ids": 2, "replication": 2},
+  {"topics": ".*", "not-colocated-with": ["^test_topic2$"]}]}`,
+	}
+
+	for _, s := range files {
+		path := writeTestPolicyFile(t, s)
+		defer os.Remove(path)
+
+		policies, err := readPolicyFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(policies) != 2 {
+			t.Fatalf("Expected 2 policies, got %d", len(policies))
+		}
+
+		p := policies.get("test_topic")
+		if p != policies[0] || *p.MinRackIDs != 2 || p.Replication != 2 {
+			t.Errorf("Unexpected policy for test_topic: %+v", p)
+		}
+
+		if p := policies.get("other"); p != policies[1] || p.MinRackIDs != nil {
+			t.Errorf("Unexpected policy for other: %+v", p)
+		}
+	}
+
+	// Invalid.
+	path := writeTestPolicyFile(t, `policies: [{"topics": "(", "brokers": [1001]}]`)
+	defer os.Remove(path)
+
+	if _, err := readPolicyFile(path); err == nil {
+		t.Error("Expected non-nil error")
+	}
+}
+
+func TestPolicyViolations(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm, _ := zk.GetPartitionMap("test_topic")
+	bmm, _ := zk.GetAllBrokerMeta(false)
+	bm := kafkazk.BrokerMapFromPartitionMap(pm, bmm, false)
+
+	min := 2
+	policies := topicPolicies{
+		&topicPolicy{
+			Topics:           "test_topic",
+			Brokers:          []int{1001, 1002, 1003},
+			MinRackIDs:       &min,
+			Replication:      2,
+			NotColocatedWith: []string{"test_topic2"},
+		},
+	}
+
+	for _, p := range policies {
+		p.topicsRegex = regexpMustCompile(t, p.Topics)
+		p.colocatedRegex = append(p.colocatedRegex, regexpMustCompile(t, p.NotColocatedWith[0]))
+	}
+
+	pp, err := policies.placementPolicies(pm, zk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The mock test_topic2 map uses brokers 1001-1004.
+	if len(pp["test_topic"].ExcludedBrokers) != 4 {
+		t.Errorf("Expected 4 excluded brokers, got %d", len(pp["test_topic"].ExcludedBrokers))
+	}
+
+	// Drop the co-location constraint.
+	pp["test_topic"].ExcludedBrokers = map[int]bool{}
+
+	errs := policies.violations(pm, bm, pp)
+
+	// p0: [1001 (a), 1002 (b)] ok.
+	// p1: [1002 (b), 1001 (a)] ok.
+	// p2: [1003 (none), 1004 (a), 1001 (a)] rf, 1004, racks.
+	// p3: [1004 (a), 1003 (none), 1002 (b)] rf, 1004.
+	if len(errs) != 5 {
+		t.Errorf("Expected 5 violations, got %d: %v", len(errs), errs)
+	}
+}
+
+func TestPolicyReservedBrokers(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm, _ := zk.GetPartitionMap("test_topic")
+	bmm, _ := zk.GetAllBrokerMeta(false)
+	bm := kafkazk.BrokerMapFromPartitionMap(pm, bmm, false)
+
+	policies := topicPolicies{
+		&topicPolicy{Topics: "other_topic", Brokers: []int{1004, 1005}},
+	}
+	policies[0].topicsRegex = regexpMustCompile(t, policies[0].Topics)
+
+	pp, err := policies.placementPolicies(pm, zk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// test_topic has no policy; the other_topic pool is reserved.
+	policy := pp.Get("test_topic")
+	if policy == nil {
+		t.Fatal("Expected a policy for test_topic")
+	}
+
+	if policy.Allows(1004) || policy.Allows(1005) || !policy.Allows(1001) {
+		t.Errorf("Expected brokers 1004, 1005 reserved, got %v", policy.ReservedBrokers)
+	}
+
+	// p2 and p3 hold 1004.
+	if errs := policies.violations(pm, bm, pp); len(errs) != 2 {
+		t.Errorf("Expected 2 violations, got %d: %v", len(errs), errs)
+	}
+
+	// The pool's own topics aren't restricted.
+	policies[0].Topics = "test_topic"
+	policies[0].topicsRegex = regexpMustCompile(t, policies[0].Topics)
+
+	pp, _ = policies.placementPolicies(pm, zk)
+	if len(pp.Get("test_topic").ReservedBrokers) != 0 {
+		t.Errorf("Expected no reserved brokers, got %v", pp.Get("test_topic").ReservedBrokers)
+	}
+}
+
+func regexpMustCompile(t *testing.T, s string) *regexp.Regexp {
+	r, err := regexp.Compile(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
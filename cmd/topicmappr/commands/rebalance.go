@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 	"sort"
-	"sync"
 
 	"github.com/DataDog/kafka-kit/kafkazk"
 
@@ -12,10 +11,11 @@ import (
 )
 
 var rebalanceCmd = &cobra.Command{
-	Use:   "rebalance",
-	Short: "Rebalance partition allotments among a set of topics and brokers",
-	Long:  `Rebalance partition allotments among a set of topics and brokers`,
-	Run:   rebalance,
+	Use:    "rebalance",
+	Short:  "Rebalance partition allotments among a set of topics and brokers",
+	Long:   `Rebalance partition allotments among a set of topics and brokers`,
+	PreRun: bundlePreRun,
+	Run:    rebalance,
 }
 
 // Rebalance may be configured to run a series
@@ -29,6 +29,7 @@ type rebalanceResults struct {
 	tolerance    float64
 	partitionMap *kafkazk.PartitionMap
 	relocations  map[int][]relocation
+	swaps        map[int][]swap
 	brokers      kafkazk.BrokerMap
 }
 
@@ -39,16 +40,35 @@ func init() {
 	rebalanceCmd.Flags().String("out-path", "", "Path to write output map files to")
 	rebalanceCmd.Flags().String("out-file", "", "If defined, write a combined map of all topics to a file")
 	rebalanceCmd.Flags().String("brokers", "", "Broker list to scope all partition placements to ('-1' for all currently mapped brokers, '-2' for all brokers in cluster)")
-	rebalanceCmd.Flags().Float64("storage-threshold", 0.20, "Percent below the harmonic mean storage free to target for partition offload (0 targets a brokers)")
+	rebalanceCmd.Flags().Float64("storage-threshold", 0.20, "Percent below the harmonic mean storage free (or above the storage used mean with broker capacities) to target for partition offload (0 targets a brokers)")
 	rebalanceCmd.Flags().Float64("storage-threshold-gb", 0.00, "Storage free in gigabytes to target for partition offload (those below the specified value); 0 [default] defers target selection to --storage-threshold")
-	rebalanceCmd.Flags().Float64("tolerance", 0.0, "Percent distance from the mean storage free to limit storage scheduling (0 performs automatic tolerance selection)")
-	rebalanceCmd.Flags().Int("partition-limit", 30, "Limit the number of top partitions by size eligible for relocation per broker")
+	rebalanceCmd.Flags().Float64("tolerance", 0.0, "Percent distance from the mean storage free (or storage used mean with broker capacities) to limit storage scheduling (0 performs automatic tolerance selection)")
+	rebalanceCmd.Flags().String("storage-capacity-map", "", "If defined, balance storage used percentages using per-broker storage capacities; JSON map of broker IDs to GB (takes precedence over capacities from broker metrics)")
+	rebalanceCmd.Flags().Int("partition-limit", 30, "Limit the number of top partitions by size (or by move cost with --move-cost) eligible for relocation per broker")
 	rebalanceCmd.Flags().Int("partition-size-threshold", 512, "Size in megabytes where partitions below this value will not be moved in a rebalance")
+	rebalanceCmd.Flags().String("policy-file", "", "YAML or JSON file of topic placement policies; policies take precedence over flags")
 	rebalanceCmd.Flags().Bool("locality-scoped", false, "Disallow a relocation to traverse rack.id values among brokers")
+	rebalanceCmd.Flags().Bool("swaps", true, "Plan pairwise replica swaps with less utilized brokers when no one-way relocation fits")
+	rebalanceCmd.Flags().String("optimizer", "greedy", "Rebalance optimizer: [greedy, search]; search runs a simulated annealing over relocations and swaps among all brokers, ignoring --tolerance and --partition-limit")
+	rebalanceCmd.Flags().Int("search-iterations", 100000, "Iteration budget for the search optimizer (0 disables)")
+	rebalanceCmd.Flags().Duration("search-timeout", 0, "Time budget for the search optimizer (0 disables); results are only reproducible under an iteration budget")
+	rebalanceCmd.Flags().Int64("search-seed", 1, "Random seed for the search optimizer")
+	rebalanceCmd.Flags().Float64("search-move-weight", 0.50, "Search optimizer cost of data moved; the cost is the storage free std. deviation in GB (or storage used std. deviation in percent with broker capacities) plus this weight times the GB moved (or --move-cost) per broker")
+	rebalanceCmd.Flags().String("move-cost", "", "If defined, rank relocations by a weighted move cost per GB offloaded across dimensions [size, throughput, leader], e.g. 'size=1,throughput=0.5,leader=0.2'; costs are in GB, where throughput and leader weights of 1 cost the mean partition size for a partition of the mean bytes in rate and a leader replica, respectively")
+	rebalanceCmd.Flags().Bool("log-dirs", false, "Assign a log dir to each relocated replica using per log dir broker metrics (requires kafka-reassign-partitions to apply)")
+	rebalanceCmd.Flags().Bool("interactive", false, "Review and edit the plan interactively before writing maps")
 	rebalanceCmd.Flags().Bool("verbose", false, "Verbose output")
 	rebalanceCmd.Flags().String("zk-metrics-prefix", "topicmappr", "ZooKeeper namespace prefix for Kafka metrics")
 	rebalanceCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes)")
 	rebalanceCmd.Flags().Bool("optimize-leadership", false, "Rebalance all broker leader/follower ratios")
+	rebalanceCmd.Flags().Int("batch-partitions", 0, "Split output maps into batches of at most this many partition moves (0 disables)")
+	rebalanceCmd.Flags().Float64("batch-size-gb", 0.00, "Split output maps into batches replicating at most this many gigabytes (0 disables)")
+	rebalanceCmd.Flags().Int("batch-broker-concurrency", 0, "Split output maps into batches with at most this many partition moves per broker (0 disables)")
+	rebalanceCmd.Flags().String("report-format", "", "If defined, write a plan report in the specified format: [json, csv]")
+	rebalanceCmd.Flags().Float64("throttle-rate", 0.00, "If defined, estimate the reassignment duration under the replication throttle rate in MB/s")
+	rebalanceCmd.Flags().String("throttle-cap-map", "", "If defined, estimate the reassignment duration under per-broker replication throttle rates; autothrottle --cap-map JSON map of instance types to MB/s (keys of the form 'broker:<id>' set a broker rate)")
+	rebalanceCmd.Flags().String("save-bundle", "", "If defined, save all plan inputs to a bundle in the specified directory")
+	rebalanceCmd.Flags().String("from-bundle", "", "If defined, generate the plan offline from a bundle saved with --save-bundle; flags set on the command line take precedence")
 
 	// Required.
 	rebalanceCmd.MarkFlagRequired("brokers")
@@ -56,6 +76,49 @@ func init() {
 }
 
 func rebalance(cmd *cobra.Command, _ []string) {
+	if rf := cmd.Flag("report-format").Value.String(); !validReportFormat(rf) {
+		fmt.Println("\n[ERROR] --report-format must be either 'json' or 'csv'")
+		defaultsAndExit()
+	}
+
+	if _, err := getThrottleRates(cmd); err != nil {
+		fmt.Printf("\n[ERROR] %s\n", err)
+		defaultsAndExit()
+	}
+
+	capacities, err := getStorageCapacities(cmd)
+	if err != nil {
+		fmt.Printf("\n[ERROR] %s\n", err)
+		defaultsAndExit()
+	}
+
+	optimizer := cmd.Flag("optimizer").Value.String()
+	iterations, _ := cmd.Flags().GetInt("search-iterations")
+	timeout, _ := cmd.Flags().GetDuration("search-timeout")
+	moveWeight, _ := cmd.Flags().GetFloat64("search-move-weight")
+
+	switch {
+	case optimizer != "greedy" && optimizer != "search":
+		fmt.Println("\n[ERROR] --optimizer must be either 'greedy' or 'search'")
+		defaultsAndExit()
+	case iterations < 0 || timeout < 0:
+		fmt.Println("\n[ERROR] --search-iterations and --search-timeout must be >= 0")
+		defaultsAndExit()
+	case iterations == 0 && timeout == 0:
+		fmt.Println("\n[ERROR] at least one of --search-iterations or --search-timeout must be > 0")
+		defaultsAndExit()
+	case moveWeight < 0:
+		fmt.Println("\n[ERROR] --search-move-weight must be >= 0")
+		defaultsAndExit()
+	}
+
+	if mc := cmd.Flag("move-cost").Value.String(); mc != "" {
+		if _, err := parseMoveCostWeights(mc); err != nil {
+			fmt.Printf("\n[ERROR] invalid --move-cost: %s\n", err)
+			defaultsAndExit()
+		}
+	}
+
 	bootstrap(cmd)
 
 	// ZooKeeper init.
@@ -70,6 +133,7 @@ func rebalance(cmd *cobra.Command, _ []string) {
 	// Get broker and partition metadata.
 	checkMetaAge(cmd, zk)
 	brokerMeta := getBrokerMeta(cmd, zk, true)
+	setStorageCapacities(brokerMeta, capacities)
 	partitionMeta := getPartitionMeta(cmd, zk)
 
 	// Get the current partition map.
@@ -88,6 +152,13 @@ func rebalance(cmd *cobra.Command, _ []string) {
 	// Print if any topics were excluded due to pending deletion.
 	printExcludedTopics(pending)
 
+	// Get the move cost model if configured.
+	moveCost, err := getMoveCost(cmd, partitionMapIn, partitionMeta)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	// Get a broker map.
 	brokersIn := kafkazk.BrokerMapFromPartitionMap(partitionMapIn, brokerMeta, false)
 
@@ -95,141 +166,120 @@ func rebalance(cmd *cobra.Command, _ []string) {
 	// broker IDs targeted for partition offloading.
 	offloadTargets := validateBrokersForRebalance(cmd, brokersIn, brokerMeta)
 
-	// Sort offloadTargets by storage free ascending.
-	sort.Sort(offloadTargetsBySize{t: offloadTargets, bm: brokersIn})
+	// Get placement policies for the input topics
+	// and report any violations in the current map.
+	policies := getPolicies(cmd)
 
-	partitionLimit, _ := cmd.Flags().GetInt("partition-limit")
-	partitionSizeThreshold, _ := cmd.Flags().GetInt("partition-size-threshold")
+	var placementPolicies kafkazk.PlacementPolicies
+	if policies != nil {
+		placementPolicies, err = policies.placementPolicies(partitionMapIn, zk)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 
-	otm := map[int]struct{}{}
-	for _, id := range offloadTargets {
-		otm[id] = struct{}{}
+		printViolations("Policy", policies.violations(partitionMapIn, brokersIn, placementPolicies))
 	}
 
-	results := make(chan rebalanceResults, 100)
-	wg := &sync.WaitGroup{}
-
-	// Compute a rebalanceResults output for all tolerance
-	// values 0.01..0.99 in parallel.
-	for i := 0.01; i < 0.99; i += 0.01 {
-		// Whether we're using a fixed tolerance
-		// (non 0.00) set via flag or an iterative value.
-		tolFlag, _ := cmd.Flags().GetFloat64("tolerance")
-		var tol float64
-
-		if tolFlag == 0.00 {
-			tol = i
-		} else {
-			tol = tolFlag
-		}
-
-		wg.Add(1)
+	// Sort offloadTargets by storage free ascending
+	// (or storage used descending).
+	sort.Sort(offloadTargetsBySize{t: offloadTargets, bm: brokersIn, byUsed: brokersIn.HasStorageTotal()})
 
-		go func() {
-			defer wg.Done()
+	var m rebalanceResults
+	var sources []int
 
-			partitionMap := partitionMapIn.Copy()
+	switch optimizer {
+	case "search":
+		params := getSearchParams(cmd, placementPolicies, moveCost)
 
-			// Bundle planRelocationsForBrokerParams.
-			params := planRelocationsForBrokerParams{
-				relos:                  map[int][]relocation{},
-				mappings:               partitionMap.Mappings(),
-				brokers:                brokersIn.Copy(),
-				partitionMeta:          partitionMeta,
-				plan:                   relocationPlan{},
-				topPartitionsLimit:     partitionLimit,
-				partitionSizeThreshold: partitionSizeThreshold,
-				offloadTargetsMap:      otm,
-				tolerance:              tol,
-			}
+		var plan relocationPlan
+		var stats searchStats
+		m.relocations, plan, m.brokers, stats = searchRebalance(partitionMapIn, brokersIn, partitionMeta, params)
 
-			// Iterate over offload targets, planning
-			// at most one relocation per iteration.
-			// Continue this loop until no more relocations
-			// can be planned.
-			for exhaustedCount := 0; exhaustedCount < len(offloadTargets); {
-				params.pass++
-				for _, sourceID := range offloadTargets {
-					// Update the source broker ID
-					params.sourceID = sourceID
-
-					relos := planRelocationsForBroker(cmd, params)
-
-					// If no relocations could be planned,
-					// increment the exhaustion counter.
-					if relos == 0 {
-						exhaustedCount++
-					}
-				}
-			}
+		// Report exchanges between brokers as swaps.
+		if swaps, _ := cmd.Flags().GetBool("swaps"); swaps {
+			m.relocations, m.swaps = pairSwaps(m.relocations)
+		}
 
-			// Update the partition map with the relocation plan.
-			applyRelocationPlan(cmd, partitionMap, params.plan)
-
-			// Insert the rebalanceResults.
-			results <- rebalanceResults{
-				storageRange: params.brokers.StorageRange(),
-				stdDev:       params.brokers.StorageStdDev(),
-				tolerance:    tol,
-				partitionMap: partitionMap,
-				relocations:  params.relos,
-				brokers:      params.brokers,
-			}
+		// Update the partition map with the relocation plan.
+		m.partitionMap = partitionMapIn.Copy()
+		applyRelocationPlan(cmd, m.partitionMap, plan)
 
-		}()
+		// Print parameters used for rebalance decisions.
+		printSearchParams(cmd, params, stats, brokersIn)
 
-		// Break early if we're using a fixed tolerance value.
-		if tolFlag != 0.00 {
-			break
+		// Relocations may source from any broker.
+		for id := range m.relocations {
+			sources = append(sources, id)
 		}
-	}
 
-	wg.Wait()
-	close(results)
+		for id := range m.swaps {
+			if _, exist := m.relocations[id]; !exist {
+				sources = append(sources, id)
+			}
+		}
 
-	// Merge all results into a slice.
-	resultsByRange := []rebalanceResults{}
-	for r := range results {
-		resultsByRange = append(resultsByRange, r)
-	}
+		sort.Ints(sources)
+	default:
+		resultsByRange := greedyRebalance(cmd, partitionMapIn, brokersIn, partitionMeta, offloadTargets, placementPolicies, moveCost)
 
-	// Sort the rebalance results by range ascending.
-	sort.Slice(resultsByRange, func(i, j int) bool {
-		switch {
-		case resultsByRange[i].storageRange < resultsByRange[j].storageRange:
-			return true
-		case resultsByRange[i].storageRange > resultsByRange[j].storageRange:
-			return false
-		}
+		// Chose the results with the lowest range.
+		m = resultsByRange[0]
 
-		return resultsByRange[i].stdDev < resultsByRange[j].stdDev
-	})
+		// Print parameters used for rebalance decisions.
+		printRebalanceParams(cmd, resultsByRange, brokersIn, m.tolerance, moveCost)
 
-	// Chose the results with the lowest range.
-	m := resultsByRange[0]
-	partitionMapOut, brokersOut, relos := m.partitionMap, m.brokers, m.relocations
+		sources = offloadTargets
+	}
 
-	// Print parameters used for rebalance decisions.
-	printRebalanceParams(cmd, resultsByRange, brokersIn, m.tolerance)
+	partitionMapOut, brokersOut, relos, swaps := m.partitionMap, m.brokers, m.relocations, m.swaps
 
 	// Print planned relocations.
-	printPlannedRelocations(offloadTargets, relos, partitionMeta)
+	printPlannedRelocations(sources, relos, swaps, partitionMeta)
+	printMoveCost(moveCost, partitionMapIn, relos, swaps, partitionMeta)
 
 	// Print map change results.
 	printMapChanges(partitionMapIn, partitionMapOut)
 
+	// Assign log dirs if configured.
+	var errs errors
+	if logDirs, _ := cmd.Flags().GetBool("log-dirs"); logDirs {
+		errs = assignLogDirs(partitionMapIn, partitionMapOut, partitionMeta, brokerMeta)
+		printLogDirAssignments(partitionMapOut)
+	}
+
 	// Print broker assignment statistics.
-	errs := printBrokerAssignmentStats(cmd, partitionMapIn, partitionMapOut, brokersIn, brokersOut)
+	errs = append(errs, printBrokerAssignmentStats(cmd, partitionMapIn, partitionMapOut, brokersIn, brokersOut)...)
+
+	// Ensure the output map satisfies all policies.
+	errs = append(errs, policies.violations(partitionMapOut, brokersOut, placementPolicies)...)
+
+	// Save a bundle of plan inputs if configured.
+	saveBundle(cmd, zk)
 
 	// Handle errors that are possible
 	// to be overridden by the user (aka
 	// 'WARN' in topicmappr console output).
 	handleOverridableErrs(cmd, errs)
 
+	// Review and edit the plan if configured.
+	partitionMapOut, brokersOut = reviewPlan(cmd, partitionMapIn, partitionMapOut, brokersIn, brokersOut, partitionMeta, policies, placementPolicies)
+
+	// Write a plan report if configured.
+	writeReport(cmd, partitionMapIn, partitionMapOut, partitionMeta, brokersIn, brokersOut)
+
 	// Ignore no-ops; rebalances will naturally have
 	// a high percentage of these.
 	partitionMapIn, partitionMapOut = skipReassignmentNoOps(partitionMapIn, partitionMapOut)
 
+	// Split the output map into batches if enabled.
+	batches := getBatches(cmd, partitionMapIn, partitionMapOut, partitionMeta)
+
+	// Estimate the reassignment duration if configured.
+	phases := reassignmentPhases(partitionMapIn, partitionMapOut, nil, batches)
+	printReassignmentEstimate(cmd, phases, partitionMeta, brokerMeta)
+
 	// Write maps.
-	writeMaps(cmd, partitionMapOut, nil)
+	writeMaps(cmd, partitionMapOut, nil, partitionMapIn)
+	writeBatches(cmd, batches)
 }
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+var rebalanceDisksCmd = &cobra.Command{
+	Use:   "rebalance-disks",
+	Short: "Rebalance replicas among the log dirs of each broker",
+	Long: `rebalance-disks plans intra-broker replica moves between the log dirs (data
+disks) of brokers configured with multiple log dirs. The current replica placements
+are read from the output of kafka-log-dirs --describe provided via --log-dirs-file.
+For each broker, replicas are moved from the most utilized log dir to the least
+utilized log dir until no move improves the balance. Log dirs are balanced by storage
+used percentage if per log dir storage free and total metrics are available (see the
+metricsfetcher log dir queries), by storage free if only storage free is available,
+and otherwise by the sum of replica sizes. Replica sets are not changed.
+
+The output maps set the destination log dir of each moved replica and must be
+applied with kafka-reassign-partitions --bootstrap-server; the execute command
+doesn't support log dirs.`,
+	Run: rebalanceDisks,
+}
+
+func init() {
+	rootCmd.AddCommand(rebalanceDisksCmd)
+
+	rebalanceDisksCmd.Flags().String("log-dirs-file", "", "File with the output of kafka-log-dirs --describe for the brokers to rebalance")
+	rebalanceDisksCmd.Flags().String("brokers", "", "Brokers to rebalance (comma delim. list); defaults to all brokers in the log dirs file")
+	rebalanceDisksCmd.Flags().Int("partition-size-threshold", 512, "Size in megabytes where partitions below this value will not be moved")
+	rebalanceDisksCmd.Flags().String("bootstrap-server", "localhost:9092", "Kafka bootstrap server(s) used in the printed apply command")
+	rebalanceDisksCmd.Flags().String("out-path", "", "Path to write output map files to")
+	rebalanceDisksCmd.Flags().String("out-file", "", "If defined, write a combined map of all topics to a file")
+	rebalanceDisksCmd.Flags().String("zk-metrics-prefix", "topicmappr", "ZooKeeper namespace prefix for Kafka metrics")
+	rebalanceDisksCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes) (when log dir metrics are available)")
+
+	// Required.
+	rebalanceDisksCmd.MarkFlagRequired("log-dirs-file")
+}
+
+func rebalanceDisks(cmd *cobra.Command, _ []string) {
+	threshold, _ := cmd.Flags().GetInt("partition-size-threshold")
+
+	if threshold < 0 {
+		fmt.Println("\n[ERROR] --partition-size-threshold must be >= 0")
+		defaultsAndExit()
+	}
+
+	data, err := ioutil.ReadFile(cmd.Flag("log-dirs-file").Value.String())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	description, err := parseLogDirs(data)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	bootstrap(cmd)
+
+	// ZooKeeper init.
+	zk, err := initZooKeeper(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	defer zk.Close()
+
+	// Log dir metrics are optional; log dirs are balanced by
+	// replica sizes if unavailable. Only check the metrics
+	// age if any broker has log dir metrics.
+	brokerMeta, _ := zk.GetAllBrokerMeta(true)
+	for _, m := range brokerMeta {
+		if len(m.LogDirs) > 0 {
+			checkMetaAge(cmd, zk)
+			break
+		}
+	}
+
+	brokers, errs := newBrokerLogDirs(description, brokerMeta, Config.brokers)
+	before := map[int]*brokerLogDirs{}
+	for id, b := range brokers {
+		before[id] = b.copy()
+	}
+
+	// Plan moves for each broker.
+	var ids []int
+	for id := range brokers {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	fmt.Printf("\nBrokers:\n")
+	for _, id := range ids {
+		fmt.Printf("%s%d: %d log dir(s), balanced by %s\n", indent, id, len(brokers[id].dirs), brokers[id].mode)
+		brokers[id].plan(float64(threshold) * 1048576.00)
+	}
+
+	moves := logDirMoves(brokers)
+
+	// Get the current replica sets of
+	// the partitions being moved.
+	topics := map[string]struct{}{}
+	for _, m := range moves {
+		topics[m.topic] = struct{}{}
+	}
+
+	var topicRegex []*regexp.Regexp
+	for t := range topics {
+		topicRegex = append(topicRegex, regexp.MustCompile(fmt.Sprintf(`^%s$`, regexp.QuoteMeta(t))))
+	}
+
+	partitionMap := kafkazk.NewPartitionMap()
+	if len(topicRegex) > 0 {
+		partitionMap, err = kafkazk.PartitionMapFromZK(topicRegex, zk)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	partitionMapIn, partitionMapOut, moveErrs := logDirMoveMaps(moves, partitionMap)
+	errs = append(errs, moveErrs...)
+
+	// Print planned moves and log dir storage changes.
+	printLogDirMoves(moves)
+	printLogDirChanges(before, brokers)
+
+	// Print error/warnings.
+	handleOverridableErrs(cmd, errs)
+
+	writeMaps(cmd, partitionMapOut, nil, partitionMapIn)
+
+	if len(partitionMapOut.Partitions) > 0 {
+		fmt.Println("\nApply with:")
+		fmt.Printf("%skafka-reassign-partitions --bootstrap-server %s --reassignment-json-file <map file> --execute\n",
+			indent, cmd.Flag("bootstrap-server").Value.String())
+	}
+}
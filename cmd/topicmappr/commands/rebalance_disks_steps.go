@@ -0,0 +1,493 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+// logDirsDescription is the output of kafka-log-dirs --describe.
+type logDirsDescription struct {
+	Version int `json:"version"`
+	Brokers []struct {
+		Broker  int `json:"broker"`
+		LogDirs []struct {
+			LogDir     string  `json:"logDir"`
+			Error      *string `json:"error"`
+			Partitions []struct {
+				Partition string  `json:"partition"`
+				Size      float64 `json:"size"`
+				IsFuture  bool    `json:"isFuture"`
+			} `json:"partitions"`
+		} `json:"logDirs"`
+	} `json:"brokers"`
+}
+
+// parseLogDirs takes the output of kafka-log-dirs --describe and
+// returns a *logDirsDescription. Any informational lines preceding
+// the JSON description are ignored.
+func parseLogDirs(data []byte) (*logDirsDescription, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		d := &logDirsDescription{}
+		if err := json.Unmarshal([]byte(line), d); err != nil {
+			return nil, fmt.Errorf("Error parsing log dirs: %s", err)
+		}
+
+		return d, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("No log dirs description found")
+}
+
+// parseTopicPartition takes a topic partition
+// name, e.g. "topic-0", and returns the topic
+// and partition number.
+func parseTopicPartition(s string) (string, int, error) {
+	i := strings.LastIndex(s, "-")
+	if i < 1 {
+		return "", 0, fmt.Errorf("Invalid topic partition %s", s)
+	}
+
+	p, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return "", 0, fmt.Errorf("Invalid topic partition %s", s)
+	}
+
+	return s[:i], p, nil
+}
+
+// logDirReplica is a replica in a broker log dir.
+type logDirReplica struct {
+	topic     string
+	partition int
+	size      float64
+	// The log dir the replica is
+	// currently stored in.
+	origin string
+}
+
+// logDir holds the replicas and storage state of a broker log dir.
+type logDir struct {
+	path     string
+	used     float64 // Sum of replica sizes in bytes.
+	free     float64 // In bytes; 0 if unknown.
+	total    float64 // In bytes; 0 if unknown.
+	replicas []*logDirReplica
+}
+
+// Log dir balance modes, in order of preference:
+// storage used percentage, storage free and
+// the sum of replica sizes.
+const (
+	logDirBalanceUsed = "storage_used"
+	logDirBalanceFree = "storage_free"
+	logDirBalanceSize = "size"
+)
+
+// brokerLogDirs holds the log dirs of a broker.
+type brokerLogDirs struct {
+	id   int
+	mode string
+	dirs []*logDir
+}
+
+// newBrokerLogDirs takes a *logDirsDescription, a BrokerMetaMap and a
+// list of broker IDs (nil for all brokers in the description) and
+// returns a *brokerLogDirs for each broker. The balance mode of each
+// broker depends on the log dir metrics available: storage used
+// percentages if all log dirs have a storage free and total, storage
+// free if all log dirs have a storage free, otherwise the sum of
+// replica sizes. Log dirs reporting an error and replicas with a
+// pending move (future replicas) are excluded.
+func newBrokerLogDirs(d *logDirsDescription, bmm kafkazk.BrokerMetaMap, ids []int) (map[int]*brokerLogDirs, errors) {
+	var errs errors
+
+	scope := map[int]struct{}{}
+	for _, id := range ids {
+		scope[id] = struct{}{}
+	}
+
+	out := map[int]*brokerLogDirs{}
+
+	for _, b := range d.Brokers {
+		if _, ok := scope[b.Broker]; len(ids) > 0 && !ok {
+			continue
+		}
+
+		bld := &brokerLogDirs{id: b.Broker}
+
+		// Partitions with a future replica on the
+		// broker are already being moved.
+		future := map[string]struct{}{}
+		for _, ld := range b.LogDirs {
+			for _, p := range ld.Partitions {
+				if p.IsFuture {
+					future[p.Partition] = struct{}{}
+				}
+			}
+		}
+
+		for _, ld := range b.LogDirs {
+			if ld.Error != nil {
+				errs = append(errs, fmt.Errorf("Broker %d log dir %s: %s; excluded", b.Broker, ld.LogDir, *ld.Error))
+				continue
+			}
+
+			dir := &logDir{path: ld.LogDir}
+
+			for _, p := range ld.Partitions {
+				dir.used += p.Size
+
+				if _, ok := future[p.Partition]; ok {
+					continue
+				}
+
+				topic, partn, err := parseTopicPartition(p.Partition)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+
+				dir.replicas = append(dir.replicas, &logDirReplica{
+					topic:     topic,
+					partition: partn,
+					size:      p.Size,
+					origin:    ld.LogDir,
+				})
+			}
+
+			bld.dirs = append(bld.dirs, dir)
+		}
+
+		if len(future) > 0 {
+			errs = append(errs, fmt.Errorf("Broker %d has %d partition(s) with a pending log dir move; excluded", b.Broker, len(future)))
+		}
+
+		sort.Slice(bld.dirs, func(i, j int) bool {
+			return bld.dirs[i].path < bld.dirs[j].path
+		})
+
+		bld.setMode(bmm[b.Broker])
+		out[b.Broker] = bld
+	}
+
+	for _, id := range ids {
+		if _, ok := out[id]; !ok {
+			errs = append(errs, fmt.Errorf("Broker %d not found in log dirs description", id))
+		}
+	}
+
+	return out, errs
+}
+
+// setMode sets the balance mode and log dir storage
+// metrics from the broker's *BrokerMeta.
+func (b *brokerLogDirs) setMode(m *kafkazk.BrokerMeta) {
+	b.mode = logDirBalanceSize
+
+	if m == nil || len(b.dirs) == 0 {
+		return
+	}
+
+	totals := true
+	for _, dir := range b.dirs {
+		ldm, ok := m.LogDirs[dir.path]
+		if !ok {
+			return
+		}
+		totals = totals && ldm.StorageTotal > 0
+	}
+
+	b.mode = logDirBalanceFree
+	if totals {
+		b.mode = logDirBalanceUsed
+	}
+
+	for _, dir := range b.dirs {
+		dir.free = m.LogDirs[dir.path].StorageFree
+		dir.total = m.LogDirs[dir.path].StorageTotal
+	}
+}
+
+// value returns the utilization of the log dir under the
+// broker's balance mode if d bytes were added to it.
+// Higher values are more utilized.
+func (b *brokerLogDirs) value(dir *logDir, d float64) float64 {
+	switch b.mode {
+	case logDirBalanceUsed:
+		return (dir.total - dir.free + d) / dir.total * 100
+	case logDirBalanceFree:
+		return -(dir.free - d) / div
+	default:
+		return (dir.used + d) / div
+	}
+}
+
+// format returns the log dir value as a string.
+func (b *brokerLogDirs) format(dir *logDir) string {
+	switch b.mode {
+	case logDirBalanceUsed:
+		return fmt.Sprintf("%.2f%% used", b.value(dir, 0))
+	case logDirBalanceFree:
+		return fmt.Sprintf("%.2fGB free", dir.free/div)
+	default:
+		return fmt.Sprintf("%.2fGB", dir.used/div)
+	}
+}
+
+// extremes returns the most and least utilized log dirs.
+func (b *brokerLogDirs) extremes() (*logDir, *logDir) {
+	var hi, lo *logDir
+	for _, dir := range b.dirs {
+		if hi == nil || b.value(dir, 0) > b.value(hi, 0) {
+			hi = dir
+		}
+		if lo == nil || b.value(dir, 0) < b.value(lo, 0) {
+			lo = dir
+		}
+	}
+
+	return hi, lo
+}
+
+// move moves the replica at index i of the src log dir to the dst log dir.
+func (b *brokerLogDirs) move(src, dst *logDir, i int) {
+	r := src.replicas[i]
+
+	src.replicas = append(src.replicas[:i], src.replicas[i+1:]...)
+	src.used -= r.size
+	src.free += r.size
+
+	dst.replicas = append(dst.replicas, r)
+	dst.used += r.size
+	dst.free -= r.size
+}
+
+// plan greedily moves replicas of at least the threshold size (in
+// bytes) from the most utilized log dir to the least utilized log
+// dir. Each step moves the replica that minimizes the sum of squared
+// log dir values of the two log dirs; planning ends when no move
+// reduces the sum.
+func (b *brokerLogDirs) plan(threshold float64) {
+	for {
+		src, dst := b.extremes()
+		if src == dst {
+			return
+		}
+
+		best := -1
+		bestCost := sq(b.value(src, 0)) + sq(b.value(dst, 0))
+
+		for i, r := range src.replicas {
+			if r.size < threshold {
+				continue
+			}
+
+			cost := sq(b.value(src, -r.size)) + sq(b.value(dst, r.size))
+			if cost < bestCost {
+				best, bestCost = i, cost
+			}
+		}
+
+		if best < 0 {
+			return
+		}
+
+		b.move(src, dst, best)
+	}
+}
+
+// copy returns a copy of the *brokerLogDirs.
+func (b *brokerLogDirs) copy() *brokerLogDirs {
+	c := &brokerLogDirs{id: b.id, mode: b.mode}
+	for _, dir := range b.dirs {
+		d := *dir
+		d.replicas = make([]*logDirReplica, len(dir.replicas))
+		for i, r := range dir.replicas {
+			rc := *r
+			d.replicas[i] = &rc
+		}
+		c.dirs = append(c.dirs, &d)
+	}
+
+	return c
+}
+
+func sq(f float64) float64 { return f * f }
+
+// logDirMove is a replica relocation between log dirs of a broker.
+type logDirMove struct {
+	broker    int
+	topic     string
+	partition int
+	size      float64
+	from      string
+	to        string
+}
+
+// logDirMoves returns the replicas of each broker stored in a log
+// dir other than their origin, ordered by broker ID, topic and
+// partition.
+func logDirMoves(bld map[int]*brokerLogDirs) []logDirMove {
+	var moves []logDirMove
+
+	for id, b := range bld {
+		for _, dir := range b.dirs {
+			for _, r := range dir.replicas {
+				if r.origin != dir.path {
+					moves = append(moves, logDirMove{
+						broker:    id,
+						topic:     r.topic,
+						partition: r.partition,
+						size:      r.size,
+						from:      r.origin,
+						to:        dir.path,
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(moves, func(i, j int) bool {
+		switch {
+		case moves[i].broker != moves[j].broker:
+			return moves[i].broker < moves[j].broker
+		case moves[i].topic != moves[j].topic:
+			return moves[i].topic < moves[j].topic
+		default:
+			return moves[i].partition < moves[j].partition
+		}
+	})
+
+	return moves
+}
+
+// logDirMoveMaps takes a list of logDirMove and the *PartitionMap of the
+// current replica sets and returns the original and updated maps of the
+// moved partitions. The updated map sets the destination log dir of each
+// moved replica and the original map sets the origin log dir; all other
+// replicas are AnyLogDir. An error is returned for each move of a replica
+// not found in the current replica sets.
+func logDirMoveMaps(moves []logDirMove, pm *kafkazk.PartitionMap) (*kafkazk.PartitionMap, *kafkazk.PartitionMap, errors) {
+	var errs errors
+
+	current := map[string]map[int]kafkazk.Partition{}
+	for _, p := range pm.Partitions {
+		if _, exist := current[p.Topic]; !exist {
+			current[p.Topic] = map[int]kafkazk.Partition{}
+		}
+		current[p.Topic][p.Partition] = p
+	}
+
+	type key struct {
+		topic     string
+		partition int
+	}
+
+	index := map[key]int{}
+	orig, out := kafkazk.NewPartitionMap(), kafkazk.NewPartitionMap()
+
+	for _, m := range moves {
+		p, exist := current[m.topic][m.partition]
+		pos := -1
+		if exist {
+			for i, id := range p.Replicas {
+				if id == m.broker {
+					pos = i
+				}
+			}
+		}
+
+		if pos < 0 {
+			errs = append(errs, fmt.Errorf("%s p%d: broker %d not in replica set; move skipped", m.topic, m.partition, m.broker))
+			continue
+		}
+
+		k := key{m.topic, m.partition}
+		i, exist := index[k]
+		if !exist {
+			i = len(out.Partitions)
+			index[k] = i
+
+			for _, pm2 := range []*kafkazk.PartitionMap{orig, out} {
+				partn := kafkazk.Partition{
+					Topic:     p.Topic,
+					Partition: p.Partition,
+					Replicas:  append([]int{}, p.Replicas...),
+					LogDirs:   make([]string, len(p.Replicas)),
+				}
+				for n := range partn.LogDirs {
+					partn.LogDirs[n] = kafkazk.AnyLogDir
+				}
+				pm2.Partitions = append(pm2.Partitions, partn)
+			}
+		}
+
+		orig.Partitions[i].LogDirs[pos] = m.from
+		out.Partitions[i].LogDirs[pos] = m.to
+	}
+
+	sort.Sort(orig.Partitions)
+	sort.Sort(out.Partitions)
+
+	return orig, out, errs
+}
+
+// printLogDirMoves prints the planned log dir relocations.
+func printLogDirMoves(moves []logDirMove) {
+	fmt.Println("\nPlanned log dir relocations:")
+
+	if len(moves) == 0 {
+		fmt.Printf("%s[none]\n", indent)
+		return
+	}
+
+	var total float64
+	for _, m := range moves {
+		fmt.Printf("%sbroker %d: %s p%d [%.2fGB] %s -> %s\n",
+			indent, m.broker, m.topic, m.partition, m.size/div, m.from, m.to)
+		total += m.size
+	}
+
+	fmt.Printf("%s-\n", indent)
+	fmt.Printf("%s%d relocation(s), %.2fGB moved\n", indent, len(moves), total/div)
+}
+
+// printLogDirChanges prints the utilization of each
+// broker log dir before and after the planned moves.
+func printLogDirChanges(before, after map[int]*brokerLogDirs) {
+	fmt.Println("\nLog dir storage change estimations:")
+
+	var ids []int
+	for id := range after {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		b1, b2 := before[id], after[id]
+		for i, dir := range b2.dirs {
+			fmt.Printf("%sBroker %d %s: %s -> %s\n",
+				indent, id, dir.path, b1.format(b1.dirs[i]), b2.format(dir))
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func testLogDirsDescription() string {
+	return `Querying brokers for log directories information
+Received log directory information from brokers 1001,1002
+{"version":1,"brokers":[` +
+		`{"broker":1001,"logDirs":[` +
+		`{"logDir":"/data1","error":null,"partitions":[` +
+		`{"partition":"test_topic-0","size":400,"offsetLag":0,"isFuture":false},` +
+		`{"partition":"test_topic-1","size":300,"offsetLag":0,"isFuture":false},` +
+		`{"partition":"test-topic-2","size":200,"offsetLag":0,"isFuture":false}]},` +
+		`{"logDir":"/data2","error":null,"partitions":[` +
+		`{"partition":"test_topic-3","size":100,"offsetLag":0,"isFuture":false}]}]},` +
+		`{"broker":1002,"logDirs":[` +
+		`{"logDir":"/data1","error":"KafkaStorageException","partitions":[]},` +
+		`{"logDir":"/data2","error":null,"partitions":[` +
+		`{"partition":"test_topic-0","size":400,"offsetLag":0,"isFuture":false}]}]}]}
+`
+}
+
+func TestParseLogDirs(t *testing.T) {
+	d, err := parseLogDirs([]byte(testLogDirsDescription()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Brokers) != 2 || len(d.Brokers[0].LogDirs) != 2 {
+		t.Fatalf("Unexpected log dirs description %v", d)
+	}
+
+	if p := d.Brokers[0].LogDirs[0].Partitions[2].Partition; p != "test-topic-2" {
+		t.Errorf("Expected partition test-topic-2, got %s", p)
+	}
+
+	if _, err := parseLogDirs([]byte("no log dirs\n")); err == nil {
+		t.Error("Expected error")
+	}
+
+	topic, p, err := parseTopicPartition("test-topic-2")
+	if err != nil || topic != "test-topic" || p != 2 {
+		t.Errorf("Expected test-topic 2, got %s %d (%v)", topic, p, err)
+	}
+
+	if _, _, err := parseTopicPartition("test_topic"); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestPlanLogDirMoves(t *testing.T) {
+	d, _ := parseLogDirs([]byte(testLogDirsDescription()))
+
+	brokers, errs := newBrokerLogDirs(d, kafkazk.BrokerMetaMap{}, nil)
+
+	// 1002 /data1 is offline.
+	if len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %v", errs)
+	}
+
+	if n := len(brokers[1002].dirs); n != 1 {
+		t.Errorf("Expected 1 log dir, got %d", n)
+	}
+
+	for _, b := range brokers {
+		if b.mode != logDirBalanceSize {
+			t.Errorf("Expected mode %s, got %s", logDirBalanceSize, b.mode)
+		}
+		b.plan(0)
+	}
+
+	// 1001 /data1 holds 900 and /data2 holds 100;
+	// moving test_topic-0 (400) balances both at 500.
+	moves := logDirMoves(brokers)
+	if len(moves) != 1 {
+		t.Fatalf("Expected 1 move, got %v", moves)
+	}
+
+	m := moves[0]
+	if m.broker != 1001 || m.topic != "test_topic" || m.partition != 0 || m.from != "/data1" || m.to != "/data2" {
+		t.Errorf("Unexpected move %v", m)
+	}
+
+	pm, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1002,1001]}]}`)
+
+	orig, out, errs := logDirMoveMaps(moves, pm)
+	if len(errs) != 0 {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+
+	if dirs := orig.Partitions[0].LogDirs; dirs[0] != kafkazk.AnyLogDir || dirs[1] != "/data1" {
+		t.Errorf("Unexpected original log dirs %v", dirs)
+	}
+
+	if dirs := out.Partitions[0].LogDirs; dirs[0] != kafkazk.AnyLogDir || dirs[1] != "/data2" {
+		t.Errorf("Unexpected log dirs %v", dirs)
+	}
+
+	// Storage used percentages are used
+	// with complete log dir metrics.
+	bmm := kafkazk.BrokerMetaMap{
+		1001: &kafkazk.BrokerMeta{
+			LogDirs: kafkazk.LogDirMetaMap{
+				"/data1": &kafkazk.LogDirMeta{StorageFree: 100, StorageTotal: 1000},
+				"/data2": &kafkazk.LogDirMeta{StorageFree: 1900, StorageTotal: 2000},
+			},
+		},
+	}
+
+	brokers, _ = newBrokerLogDirs(d, bmm, []int{1001})
+	if len(brokers) != 1 || brokers[1001].mode != logDirBalanceUsed {
+		t.Fatalf("Unexpected brokers %v", brokers)
+	}
+
+	// /data1 is 90% used and /data2 is 5% used; moving
+	// test_topic-0 (400) results in 50% and 25%, then
+	// test_topic-1 (300) results in 20% and 40%.
+	brokers[1001].plan(0)
+
+	moves = logDirMoves(brokers)
+	if len(moves) != 2 || moves[0].partition != 0 || moves[1].partition != 1 {
+		t.Errorf("Unexpected moves %v", moves)
+	}
+
+	// Partitions below the size threshold aren't moved.
+	brokers, _ = newBrokerLogDirs(d, bmm, []int{1001})
+	brokers[1001].plan(500)
+
+	if moves = logDirMoves(brokers); len(moves) != 0 {
+		t.Errorf("Unexpected moves %v", moves)
+	}
+}
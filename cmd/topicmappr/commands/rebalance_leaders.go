@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+var rebalanceLeadersCmd = &cobra.Command{
+	Use:   "rebalance-leaders",
+	Short: "Rebalance preferred leadership by reordering replicas without moving data",
+	Long: `rebalance-leaders reorders the replicas within existing replica sets so that
+each broker holds an even share of preferred leaders. Replica sets are never changed,
+so no data is moved. Leadership can be weighted by partition count, partition size
+or partition throughput via --weight. In addition to the partition maps, a preferred replica election file
+is written for use with kafka-preferred-replica-election.`,
+	Run: rebalanceLeaders,
+}
+
+func init() {
+	rootCmd.AddCommand(rebalanceLeadersCmd)
+
+	rebalanceLeadersCmd.Flags().String("topics", "", "Limit the scope to topics (comma delim. list) by lookup in ZooKeeper (default: all topics)")
+	rebalanceLeadersCmd.Flags().String("weight", "count", "Leadership weight: [count, size, throughput]")
+	rebalanceLeadersCmd.Flags().String("out-path", "", "Path to write output map files to")
+	rebalanceLeadersCmd.Flags().String("out-file", "", "If defined, write a combined map of all topics to a file")
+	rebalanceLeadersCmd.Flags().String("zk-metrics-prefix", "topicmappr", "ZooKeeper namespace prefix for Kafka metrics (when using size or throughput weights)")
+	rebalanceLeadersCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes) (when using size or throughput weights)")
+}
+
+func rebalanceLeaders(cmd *cobra.Command, _ []string) {
+	w := cmd.Flag("weight").Value.String()
+
+	switch {
+	case w != "count" && w != "size" && w != "throughput":
+		fmt.Println("\n[ERROR] --weight must be one of 'count', 'size' or 'throughput'")
+		defaultsAndExit()
+	}
+
+	bootstrap(cmd)
+
+	// Default to all topics.
+	if len(Config.topics) == 0 {
+		Config.topics = []*regexp.Regexp{regexp.MustCompile(".*")}
+	}
+
+	// ZooKeeper init.
+	zk, err := initZooKeeper(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	defer zk.Close()
+
+	// Partition metadata is only required
+	// for size and throughput weights.
+	var partitionMeta kafkazk.PartitionMetaMap
+	if w != "count" {
+		checkMetaAge(cmd, zk)
+		partitionMeta = getPartitionMeta(cmd, zk)
+	}
+
+	// Get the current partition map.
+	partitionMapIn, err := kafkazk.PartitionMapFromZK(Config.topics, zk)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Exclude any topics that are pending deletion.
+	pending := stripPendingDeletes(partitionMapIn, zk)
+
+	printTopics(partitionMapIn)
+	printExcludedTopics(pending)
+
+	weights, err := getLeaderWeights(partitionMapIn, partitionMeta, w)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	partitionMapOut := partitionMapIn.Copy()
+	changes := optimizeLeaders(partitionMapOut, weights)
+
+	fmt.Printf("\nAction:\n")
+	fmt.Printf("%sReordering replicas for %d partition(s)\n", indent, changes)
+
+	// Print map change results.
+	printMapChanges(partitionMapIn, partitionMapOut)
+
+	// Print weighted leadership.
+	printLeaderLoads(partitionMapIn, partitionMapOut, weights, w)
+
+	// Print broker assignment statistics.
+	printBrokerAssignmentStats(cmd, partitionMapIn, partitionMapOut, nil, nil)
+
+	election := preferredReplicaElection(partitionMapIn, partitionMapOut)
+
+	_, partitionMapOut = skipReassignmentNoOps(partitionMapIn, partitionMapOut)
+
+	writeMaps(cmd, partitionMapOut, nil, partitionMapIn)
+
+	if len(election) == 0 {
+		return
+	}
+
+	// Write the preferred replica election file.
+	path := cmd.Flag("out-path").Value.String() + "preferred-replica-election.json"
+	if err := writePreferredReplicaElection(election, path); err != nil {
+		fmt.Printf("%s%s\n", indent, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s%s [preferred replica election]\n", indent, path)
+}
@@ -0,0 +1,243 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+// leaderWeights is a mapping of topic, partition
+// number to the weight of the partition's leadership.
+type leaderWeights map[string]map[int]float64
+
+// weight returns the weight for a partition, defaulting to 1.
+func (l leaderWeights) weight(p kafkazk.Partition) float64 {
+	if w, exist := l[p.Topic][p.Partition]; exist {
+		return w
+	}
+
+	return 1
+}
+
+// getLeaderWeights takes a *PartitionMap, PartitionMetaMap and weight
+// method and returns leaderWeights. The "count" method weights all
+// partitions equally; the "size" method weights partitions by size and
+// the "throughput" method by the sum of bytes in and bytes out.
+func getLeaderWeights(pm *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap, method string) (leaderWeights, error) {
+	lw := leaderWeights{}
+
+	for _, p := range pm.Partitions {
+		if _, exist := lw[p.Topic]; !exist {
+			lw[p.Topic] = map[int]float64{}
+		}
+
+		switch method {
+		case "count":
+			lw[p.Topic][p.Partition] = 1
+		case "size":
+			s, err := pmm.Size(p)
+			if err != nil {
+				return nil, err
+			}
+			lw[p.Topic][p.Partition] = s
+		case "throughput":
+			t, err := pmm.Throughput(p)
+			if err != nil {
+				return nil, err
+			}
+			lw[p.Topic][p.Partition] = t
+		default:
+			return nil, fmt.Errorf("invalid weight method '%s'", method)
+		}
+	}
+
+	return lw, nil
+}
+
+// leaderLoads returns the sum of leaderWeights
+// for each leader in the *PartitionMap.
+func leaderLoads(pm *kafkazk.PartitionMap, lw leaderWeights) map[int]float64 {
+	loads := map[int]float64{}
+
+	// Brokers holding any replica have a load.
+	for _, p := range pm.Partitions {
+		for _, id := range p.Replicas {
+			if _, exist := loads[id]; !exist {
+				loads[id] = 0
+			}
+		}
+	}
+
+	for _, p := range pm.Partitions {
+		if len(p.Replicas) > 0 {
+			loads[p.Replicas[0]] += lw.weight(p)
+		}
+	}
+
+	return loads
+}
+
+// optimizeLeaders takes a *PartitionMap and leaderWeights and reorders
+// replica sets in place so that the weighted leadership of each broker is
+// as even as possible. Only replica order is changed; replica sets retain
+// the same brokers. Starting from the current leaders, partitions (heaviest
+// first) have leadership transferred to the least loaded follower only if
+// the follower's resulting load is less than the current leader's load.
+// This strictly improves the distribution with each transfer and minimizes
+// the number of leadership changes. The remaining replicas keep their
+// relative order. The number of leadership changes is returned.
+func optimizeLeaders(pm *kafkazk.PartitionMap, lw leaderWeights) int {
+	loads := leaderLoads(pm, lw)
+
+	// Partition indexes sorted by weight, descending.
+	order := make([]int, len(pm.Partitions))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return lw.weight(pm.Partitions[order[i]]) > lw.weight(pm.Partitions[order[j]])
+	})
+
+	changed := map[int]struct{}{}
+
+	for {
+		var moved bool
+
+		for _, i := range order {
+			p := pm.Partitions[i]
+			if len(p.Replicas) < 2 {
+				continue
+			}
+
+			leader, w := p.Replicas[0], lw.weight(p)
+
+			// Find the least loaded follower.
+			best := -1
+			for n, id := range p.Replicas[1:] {
+				if best == -1 || loads[id] < loads[p.Replicas[best]] {
+					best = n + 1
+				}
+			}
+
+			if loads[p.Replicas[best]]+w >= loads[leader] {
+				continue
+			}
+
+			// Transfer leadership.
+			loads[leader] -= w
+			loads[p.Replicas[best]] += w
+
+			id := p.Replicas[best]
+			copy(p.Replicas[1:best+1], p.Replicas[:best])
+			p.Replicas[0] = id
+
+			changed[i] = struct{}{}
+			moved = true
+		}
+
+		if !moved {
+			break
+		}
+	}
+
+	return len(changed)
+}
+
+// electionPartition is a partition reference in the
+// kafka-preferred-replica-election JSON format.
+type electionPartition struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+}
+
+// preferredReplicaElection returns the partitions with a changed
+// leader between the original and new *PartitionMap.
+func preferredReplicaElection(pm1, pm2 *kafkazk.PartitionMap) []electionPartition {
+	partitions := []electionPartition{}
+
+	for i := range pm1.Partitions {
+		p1, p2 := pm1.Partitions[i], pm2.Partitions[i]
+		if len(p1.Replicas) == 0 || len(p2.Replicas) == 0 {
+			continue
+		}
+
+		if p1.Replicas[0] != p2.Replicas[0] {
+			partitions = append(partitions, electionPartition{
+				Topic:     p2.Topic,
+				Partition: p2.Partition,
+			})
+		}
+	}
+
+	return partitions
+}
+
+// writePreferredReplicaElection writes the partitions to the path in
+// the format used by kafka-preferred-replica-election --path-to-json-file.
+func writePreferredReplicaElection(partitions []electionPartition, path string) error {
+	out, err := json.Marshal(struct {
+		Partitions []electionPartition `json:"partitions"`
+	}{Partitions: partitions})
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, out, 0644)
+}
+
+// printLeaderLoads prints before and after weighted leadership per broker.
+func printLeaderLoads(pm1, pm2 *kafkazk.PartitionMap, lw leaderWeights, method string) {
+	l1, l2 := leaderLoads(pm1, lw), leaderLoads(pm2, lw)
+
+	fmt.Printf("\nLeadership distribution (by %s):\n", method)
+
+	ids := []int{}
+	for id := range l1 {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	unit := func(v float64) string {
+		switch method {
+		case "size":
+			return fmt.Sprintf("%.2fGB", v/div)
+		case "throughput":
+			return fmt.Sprintf("%.2fMB/s", v/throttleUnit)
+		}
+		return fmt.Sprintf("%.0f", v)
+	}
+
+	for _, id := range ids {
+		fmt.Printf("%sBroker %d: %s -> %s\n", indent, id, unit(l1[id]), unit(l2[id]))
+	}
+
+	min1, max1 := loadRange(l1)
+	min2, max2 := loadRange(l2)
+
+	fmt.Printf("%s-\n", indent)
+	fmt.Printf("%smin-max: %s, %s -> %s, %s\n",
+		indent, unit(min1), unit(max1), unit(min2), unit(max2))
+}
+
+// loadRange returns the min and max values.
+func loadRange(loads map[int]float64) (float64, float64) {
+	var min, max float64
+	var set bool
+
+	for _, v := range loads {
+		if !set || v < min {
+			min = v
+		}
+		if !set || v > max {
+			max = v
+		}
+		set = true
+	}
+
+	return min, max
+}
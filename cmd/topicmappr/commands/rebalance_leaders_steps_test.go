@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func TestRebalanceLeaders(t *testing.T) {
+	pm, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002,1003]},
+		{"topic":"test_topic","partition":1,"replicas":[1001,1003,1002]},
+		{"topic":"test_topic","partition":2,"replicas":[1001,1002,1003]},
+		{"topic":"test_topic","partition":3,"replicas":[1001,1003,1002]},
+		{"topic":"test_topic","partition":4,"replicas":[1001,1002,1003]},
+		{"topic":"test_topic","partition":5,"replicas":[1002,1001,1003]}]}`)
+
+	original := pm.Copy()
+
+	lw, _ := getLeaderWeights(pm, nil, "count")
+	changes := optimizeLeaders(pm, lw)
+
+	loads := leaderLoads(pm, lw)
+	for id, l := range loads {
+		if l != 2 {
+			t.Errorf("Expected broker %d leadership of 2, got %.0f", id, l)
+		}
+	}
+
+	// 1001 held 5 leaders; 3 transfers are required.
+	if changes != 3 {
+		t.Errorf("Expected 3 changes, got %d", changes)
+	}
+
+	// Replica sets must be unchanged.
+	for i := range pm.Partitions {
+		if whatChanged(original.Partitions[i].Replicas, pm.Partitions[i].Replicas) == "replaced broker" {
+			t.Errorf("Unexpected replica set change for p%d", i)
+		}
+	}
+
+	election := preferredReplicaElection(original, pm)
+	if len(election) != changes {
+		t.Errorf("Expected %d election partitions, got %d", changes, len(election))
+	}
+}
+
+func TestRebalanceLeadersBySize(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm, _ := zk.GetPartitionMap("test_topic")
+	pmm, _ := zk.GetAllPartitionMeta()
+
+	// Sizes: p0 1000, p1 1500, p2 2000, p3 2500.
+	pm.Partitions[1].Replicas = []int{1001, 1002}
+	pm.Partitions[3].Replicas = []int{1003, 1004, 1002}
+
+	lw, err := getLeaderWeights(pm, pmm, "size")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	optimizeLeaders(pm, lw)
+
+	expected := map[int]float64{1001: 1000, 1002: 1500, 1003: 2000, 1004: 2500}
+	loads := leaderLoads(pm, lw)
+
+	for id, l := range expected {
+		if loads[id] != l {
+			t.Errorf("Expected broker %d leadership of %.0f, got %.0f", id, l, loads[id])
+		}
+	}
+}
+
+func TestGetLeaderWeightsThroughput(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm, _ := zk.GetPartitionMap("test_topic")
+	pmm, _ := zk.GetAllPartitionMeta()
+
+	for i, p := range pmm["test_topic"] {
+		p.BytesIn = float64(i)
+		p.BytesOut = float64(i * 10)
+	}
+
+	lw, err := getLeaderWeights(pm, pmm, "throughput")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range pm.Partitions {
+		if w, e := lw.weight(p), float64(p.Partition*11); w != e {
+			t.Errorf("p%d: expected weight %.0f, got %.0f", p.Partition, e, w)
+		}
+	}
+
+	// Missing partition meta.
+	delete(pmm["test_topic"], 0)
+	if _, err := getLeaderWeights(pm, pmm, "throughput"); err == nil {
+		t.Error("Expected non-nil error")
+	}
+}
@@ -0,0 +1,429 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+// searchInitialTemperature is the starting annealing temperature
+// as a fraction of the initial cost. The temperature is linearly
+// cooled to 0 over the iteration or time budget.
+const searchInitialTemperature = 0.01
+
+// searchParams holds parameters for the search optimizer.
+type searchParams struct {
+	// Iteration and time budgets; a 0 value
+	// disables the respective budget.
+	iterations int
+	timeout    time.Duration
+	seed       int64
+	// Weight of the per broker GB moved (or move
+	// cost) against the storage free std. deviation
+	// in GB.
+	moveWeight float64
+	// If set, relocations are counted
+	// by move cost rather than GB moved.
+	moveCost moveCost
+	// Partitions below this size in
+	// bytes are never relocated.
+	partitionSizeThreshold float64
+	localityScoped         bool
+	policies               kafkazk.PlacementPolicies
+}
+
+// searchStats summarizes a search optimizer run.
+type searchStats struct {
+	iterations  int
+	accepted    int
+	initialCost float64
+	finalCost   float64
+}
+
+// searchMove describes replacing the from broker ID with
+// the to broker ID at a position in a partition replica set.
+type searchMove struct {
+	partition int
+	position  int
+	from      int
+	to        int
+}
+
+// searchState is the working state of the search optimizer.
+// Storage values are tracked in gigabytes. If the StorageTotal
+// is known for all brokers, the std. deviation is of storage
+// used percentages rather than storage free.
+type searchState struct {
+	params     searchParams
+	partitions []kafkazk.Partition
+	original   []map[int]bool
+	sizes      []float64
+	costs      [][2]float64 // Follower and leader replica move costs.
+	eligible   []int
+	ids        []int
+	free       map[int]float64
+	total      map[int]float64
+	locality   map[int]string
+	sum        float64
+	sumSq      float64
+	moved      float64
+	journal    []searchMove
+}
+
+func newSearchState(pm *kafkazk.PartitionMap, bm kafkazk.BrokerMap, pmm kafkazk.PartitionMetaMap, p searchParams) *searchState {
+	s := &searchState{
+		params:     p,
+		partitions: pm.Copy().Partitions,
+		free:       map[int]float64{},
+		locality:   map[int]string{},
+	}
+
+	if bm.HasStorageTotal() {
+		s.total = map[int]float64{}
+	}
+
+	for id, b := range bm {
+		if id == kafkazk.StubBrokerID {
+			continue
+		}
+
+		s.ids = append(s.ids, id)
+		s.free[id] = b.StorageFree / div
+		s.locality[id] = b.Locality
+
+		if s.total != nil {
+			s.total[id] = b.StorageTotal / div
+		}
+
+		v := s.value(id)
+		s.sum += v
+		s.sumSq += v * v
+	}
+
+	sort.Ints(s.ids)
+
+	for i, partn := range s.partitions {
+		size, _ := pmm.Size(partn)
+		s.sizes = append(s.sizes, size/div)
+
+		costs := [2]float64{size / div, size / div}
+		if p.moveCost != nil {
+			costs = [2]float64{p.moveCost.cost(partn, false), p.moveCost.cost(partn, true)}
+		}
+		s.costs = append(s.costs, costs)
+
+		orig := map[int]bool{}
+		eligible := size >= p.partitionSizeThreshold
+		for _, id := range partn.Replicas {
+			orig[id] = true
+			if _, exist := s.free[id]; !exist {
+				eligible = false
+			}
+		}
+
+		s.original = append(s.original, orig)
+
+		if eligible {
+			s.eligible = append(s.eligible, i)
+		}
+	}
+
+	return s
+}
+
+// value returns the balanced storage value of a broker;
+// the storage used percentage if capacities are known,
+// otherwise the storage free in GB.
+func (s *searchState) value(id int) float64 {
+	if s.total != nil {
+		return (s.total[id] - s.free[id]) / s.total[id] * 100
+	}
+
+	return s.free[id]
+}
+
+// stdDev returns the standard deviation of the
+// storage free in GB or storage used percentage.
+func (s *searchState) stdDev() float64 {
+	n := float64(len(s.ids))
+	m := s.sum / n
+
+	return math.Sqrt(math.Max(s.sumSq/n-m*m, 0))
+}
+
+// cost returns the storage std. deviation plus the
+// weighted GB moved (or move cost) per broker.
+func (s *searchState) cost() float64 {
+	return s.stdDev() + s.params.moveWeight*s.moved/float64(len(s.ids))
+}
+
+// allowed returns whether a searchMove satisfies the replica
+// set, placement policy and rack ID constraints.
+func (s *searchState) allowed(m searchMove) bool {
+	partn := s.partitions[m.partition]
+
+	if m.from == m.to || !s.params.policies.Get(partn.Topic).Allows(m.to) {
+		return false
+	}
+
+	if s.params.localityScoped && s.locality[m.to] != s.locality[m.from] {
+		return false
+	}
+
+	for i, id := range partn.Replicas {
+		if i == m.position {
+			continue
+		}
+
+		if id == m.to {
+			return false
+		}
+
+		// Rack IDs must be unique within the replica set.
+		if l := s.locality[m.to]; l != "" && s.locality[id] == l {
+			return false
+		}
+	}
+
+	return true
+}
+
+// apply applies a searchMove, updating storage free,
+// the std. deviation terms and the volume moved.
+func (s *searchState) apply(m searchMove) {
+	size := s.sizes[m.partition]
+
+	// The leader is at position 0.
+	var cost float64
+	switch m.position {
+	case 0:
+		cost = s.costs[m.partition][1]
+	default:
+		cost = s.costs[m.partition][0]
+	}
+
+	s.partitions[m.partition].Replicas[m.position] = m.to
+
+	s.adjustFree(m.from, size)
+	s.adjustFree(m.to, -size)
+
+	// Bytes moved counts replicas on brokers
+	// not in the original replica set.
+	if !s.original[m.partition][m.from] {
+		s.moved -= cost
+	}
+
+	if !s.original[m.partition][m.to] {
+		s.moved += cost
+	}
+}
+
+// adjustFree adds d to the storage free
+// of a broker and the std. deviation terms.
+func (s *searchState) adjustFree(id int, d float64) {
+	v := s.value(id)
+	s.free[id] += d
+	nv := s.value(id)
+
+	s.sum += nv - v
+	s.sumSq += nv*nv - v*v
+}
+
+// undo reverts a searchMove.
+func (s *searchState) undo(m searchMove) {
+	s.apply(searchMove{partition: m.partition, position: m.position, from: m.to, to: m.from})
+}
+
+// propose returns a random relocation or swap of replicas
+// between two partitions. A nil value is returned if the
+// proposal violates any constraints.
+func (s *searchState) propose(r *rand.Rand) []searchMove {
+	if len(s.eligible) == 0 {
+		return nil
+	}
+
+	p := s.eligible[r.Intn(len(s.eligible))]
+	pos := r.Intn(len(s.partitions[p].Replicas))
+	from := s.partitions[p].Replicas[pos]
+
+	// Relocation.
+	if len(s.eligible) < 2 || r.Intn(2) == 0 {
+		m := searchMove{partition: p, position: pos, from: from, to: s.ids[r.Intn(len(s.ids))]}
+		if !s.allowed(m) || s.free[m.to]-s.sizes[p] < 0 {
+			return nil
+		}
+
+		return []searchMove{m}
+	}
+
+	// Swap.
+	q := s.eligible[r.Intn(len(s.eligible))]
+	qpos := r.Intn(len(s.partitions[q].Replicas))
+	to := s.partitions[q].Replicas[qpos]
+
+	m1 := searchMove{partition: p, position: pos, from: from, to: to}
+	m2 := searchMove{partition: q, position: qpos, from: to, to: from}
+
+	switch {
+	case p == q, !s.allowed(m1), !s.allowed(m2):
+		return nil
+	case s.free[from]+s.sizes[p]-s.sizes[q] < 0, s.free[to]+s.sizes[q]-s.sizes[p] < 0:
+		return nil
+	}
+
+	return []searchMove{m1, m2}
+}
+
+// searchRebalance runs a simulated annealing search over replica
+// relocations and swaps, minimizing the searchState cost. The search
+// is deterministic for a given seed under an iteration budget. The
+// lowest cost state found is returned as relocations, a relocationPlan
+// and the resulting BrokerMap.
+func searchRebalance(pm *kafkazk.PartitionMap, bm kafkazk.BrokerMap, pmm kafkazk.PartitionMetaMap, p searchParams) (map[int][]relocation, relocationPlan, kafkazk.BrokerMap, searchStats) {
+	s := newSearchState(pm, bm, pmm, p)
+	r := rand.New(rand.NewSource(p.seed))
+
+	cost := s.cost()
+	best, bestLen := cost, 0
+	t0 := cost * searchInitialTemperature
+
+	stats := searchStats{initialCost: cost}
+	start := time.Now()
+
+	for i := 0; p.iterations == 0 || i < p.iterations; i++ {
+		// Progress is the greater of the
+		// iteration and time budget used.
+		var progress float64
+		if p.iterations > 0 {
+			progress = float64(i) / float64(p.iterations)
+		}
+
+		if p.timeout > 0 {
+			elapsed := time.Since(start)
+			if elapsed >= p.timeout {
+				break
+			}
+
+			progress = math.Max(progress, float64(elapsed)/float64(p.timeout))
+		}
+
+		stats.iterations++
+		temp := t0 * (1 - progress)
+
+		moves := s.propose(r)
+		if moves == nil {
+			continue
+		}
+
+		for _, m := range moves {
+			s.apply(m)
+		}
+
+		c := s.cost()
+		delta := c - cost
+
+		// Reject worse states with a probability
+		// that increases as the search cools.
+		if delta > 0 && (temp <= 0 || r.Float64() >= math.Exp(-delta/temp)) {
+			for k := len(moves) - 1; k >= 0; k-- {
+				s.undo(moves[k])
+			}
+			continue
+		}
+
+		s.journal = append(s.journal, moves...)
+		stats.accepted++
+		cost = c
+
+		if cost < best {
+			best, bestLen = cost, len(s.journal)
+		}
+	}
+
+	// Roll back to the lowest cost state.
+	for len(s.journal) > bestLen {
+		s.undo(s.journal[len(s.journal)-1])
+		s.journal = s.journal[:len(s.journal)-1]
+	}
+
+	stats.finalCost = s.cost()
+
+	// Diff the original and final replica sets
+	// to get the relocations.
+	relos := map[int][]relocation{}
+	plan := relocationPlan{}
+	brokers := bm.Copy()
+
+	for i, partn := range pm.Partitions {
+		final := map[int]bool{}
+		for _, id := range s.partitions[i].Replicas {
+			final[id] = true
+		}
+
+		var removed, added []int
+		for _, id := range partn.Replicas {
+			if !final[id] {
+				removed = append(removed, id)
+			}
+		}
+
+		for _, id := range s.partitions[i].Replicas {
+			if !s.original[i][id] {
+				added = append(added, id)
+			}
+		}
+
+		size, _ := pmm.Size(partn)
+
+		for j := range removed {
+			relos[removed[j]] = append(relos[removed[j]], relocation{partition: partn, destination: added[j]})
+			plan.add(partn, [2]int{removed[j], added[j]})
+			brokers[removed[j]].StorageFree += size
+			brokers[added[j]].StorageFree -= size
+		}
+	}
+
+	return relos, plan, brokers, stats
+}
+
+// getSearchParams returns searchParams from the rebalance flags.
+func getSearchParams(cmd *cobra.Command, policies kafkazk.PlacementPolicies, mc moveCost) searchParams {
+	iterations, _ := cmd.Flags().GetInt("search-iterations")
+	timeout, _ := cmd.Flags().GetDuration("search-timeout")
+	seed, _ := cmd.Flags().GetInt64("search-seed")
+	moveWeight, _ := cmd.Flags().GetFloat64("search-move-weight")
+	pst, _ := cmd.Flags().GetInt("partition-size-threshold")
+	localityScoped, _ := cmd.Flags().GetBool("locality-scoped")
+
+	return searchParams{
+		iterations:             iterations,
+		timeout:                timeout,
+		seed:                   seed,
+		moveWeight:             moveWeight,
+		partitionSizeThreshold: float64(pst * 1 << 20),
+		localityScoped:         localityScoped,
+		policies:               policies,
+		moveCost:               mc,
+	}
+}
+
+func printSearchParams(cmd *cobra.Command, p searchParams, stats searchStats, brokers kafkazk.BrokerMap) {
+	fmt.Println("\nRebalance parameters:")
+
+	pst, _ := cmd.Flags().GetInt("partition-size-threshold")
+
+	fmt.Printf("%sIgnoring partitions smaller than %dMB\n", indent, pst)
+	printStorageMeans(brokers)
+	if p.moveCost != nil {
+		fmt.Printf("%sMove cost: %s\n", indent, p.moveCost)
+	}
+	fmt.Printf("%sSearch optimizer (seed %d, move weight %.2f):\n", indent, p.seed, p.moveWeight)
+	fmt.Printf("%s%s%d iterations, %d moves accepted\n", indent, indent, stats.iterations, stats.accepted)
+	fmt.Printf("%s%sCost: %.2f -> %.2f\n", indent, indent, stats.initialCost, stats.finalCost)
+}
@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func testSearchInput() (*kafkazk.PartitionMap, kafkazk.BrokerMap, kafkazk.PartitionMetaMap) {
+	pm, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":1,"replicas":[1002,1001]},
+		{"topic":"test_topic","partition":2,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":3,"replicas":[1002,1001]},
+		{"topic":"test_topic","partition":4,"replicas":[1003,1004]},
+		{"topic":"test_topic","partition":5,"replicas":[1004,1003]}]}`)
+
+	pmm := kafkazk.NewPartitionMetaMap()
+	pmm["test_topic"] = map[int]*kafkazk.PartitionMeta{}
+	for i, s := range []float64{100, 200, 300, 400, 50, 50} {
+		pmm["test_topic"][i] = &kafkazk.PartitionMeta{Size: s * div}
+	}
+
+	bm := kafkazk.BrokerMapFromPartitionMap(pm, kafkazk.BrokerMetaMap{}, false)
+	for id, l := range map[int]string{1001: "a", 1002: "b", 1003: "a", 1004: "b"} {
+		bm[id].Locality = l
+	}
+
+	// 2000GB capacity less the replicas held.
+	bm[1001].StorageFree = 1000 * div
+	bm[1002].StorageFree = 1000 * div
+	bm[1003].StorageFree = 1900 * div
+	bm[1004].StorageFree = 1900 * div
+
+	return pm, bm, pmm
+}
+
+func TestSearchRebalance(t *testing.T) {
+	pm, bm, pmm := testSearchInput()
+
+	params := searchParams{
+		iterations: 5000,
+		seed:       1,
+		moveWeight: 0.10,
+	}
+
+	relos, plan, brokers, stats := searchRebalance(pm, bm, pmm, params)
+
+	if stats.finalCost >= stats.initialCost {
+		t.Errorf("Expected cost below %.2f, got %.2f", stats.initialCost, stats.finalCost)
+	}
+
+	if brokers.StorageStdDev() >= bm.StorageStdDev() {
+		t.Errorf("Expected std. deviation below %.2f, got %.2f",
+			bm.StorageStdDev()/div, brokers.StorageStdDev()/div)
+	}
+
+	// Total storage free is unchanged.
+	var before, after float64
+	for id := range bm {
+		before += bm[id].StorageFree
+		after += brokers[id].StorageFree
+	}
+
+	if before != after {
+		t.Errorf("Expected total storage free %.2f, got %.2f", before/div, after/div)
+	}
+
+	pmOut := pm.Copy()
+	applyRelocationPlan(rebalanceCmd, pmOut, plan)
+
+	// Rack IDs must remain unique and all
+	// relocations must be reflected in the map.
+	var planned int
+	for _, r := range relos {
+		planned += len(r)
+	}
+
+	var changed int
+	for i, partn := range pmOut.Partitions {
+		if bm[partn.Replicas[0]].Locality == bm[partn.Replicas[1]].Locality {
+			t.Errorf("Expected unique rack IDs for %s p%d: %v",
+				partn.Topic, partn.Partition, partn.Replicas)
+		}
+
+		for _, id := range partn.Replicas {
+			var exist bool
+			for _, orig := range pm.Partitions[i].Replicas {
+				if id == orig {
+					exist = true
+				}
+			}
+
+			if !exist {
+				changed++
+			}
+		}
+	}
+
+	if planned == 0 || planned != changed {
+		t.Errorf("Expected %d planned relocations, got %d", changed, planned)
+	}
+
+	// The same seed yields the same plan.
+	_, plan2, _, _ := searchRebalance(pm, bm, pmm, params)
+	if !reflect.DeepEqual(plan, plan2) {
+		t.Errorf("Expected identical plans for the same seed")
+	}
+}
+
+func TestSearchRebalanceConstraints(t *testing.T) {
+	pm, bm, pmm := testSearchInput()
+
+	// A large move weight permits no moves.
+	params := searchParams{iterations: 2000, seed: 1, moveWeight: 1000}
+
+	relos, _, _, stats := searchRebalance(pm, bm, pmm, params)
+	if len(relos) != 0 {
+		t.Errorf("Expected no relocations, got %v", relos)
+	}
+
+	if stats.finalCost != stats.initialCost {
+		t.Errorf("Expected cost %.2f, got %.2f", stats.initialCost, stats.finalCost)
+	}
+
+	// Exclude broker 1003 by policy; partitions may
+	// only move to 1004 in locality-scoped mode.
+	params = searchParams{
+		iterations:     2000,
+		seed:           1,
+		localityScoped: true,
+		policies: kafkazk.PlacementPolicies{
+			"test_topic": &kafkazk.PlacementPolicy{ExcludedBrokers: map[int]bool{1003: true}},
+		},
+	}
+
+	relos, _, _, _ = searchRebalance(pm, bm, pmm, params)
+	for id, r := range relos {
+		for _, relo := range r {
+			if relo.destination == 1003 {
+				t.Errorf("Unexpected relocation to excluded broker 1003")
+			}
+
+			if bm[id].Locality != bm[relo.destination].Locality {
+				t.Errorf("Unexpected relocation from %d to %d across rack IDs", id, relo.destination)
+			}
+		}
+	}
+
+	if len(relos[1002]) == 0 {
+		t.Errorf("Expected relocations from broker 1002")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"math"
 	"os"
 	"sort"
+	"sync"
 
 	"github.com/DataDog/kafka-kit/kafkazk"
 
@@ -16,6 +17,9 @@ import (
 type offloadTargetsBySize struct {
 	t  []int
 	bm kafkazk.BrokerMap
+	// Sort by storage used percentage
+	// rather than storage free.
+	byUsed bool
 }
 
 // We work with storage free, so a sort by utilization
@@ -26,6 +30,11 @@ func (o offloadTargetsBySize) Less(i, j int) bool {
 	s1 := o.bm[o.t[i]].StorageFree
 	s2 := o.bm[o.t[j]].StorageFree
 
+	if o.byUsed {
+		s1 = -o.bm[o.t[i]].StorageUsedPercent()
+		s2 = -o.bm[o.t[j]].StorageUsedPercent()
+	}
+
 	if s1 < s2 {
 		return true
 	}
@@ -37,14 +46,108 @@ func (o offloadTargetsBySize) Less(i, j int) bool {
 	return o.t[i] < o.t[j]
 }
 
+// storageLimits holds the storage bounds that relocation sources
+// and destinations are limited to. If the StorageTotal is known for
+// all brokers, the limits are storage used percentages within the
+// tolerance of the StorageUsedMean, balancing brokers of differing
+// capacities by utilization. Otherwise, the limits are in bytes of
+// storage free within the tolerance of the mean storage free.
+type storageLimits struct {
+	byUsed bool
+	source float64
+	dest   float64
+}
+
+func newStorageLimits(brokers kafkazk.BrokerMap, tolerance float64) storageLimits {
+	if brokers.HasStorageTotal() {
+		m := brokers.StorageUsedMean()
+		return storageLimits{byUsed: true, source: m * (1 - tolerance), dest: m * (1 + tolerance)}
+	}
+
+	m := brokers.Mean()
+	return storageLimits{source: m * (1 + tolerance), dest: m * (1 - tolerance)}
+}
+
+// value returns the storage value of the broker given the
+// storage free f in the units of the storageLimits.
+func (l storageLimits) value(b *kafkazk.Broker, f float64) float64 {
+	if l.byUsed {
+		c := b.Copy()
+		c.StorageFree = f
+		return c.StorageUsedPercent()
+	}
+
+	return f
+}
+
+// sourceAllows returns whether the source broker
+// may be left with the storage free f.
+func (l storageLimits) sourceAllows(b *kafkazk.Broker, f float64) bool {
+	if l.byUsed {
+		return l.value(b, f) >= l.source
+	}
+
+	return f <= l.source
+}
+
+// destAllows returns whether the destination
+// broker may be left with the storage free f.
+func (l storageLimits) destAllows(b *kafkazk.Broker, f float64) bool {
+	if l.byUsed {
+		return l.value(b, f) <= l.dest
+	}
+
+	return f >= l.dest
+}
+
+// sort sorts a BrokerList by the most preferred destination.
+func (l storageLimits) sort(bl kafkazk.BrokerList) {
+	if l.byUsed {
+		bl.SortByStorageUsed()
+		return
+	}
+
+	bl.SortByStorage()
+}
+
+// selection returns the BestCandidate selection method.
+func (l storageLimits) selection() string {
+	if l.byUsed {
+		return "storage_used"
+	}
+
+	return "storage"
+}
+
+// format returns a string of the storage value v
+// in the units of the storageLimits.
+func (l storageLimits) format(v float64) string {
+	if l.byUsed {
+		return fmt.Sprintf("%.2f%% used", v)
+	}
+
+	return fmt.Sprintf("%.2fGB free", v/div)
+}
+
 type relocation struct {
 	partition   kafkazk.Partition
 	destination int
 }
 
+// swap describes a pairwise exchange of replicas where
+// the partition moves from a source broker to the peer
+// broker and the peerPartition moves from the peer
+// broker to the source broker.
+type swap struct {
+	partition     kafkazk.Partition
+	peer          int
+	peerPartition kafkazk.Partition
+}
+
 type planRelocationsForBrokerParams struct {
 	sourceID               int
 	relos                  map[int][]relocation
+	swaps                  map[int][]swap
 	mappings               kafkazk.Mappings
 	brokers                kafkazk.BrokerMap
 	partitionMeta          kafkazk.PartitionMetaMap
@@ -54,6 +157,8 @@ type planRelocationsForBrokerParams struct {
 	partitionSizeThreshold int
 	offloadTargetsMap      map[int]struct{}
 	tolerance              float64
+	policies               kafkazk.PlacementPolicies
+	moveCost               moveCost
 }
 
 // relocationPlan is a mapping of topic,
@@ -110,6 +215,10 @@ func validateBrokersForRebalance(cmd *cobra.Command, brokers kafkazk.BrokerMap,
 	// missing/partial metrics data.
 	ensureBrokerMetrics(cmd, brokers, bm)
 
+	// Check that storage capacities are either
+	// known for all brokers or for none.
+	ensureStorageTotals(brokers)
+
 	switch {
 	case c.Missing > 0, c.OldMissing > 0, c.Replace > 0:
 		fmt.Printf("%s[ERROR] rebalance only allows broker additions\n", indent)
@@ -153,6 +262,26 @@ func validateBrokersForRebalance(cmd *cobra.Command, brokers kafkazk.BrokerMap,
 		}
 
 		sort.Ints(offloadTargets)
+	case brokers.HasStorageTotal():
+		selectorMethod.WriteString(fmt.Sprintf("(>= %.2f%% threshold above storage used mean)", st*100))
+
+		// Find brokers where the storage used percentage
+		// is t % above the storage used percentage of the
+		// combined capacity. Specifying 0 targets all
+		// non-new brokers.
+		switch st {
+		case 0.00:
+			f := func(b *kafkazk.Broker) bool { return !b.New }
+
+			matches := brokers.Filter(f)
+			for _, b := range matches {
+				offloadTargets = append(offloadTargets, b.ID)
+			}
+
+			sort.Ints(offloadTargets)
+		default:
+			offloadTargets = brokers.AboveStorageUsedMean(st)
+		}
 	default:
 		selectorMethod.WriteString(fmt.Sprintf("(>= %.2f%% threshold below hmean)", st*100))
 
@@ -189,24 +318,34 @@ func validateBrokersForRebalance(cmd *cobra.Command, brokers kafkazk.BrokerMap,
 	return offloadTargets
 }
 
-func printRebalanceParams(cmd *cobra.Command, results []rebalanceResults, brokers kafkazk.BrokerMap, tol float64) {
+func printRebalanceParams(cmd *cobra.Command, results []rebalanceResults, brokers kafkazk.BrokerMap, tol float64, mc moveCost) {
 	// Print rebalance parameters as a result of
 	// input configurations and brokers found
 	// to be beyond the storage threshold.
 	fmt.Println("\nRebalance parameters:")
 
 	pst, _ := cmd.Flags().GetInt("partition-size-threshold")
-	mean, hMean := brokers.Mean(), brokers.HMean()
+	limits := newStorageLimits(brokers, tol)
 
 	fmt.Printf("%sIgnoring partitions smaller than %dMB\n", indent, pst)
-	fmt.Printf("%sFree storage mean, harmonic mean: %.2fGB, %.2fGB\n",
-		indent, mean/div, hMean/div)
+	printStorageMeans(brokers)
 
-	fmt.Printf("%sBroker free storage limits (with a %.2f%% tolerance from mean):\n",
-		indent, tol*100)
+	if mc != nil {
+		fmt.Printf("%sRanking relocations by move cost per GB: %s\n", indent, mc)
+	}
 
-	fmt.Printf("%s%sSources limited to <= %.2fGB\n", indent, indent, mean*(1+tol)/div)
-	fmt.Printf("%s%sDestinations limited to >= %.2fGB\n", indent, indent, mean*(1-tol)/div)
+	switch limits.byUsed {
+	case true:
+		fmt.Printf("%sBroker storage used limits (with a %.2f%% tolerance from mean):\n",
+			indent, tol*100)
+		fmt.Printf("%s%sSources limited to >= %.2f%%\n", indent, indent, limits.source)
+		fmt.Printf("%s%sDestinations limited to <= %.2f%%\n", indent, indent, limits.dest)
+	case false:
+		fmt.Printf("%sBroker free storage limits (with a %.2f%% tolerance from mean):\n",
+			indent, tol*100)
+		fmt.Printf("%s%sSources limited to <= %.2fGB\n", indent, indent, limits.source/div)
+		fmt.Printf("%s%sDestinations limited to >= %.2fGB\n", indent, indent, limits.dest/div)
+	}
 
 	verbose, _ := cmd.Flags().GetBool("verbose")
 
@@ -215,8 +354,14 @@ func printRebalanceParams(cmd *cobra.Command, results []rebalanceResults, broker
 	if verbose {
 		fmt.Printf("%s-\n%sTop 10 rebalance map results\n", indent, indent)
 		for i, r := range results {
-			fmt.Printf("%stolerance: %.2f -> range: %.2fGB, std. deviation: %.2fGB\n",
-				indent, r.tolerance, r.storageRange/div, r.stdDev/div)
+			switch limits.byUsed {
+			case true:
+				fmt.Printf("%stolerance: %.2f -> range: %.2f%%, std. deviation: %.2f%%\n",
+					indent, r.tolerance, r.storageRange, r.stdDev)
+			case false:
+				fmt.Printf("%stolerance: %.2f -> range: %.2fGB, std. deviation: %.2fGB\n",
+					indent, r.tolerance, r.storageRange/div, r.stdDev/div)
+			}
 			if i == 10 {
 				break
 			}
@@ -224,6 +369,183 @@ func printRebalanceParams(cmd *cobra.Command, results []rebalanceResults, broker
 	}
 }
 
+// printStorageMeans prints the storage free mean and harmonic mean, along
+// with the storage used mean if the StorageTotal is known for all brokers.
+func printStorageMeans(brokers kafkazk.BrokerMap) {
+	mean, hMean := brokers.Mean(), brokers.HMean()
+
+	fmt.Printf("%sFree storage mean, harmonic mean: %.2fGB, %.2fGB\n",
+		indent, mean/div, hMean/div)
+
+	if brokers.HasStorageTotal() {
+		fmt.Printf("%sStorage used mean (of total capacity): %.2f%%\n",
+			indent, brokers.StorageUsedMean())
+	}
+}
+
+// ensureStorageTotals exits if the StorageTotal is known for
+// some but not all brokers in the BrokerMap. Balancing storage
+// used percentages requires the capacity of every broker.
+func ensureStorageTotals(bm kafkazk.BrokerMap) {
+	var unknown []int
+	var known int
+
+	for id, b := range bm {
+		switch {
+		case id == kafkazk.StubBrokerID, b.Missing:
+			continue
+		case b.StorageTotal <= 0:
+			unknown = append(unknown, id)
+		default:
+			known++
+		}
+	}
+
+	if known == 0 || len(unknown) == 0 {
+		return
+	}
+
+	sort.Ints(unknown)
+
+	for _, id := range unknown {
+		fmt.Printf("Storage capacity not found for broker %d\n", id)
+	}
+
+	os.Exit(1)
+}
+
+// greedyRebalance computes a rebalanceResults for all tolerance values
+// 0.01..0.99 (or the fixed --tolerance value) using planRelocationsForBroker
+// passes. The results are returned sorted by storage range ascending.
+func greedyRebalance(cmd *cobra.Command, partitionMapIn *kafkazk.PartitionMap, brokersIn kafkazk.BrokerMap, partitionMeta kafkazk.PartitionMetaMap, offloadTargets []int, placementPolicies kafkazk.PlacementPolicies, mc moveCost) []rebalanceResults {
+	partitionLimit, _ := cmd.Flags().GetInt("partition-limit")
+	swapsEnabled, _ := cmd.Flags().GetBool("swaps")
+	partitionSizeThreshold, _ := cmd.Flags().GetInt("partition-size-threshold")
+
+	otm := map[int]struct{}{}
+	for _, id := range offloadTargets {
+		otm[id] = struct{}{}
+	}
+
+	results := make(chan rebalanceResults, 100)
+	wg := &sync.WaitGroup{}
+
+	// Compute a rebalanceResults output for all tolerance
+	// values 0.01..0.99 in parallel.
+	for i := 0.01; i < 0.99; i += 0.01 {
+		// Whether we're using a fixed tolerance
+		// (non 0.00) set via flag or an iterative value.
+		tolFlag, _ := cmd.Flags().GetFloat64("tolerance")
+		var tol float64
+
+		if tolFlag == 0.00 {
+			tol = i
+		} else {
+			tol = tolFlag
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			partitionMap := partitionMapIn.Copy()
+
+			// A nil swaps map disables swaps.
+			var swaps map[int][]swap
+			if swapsEnabled {
+				swaps = map[int][]swap{}
+			}
+
+			// Bundle planRelocationsForBrokerParams.
+			params := planRelocationsForBrokerParams{
+				relos:                  map[int][]relocation{},
+				swaps:                  swaps,
+				mappings:               partitionMap.Mappings(),
+				brokers:                brokersIn.Copy(),
+				partitionMeta:          partitionMeta,
+				plan:                   relocationPlan{},
+				topPartitionsLimit:     partitionLimit,
+				partitionSizeThreshold: partitionSizeThreshold,
+				offloadTargetsMap:      otm,
+				tolerance:              tol,
+				policies:               placementPolicies,
+				moveCost:               mc,
+			}
+
+			// Iterate over offload targets, planning
+			// at most one relocation per iteration.
+			// Continue this loop until no more relocations
+			// can be planned.
+			for exhaustedCount := 0; exhaustedCount < len(offloadTargets); {
+				params.pass++
+				for _, sourceID := range offloadTargets {
+					// Update the source broker ID
+					params.sourceID = sourceID
+
+					relos := planRelocationsForBroker(cmd, params)
+
+					// If no relocations could be planned,
+					// increment the exhaustion counter.
+					if relos == 0 {
+						exhaustedCount++
+					}
+				}
+			}
+
+			// Update the partition map with the relocation plan.
+			applyRelocationPlan(cmd, partitionMap, params.plan)
+
+			// Ranges are storage used percentages
+			// if balancing by broker capacity.
+			storageRange, stdDev := params.brokers.StorageRange(), params.brokers.StorageStdDev()
+			if params.brokers.HasStorageTotal() {
+				storageRange, stdDev = params.brokers.StorageRangeSpread(), params.brokers.StorageUsedStdDev()
+			}
+
+			// Insert the rebalanceResults.
+			results <- rebalanceResults{
+				storageRange: storageRange,
+				stdDev:       stdDev,
+				tolerance:    tol,
+				partitionMap: partitionMap,
+				relocations:  params.relos,
+				swaps:        params.swaps,
+				brokers:      params.brokers,
+			}
+
+		}()
+
+		// Break early if we're using a fixed tolerance value.
+		if tolFlag != 0.00 {
+			break
+		}
+	}
+
+	wg.Wait()
+	close(results)
+
+	// Merge all results into a slice.
+	resultsByRange := []rebalanceResults{}
+	for r := range results {
+		resultsByRange = append(resultsByRange, r)
+	}
+
+	// Sort the rebalance results by range ascending.
+	sort.Slice(resultsByRange, func(i, j int) bool {
+		switch {
+		case resultsByRange[i].storageRange < resultsByRange[j].storageRange:
+			return true
+		case resultsByRange[i].storageRange > resultsByRange[j].storageRange:
+			return false
+		}
+
+		return resultsByRange[i].stdDev < resultsByRange[j].stdDev
+	})
+
+	return resultsByRange
+}
+
 func planRelocationsForBroker(cmd *cobra.Command, params planRelocationsForBrokerParams) int {
 	verbose, _ := cmd.Flags().GetBool("verbose")
 	localityScoped, _ := cmd.Flags().GetBool("locality-scoped")
@@ -239,12 +561,22 @@ func planRelocationsForBroker(cmd *cobra.Command, params planRelocationsForBroke
 	offloadTargetsMap := params.offloadTargetsMap
 	tolerance := params.tolerance
 
-	// Use the arithmetic mean for target
-	// thresholds.
-	meanStorageFree := brokers.Mean()
+	// Use the arithmetic mean (or storage used
+	// mean) for target thresholds.
+	limits := newStorageLimits(brokers, tolerance)
+
+	// Get the top partitions for the target broker. With a move
+	// cost, all partitions are fetched and the limit is applied
+	// once ranked by cost.
+	limit := topPartitionsLimit
+	if params.moveCost != nil {
+		limit = 0
+		for _, pl := range mappings[sourceID] {
+			limit += len(pl)
+		}
+	}
 
-	// Get the top partitions for the target broker.
-	topPartn, _ := mappings.LargestPartitions(sourceID, topPartitionsLimit, partitionMeta)
+	topPartn, _ := mappings.LargestPartitions(sourceID, limit, partitionMeta)
 
 	// Filter out partitions below the targeted size threshold.
 	for i, p := range topPartn {
@@ -255,12 +587,32 @@ func planRelocationsForBroker(cmd *cobra.Command, params planRelocationsForBroke
 		}
 	}
 
+	// Rank partitions by move cost per GB offloaded. Partitions
+	// of an equal cost per GB are kept in size order.
+	if params.moveCost != nil {
+		sort.SliceStable(topPartn, func(i, j int) bool {
+			si, _ := partitionMeta.Size(topPartn[i])
+			sj, _ := partitionMeta.Size(topPartn[j])
+			return costPerGB(params.moveCost, topPartn[i], sourceID, si) < costPerGB(params.moveCost, topPartn[j], sourceID, sj)
+		})
+
+		if len(topPartn) > topPartitionsLimit {
+			topPartn = topPartn[:topPartitionsLimit]
+		}
+	}
+
 	if verbose {
-		fmt.Printf("\n[pass %d with tolerance %.2f] Broker %d has a storage free of %.2fGB. Top partitions:\n",
-			params.pass, tolerance, sourceID, brokers[sourceID].StorageFree/div)
+		source := brokers[sourceID]
+		fmt.Printf("\n[pass %d with tolerance %.2f] Broker %d has a storage of %s. Top partitions:\n",
+			params.pass, tolerance, sourceID, limits.format(limits.value(source, source.StorageFree)))
 
 		for _, p := range topPartn {
 			pSize, _ := partitionMeta.Size(p)
+			if params.moveCost != nil {
+				fmt.Printf("%s%s p%d: %.2fGB (cost %.2f/GB)\n",
+					indent, p.Topic, p.Partition, pSize/div, costPerGB(params.moveCost, p, sourceID, pSize))
+				continue
+			}
 			fmt.Printf("%s%s p%d: %.2fGB\n",
 				indent, p.Topic, p.Partition, pSize/div)
 		}
@@ -273,9 +625,13 @@ func planRelocationsForBroker(cmd *cobra.Command, params planRelocationsForBroke
 	// not retried the next iteration.
 	var reloCount int
 	for _, partn := range topPartn {
-		// Get a storage sorted brokerList.
-		brokerList := brokers.List()
-		brokerList.SortByStorage()
+		// Get a storage sorted brokerList of
+		// brokers allowed by any topic policy.
+		policy := params.policies.Get(partn.Topic)
+		brokerList := brokers.List().Filter(func(b *kafkazk.Broker) bool {
+			return policy.Allows(b.ID)
+		})
+		limits.sort(brokerList)
 
 		pSize, _ := partitionMeta.Size(partn)
 
@@ -330,7 +686,7 @@ func planRelocationsForBroker(cmd *cobra.Command, params planRelocationsForBroke
 			}
 
 			// Select the best candidate by storage.
-			dest, _ = brokerList.BestCandidate(c, "storage", 0)
+			dest, _ = brokerList.BestCandidate(c, limits.selection(), 0)
 		}
 
 		// If dest == nil, it's likely that the only available
@@ -344,8 +700,8 @@ func planRelocationsForBroker(cmd *cobra.Command, params planRelocationsForBroke
 		if verbose {
 			fmt.Printf("%s-\n", indent)
 			fmt.Printf("%sAttempting migration plan for %s p%d\n", indent, partn.Topic, partn.Partition)
-			fmt.Printf("%sCandidate destination broker %d has a storage free of %.2fGB\n",
-				indent, dest.ID, dest.StorageFree/div)
+			fmt.Printf("%sCandidate destination broker %d has a storage of %s\n",
+				indent, dest.ID, limits.format(limits.value(dest, dest.StorageFree)))
 		}
 
 		sourceFree := brokers[sourceID].StorageFree + pSize
@@ -355,23 +711,21 @@ func planRelocationsForBroker(cmd *cobra.Command, params planRelocationsForBroke
 		// target or destination beyond the threshold distance
 		// from the mean, try the next partition.
 
-		sLim := meanStorageFree * (1 + tolerance)
-		if sourceFree > sLim {
+		if !limits.sourceAllows(brokers[sourceID], sourceFree) {
 			if verbose {
 				fmt.Printf("%sCannot move partition from target: "+
-					"expected storage free %.2fGB above tolerated threshold of %.2fGB\n",
-					indent, sourceFree/div, sLim/div)
+					"expected storage of %s beyond tolerated threshold of %s\n",
+					indent, limits.format(limits.value(brokers[sourceID], sourceFree)), limits.format(limits.source))
 			}
 
 			continue
 		}
 
-		dLim := meanStorageFree * (1 - tolerance)
-		if destFree < dLim {
+		if !limits.destAllows(dest, destFree) {
 			if verbose {
 				fmt.Printf("%sCannot move partition to candidate: "+
-					"expected storage free %.2fGB below tolerated threshold of %.2fGB\n",
-					indent, destFree/div, dLim/div)
+					"expected storage of %s beyond tolerated threshold of %s\n",
+					indent, limits.format(limits.value(dest, destFree)), limits.format(limits.dest))
 			}
 
 			continue
@@ -401,6 +755,12 @@ func planRelocationsForBroker(cmd *cobra.Command, params planRelocationsForBroke
 		break
 	}
 
+	// If no one-way relocation fits,
+	// attempt a pairwise swap.
+	if reloCount == 0 && params.swaps != nil {
+		reloCount = planSwapForBroker(cmd, params, topPartn)
+	}
+
 	if verbose && reloCount == 0 {
 		fmt.Printf("%s-\n", indent)
 		fmt.Printf("%sNo suitable relocation destinations were found for any partitions "+
@@ -412,6 +772,214 @@ func planRelocationsForBroker(cmd *cobra.Command, params planRelocationsForBroke
 	return reloCount
 }
 
+// planSwapForBroker attempts to plan a swap of a partition held by the
+// source broker with a smaller partition held by a less utilized peer
+// broker. This allows a source to offload storage when no destination
+// can take a partition outright. If a moveCost is configured, the swap
+// with the lowest move cost per GB offloaded is planned; otherwise the
+// first swap found. The number of swaps planned (at most 1) is returned.
+func planSwapForBroker(cmd *cobra.Command, params planRelocationsForBrokerParams, topPartn kafkazk.PartitionList) int {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	localityScoped, _ := cmd.Flags().GetBool("locality-scoped")
+
+	brokers := params.brokers
+	partitionMeta := params.partitionMeta
+	plan := params.plan
+	source := brokers[params.sourceID]
+	partitionSizeThreshold := float64(params.partitionSizeThreshold * 1 << 20)
+
+	limits := newStorageLimits(brokers, params.tolerance)
+
+	// Get a storage sorted list of peers,
+	// excluding offload targets.
+	peers := brokers.List().Filter(func(b *kafkazk.Broker) bool {
+		_, t := params.offloadTargetsMap[b.ID]
+		return !t && b.ID != source.ID && b.ID != kafkazk.StubBrokerID
+	})
+	limits.sort(peers)
+
+	var best *swap
+	var bestPeer *kafkazk.Broker
+	var bestDelta, bestCost float64
+
+	for _, partn := range topPartn {
+		if _, planned := plan.isPlanned(partn); planned {
+			continue
+		}
+
+		pSize, _ := partitionMeta.Size(partn)
+
+		for _, peer := range peers {
+			if !replicaMoveAllowed(partn, source, peer, brokers, localityScoped, params.policies) {
+				continue
+			}
+
+			peerPartns, _ := params.mappings.LargestPartitions(peer.ID, math.MaxInt32, partitionMeta)
+
+			// Try the smallest peer partitions first; these
+			// offload the most storage from the source.
+			for i := len(peerPartns) - 1; i >= 0; i-- {
+				peerPartn := peerPartns[i]
+				qSize, _ := partitionMeta.Size(peerPartn)
+
+				if qSize >= pSize {
+					break
+				}
+
+				if qSize < partitionSizeThreshold {
+					continue
+				}
+
+				if _, planned := plan.isPlanned(peerPartn); planned {
+					continue
+				}
+
+				if !replicaMoveAllowed(peerPartn, peer, source, brokers, localityScoped, params.policies) {
+					continue
+				}
+
+				// The swap must keep both brokers
+				// within the tolerated thresholds.
+				delta := pSize - qSize
+				if !limits.sourceAllows(source, source.StorageFree+delta) || !limits.destAllows(peer, peer.StorageFree-delta) {
+					continue
+				}
+
+				s := &swap{partition: partn, peer: peer.ID, peerPartition: peerPartn}
+
+				if params.moveCost == nil {
+					best, bestPeer, bestDelta = s, peer, delta
+					break
+				}
+
+				// The cost of both replica moves
+				// per GB offloaded from the source.
+				cost := (params.moveCost.cost(partn, partn.Replicas[0] == source.ID) +
+					params.moveCost.cost(peerPartn, peerPartn.Replicas[0] == peer.ID)) / (delta / div)
+
+				if best == nil || cost < bestCost {
+					best, bestPeer, bestDelta, bestCost = s, peer, delta, cost
+				}
+			}
+
+			if best != nil && params.moveCost == nil {
+				break
+			}
+		}
+
+		if best != nil && params.moveCost == nil {
+			break
+		}
+	}
+
+	if best == nil {
+		return 0
+	}
+
+	params.swaps[source.ID] = append(params.swaps[source.ID], *best)
+
+	plan.add(best.partition, [2]int{source.ID, bestPeer.ID})
+	plan.add(best.peerPartition, [2]int{bestPeer.ID, source.ID})
+
+	source.StorageFree += bestDelta
+	bestPeer.StorageFree -= bestDelta
+
+	params.mappings.Remove(source.ID, best.partition)
+	params.mappings.Remove(bestPeer.ID, best.peerPartition)
+
+	if verbose {
+		fmt.Printf("%s-\n", indent)
+		fmt.Printf("%sPlanning swap of %s p%d with %s p%d on broker %d\n",
+			indent, best.partition.Topic, best.partition.Partition,
+			best.peerPartition.Topic, best.peerPartition.Partition, bestPeer.ID)
+	}
+
+	return 1
+}
+
+// replicaMoveAllowed returns whether moving a replica of the partition
+// from one broker to another satisfies the replica set, placement policy
+// and rack ID constraints.
+func replicaMoveAllowed(partn kafkazk.Partition, from, to *kafkazk.Broker, brokers kafkazk.BrokerMap, localityScoped bool, policies kafkazk.PlacementPolicies) bool {
+	if !policies.Get(partn.Topic).Allows(to.ID) {
+		return false
+	}
+
+	if localityScoped && to.Locality != from.Locality {
+		return false
+	}
+
+	for _, id := range partn.Replicas {
+		if id == from.ID {
+			continue
+		}
+
+		if id == to.ID {
+			return false
+		}
+
+		if b, exist := brokers[id]; exist && to.Locality != "" && b.Locality == to.Locality {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pairSwaps takes relocations keyed by source broker ID and returns the
+// relocations that aren't part of a pairwise exchange, along with the
+// exchanges as swaps.
+func pairSwaps(relos map[int][]relocation) (map[int][]relocation, map[int][]swap) {
+	ids := []int{}
+	for id := range relos {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	paired := map[int]map[int]bool{}
+	for _, id := range ids {
+		paired[id] = map[int]bool{}
+	}
+
+	oneWay := map[int][]relocation{}
+	swaps := map[int][]swap{}
+
+	for _, id := range ids {
+		for i, r := range relos[id] {
+			if paired[id][i] {
+				continue
+			}
+
+			// Find an unpaired relocation from the
+			// destination back to this broker.
+			match := -1
+			for j, r2 := range relos[r.destination] {
+				if r2.destination == id && !paired[r.destination][j] {
+					match = j
+					break
+				}
+			}
+
+			if match < 0 {
+				oneWay[id] = append(oneWay[id], r)
+				continue
+			}
+
+			paired[id][i] = true
+			paired[r.destination][match] = true
+
+			swaps[id] = append(swaps[id], swap{
+				partition:     r.partition,
+				peer:          r.destination,
+				peerPartition: relos[r.destination][match].partition,
+			})
+		}
+	}
+
+	return oneWay, swaps
+}
+
 func applyRelocationPlan(cmd *cobra.Command, pm *kafkazk.PartitionMap, plan relocationPlan) {
 	// Traverse the partition list.
 	for _, partn := range pm.Partitions {
@@ -435,15 +1003,14 @@ func applyRelocationPlan(cmd *cobra.Command, pm *kafkazk.PartitionMap, plan relo
 	}
 }
 
-func printPlannedRelocations(targets []int, relos map[int][]relocation, pmm kafkazk.PartitionMetaMap) {
-	var total float64
+func printPlannedRelocations(targets []int, relos map[int][]relocation, swaps map[int][]swap, pmm kafkazk.PartitionMetaMap) {
+	var total, swapTotal float64
 
 	for _, id := range targets {
 		fmt.Printf("\nBroker %d relocations planned:\n", id)
 
 		if _, exist := relos[id]; !exist {
 			fmt.Printf("%s[none]\n", indent)
-			continue
 		}
 
 		for _, r := range relos[id] {
@@ -452,9 +1019,38 @@ func printPlannedRelocations(targets []int, relos map[int][]relocation, pmm kafk
 			fmt.Printf("%s[%.2fGB] %s p%d -> %d\n",
 				indent, pSize/div, r.partition.Topic, r.partition.Partition, r.destination)
 		}
+
+		if _, exist := swaps[id]; !exist {
+			continue
+		}
+
+		fmt.Printf("\nBroker %d swaps planned:\n", id)
+
+		for _, s := range swaps[id] {
+			pSize, _ := pmm.Size(s.partition)
+			qSize, _ := pmm.Size(s.peerPartition)
+			swapTotal += (pSize + qSize) / div
+			fmt.Printf("%s[%.2fGB] %s p%d <-> [%.2fGB] %s p%d on %d\n",
+				indent, pSize/div, s.partition.Topic, s.partition.Partition,
+				qSize/div, s.peerPartition.Topic, s.peerPartition.Partition, s.peer)
+		}
 	}
 	fmt.Printf("%s-\n", indent)
 	fmt.Printf("%sTotal relocation volume: %.2fGB\n", indent, total)
+
+	if swapTotal > 0 {
+		fmt.Printf("%sTotal swap volume: %.2fGB\n", indent, swapTotal)
+	}
+}
+
+// printMoveCost prints the total move cost of the
+// relocations and swaps of the original *PartitionMap.
+func printMoveCost(mc moveCost, pm *kafkazk.PartitionMap, relos map[int][]relocation, swaps map[int][]swap, pmm kafkazk.PartitionMetaMap) {
+	if mc == nil {
+		return
+	}
+
+	fmt.Printf("%sTotal move cost: %.2f (%s)\n", indent, relocationCost(mc, pm, relos, swaps, pmm), mc)
 }
 
 func absDistance(x, t float64) float64 {
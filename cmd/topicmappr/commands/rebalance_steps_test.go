@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func TestPlanSwapForBroker(t *testing.T) {
+	pm, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":1,"replicas":[1003,1002]}]}`)
+
+	pmm := kafkazk.NewPartitionMetaMap()
+	pmm["test_topic"] = map[int]*kafkazk.PartitionMeta{
+		0: &kafkazk.PartitionMeta{Size: 100 * div},
+		1: &kafkazk.PartitionMeta{Size: 60 * div},
+	}
+
+	bm := kafkazk.BrokerMapFromPartitionMap(pm, kafkazk.BrokerMetaMap{}, false)
+	bm[1001].StorageFree, bm[1001].Locality = 50*div, "a"
+	bm[1002].StorageFree, bm[1002].Locality = 70*div, "b"
+	bm[1003].StorageFree, bm[1003].Locality = 90*div, "a"
+
+	params := planRelocationsForBrokerParams{
+		sourceID:               1001,
+		relos:                  map[int][]relocation{},
+		swaps:                  map[int][]swap{},
+		mappings:               pm.Mappings(),
+		brokers:                bm,
+		partitionMeta:          pmm,
+		plan:                   relocationPlan{},
+		topPartitionsLimit:     10,
+		partitionSizeThreshold: 512,
+		offloadTargetsMap:      map[int]struct{}{1001: struct{}{}},
+		tolerance:              0.50,
+	}
+
+	// Broker 1003 can't take p0 outright without going
+	// below the tolerated threshold; a swap with the
+	// smaller p1 fits.
+	if n := planRelocationsForBroker(rebalanceCmd, params); n != 1 {
+		t.Fatalf("Expected 1 planned swap, got %d", n)
+	}
+
+	if len(params.relos) != 0 {
+		t.Errorf("Expected no relocations, got %v", params.relos)
+	}
+
+	s := params.swaps[1001]
+	if len(s) != 1 || s[0].peer != 1003 || s[0].partition.Partition != 0 || s[0].peerPartition.Partition != 1 {
+		t.Fatalf("Unexpected swaps %v", params.swaps)
+	}
+
+	if bm[1001].StorageFree != 90*div || bm[1003].StorageFree != 50*div {
+		t.Errorf("Expected storage free 90GB, 50GB, got %.2fGB, %.2fGB",
+			bm[1001].StorageFree/div, bm[1003].StorageFree/div)
+	}
+
+	applyRelocationPlan(rebalanceCmd, pm, params.plan)
+
+	expected := [][]int{{1003, 1002}, {1001, 1002}}
+	for i, partn := range pm.Partitions {
+		if partn.Replicas[0] != expected[i][0] || partn.Replicas[1] != expected[i][1] {
+			t.Errorf("Expected replicas %v, got %v", expected[i], partn.Replicas)
+		}
+	}
+
+	// Both partitions are now planned.
+	if n := planSwapForBroker(rebalanceCmd, params, pm.Partitions); n != 0 {
+		t.Errorf("Expected no swaps, got %d", n)
+	}
+}
+
+func TestPlanRelocationsForBrokerStorageUsed(t *testing.T) {
+	pm, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001]},
+		{"topic":"test_topic","partition":1,"replicas":[1002]},
+		{"topic":"test_topic","partition":2,"replicas":[1003]}]}`)
+
+	pmm := kafkazk.NewPartitionMetaMap()
+	pmm["test_topic"] = map[int]*kafkazk.PartitionMeta{
+		0: &kafkazk.PartitionMeta{Size: 50 * div},
+		1: &kafkazk.PartitionMeta{Size: 50 * div},
+		2: &kafkazk.PartitionMeta{Size: 50 * div},
+	}
+
+	// 1003 has the most storage free but 1002
+	// has the lowest storage used percentage.
+	bm := kafkazk.BrokerMapFromPartitionMap(pm, kafkazk.BrokerMetaMap{}, false)
+	bm[1001].StorageFree, bm[1001].StorageTotal, bm[1001].Locality = 100*div, 1000*div, "a"
+	bm[1002].StorageFree, bm[1002].StorageTotal, bm[1002].Locality = 150*div, 300*div, "b"
+	bm[1003].StorageFree, bm[1003].StorageTotal, bm[1003].Locality = 400*div, 2000*div, "c"
+
+	if !bm.HasStorageTotal() {
+		t.Fatal("Expected HasStorageTotal true")
+	}
+
+	limits := newStorageLimits(bm, 0.50)
+	if !limits.byUsed || limits.selection() != "storage_used" {
+		t.Fatalf("Expected storage used limits, got %+v", limits)
+	}
+
+	params := planRelocationsForBrokerParams{
+		sourceID:               1001,
+		relos:                  map[int][]relocation{},
+		mappings:               pm.Mappings(),
+		brokers:                bm,
+		partitionMeta:          pmm,
+		plan:                   relocationPlan{},
+		topPartitionsLimit:     10,
+		partitionSizeThreshold: 512,
+		offloadTargetsMap:      map[int]struct{}{1001: struct{}{}},
+		tolerance:              0.50,
+	}
+
+	if n := planRelocationsForBroker(rebalanceCmd, params); n != 1 {
+		t.Fatalf("Expected 1 planned relocation, got %d", n)
+	}
+
+	r := params.relos[1001]
+	if len(r) != 1 || r[0].destination != 1002 {
+		t.Fatalf("Expected relocation to 1002, got %v", params.relos)
+	}
+
+	if u := bm[1002].StorageUsedPercent(); u < 66.66 || u > 66.67 {
+		t.Errorf("Expected storage used 66.67%%, got %.2f%%", u)
+	}
+}
+
+func TestReplicaMoveAllowed(t *testing.T) {
+	bm := kafkazk.BrokerMap{
+		1001: &kafkazk.Broker{ID: 1001, Locality: "a"},
+		1002: &kafkazk.Broker{ID: 1002, Locality: "b"},
+		1003: &kafkazk.Broker{ID: 1003, Locality: "a"},
+		1004: &kafkazk.Broker{ID: 1004, Locality: "b"},
+	}
+
+	partn := kafkazk.Partition{Topic: "test_topic", Replicas: []int{1001, 1002}}
+
+	tests := []struct {
+		from, to       int
+		localityScoped bool
+		expected       bool
+	}{
+		{1001, 1003, false, true},
+		{1001, 1004, false, false},
+		{1001, 1002, false, false},
+		{1002, 1004, true, true},
+		{1002, 1003, true, false},
+	}
+
+	for _, test := range tests {
+		ok := replicaMoveAllowed(partn, bm[test.from], bm[test.to], bm, test.localityScoped, nil)
+		if ok != test.expected {
+			t.Errorf("Expected %v for %d -> %d, got %v", test.expected, test.from, test.to, ok)
+		}
+	}
+
+	policies := kafkazk.PlacementPolicies{
+		"test_topic": &kafkazk.PlacementPolicy{ExcludedBrokers: map[int]bool{1003: true}},
+	}
+
+	if replicaMoveAllowed(partn, bm[1001], bm[1003], bm, false, policies) {
+		t.Errorf("Expected move to excluded broker 1003 to be disallowed")
+	}
+}
+
+func TestPairSwaps(t *testing.T) {
+	p0 := kafkazk.Partition{Topic: "test_topic", Partition: 0}
+	p1 := kafkazk.Partition{Topic: "test_topic", Partition: 1}
+	p2 := kafkazk.Partition{Topic: "test_topic", Partition: 2}
+
+	relos := map[int][]relocation{
+		1001: {{partition: p0, destination: 1002}, {partition: p2, destination: 1003}},
+		1002: {{partition: p1, destination: 1001}},
+	}
+
+	oneWay, swaps := pairSwaps(relos)
+
+	if len(oneWay) != 1 || len(oneWay[1001]) != 1 || oneWay[1001][0].destination != 1003 {
+		t.Errorf("Unexpected relocations %v", oneWay)
+	}
+
+	if len(swaps) != 1 || len(swaps[1001]) != 1 {
+		t.Fatalf("Unexpected swaps %v", swaps)
+	}
+
+	s := swaps[1001][0]
+	if !s.partition.Equal(p0) || s.peer != 1002 || !s.peerPartition.Equal(p1) {
+		t.Errorf("Unexpected swap %v", s)
+	}
+}
@@ -18,7 +18,8 @@ Target topics are provided as a comma delimited list of topic names and/or regex
 via the --topics parameter, which discovers matching topics in ZooKeeper (additionally,
 the --zk-addr and --zk-prefix global flags should be set). Alternatively, a JSON map can be
 provided via the --map-string flag. Target broker IDs are provided via the --broker flag.`,
-	Run: rebuild,
+	PreRun: bundlePreRun,
+	Run:    rebuild,
 }
 
 func init() {
@@ -32,16 +33,30 @@ func init() {
 	rebuildCmd.Flags().Bool("force-rebuild", false, "Forces a complete map rebuild")
 	rebuildCmd.Flags().Int("replication", 0, "Normalize the topic replication factor across all replica sets (0 results in a no-op)")
 	rebuildCmd.Flags().Bool("sub-affinity", false, "Replacement broker substitution affinity")
-	rebuildCmd.Flags().String("placement", "count", "Partition placement strategy: [count, storage]")
+	rebuildCmd.Flags().String("placement", "count", "Partition placement strategy: [count, storage, throughput]")
 	rebuildCmd.Flags().Int("min-rack-ids", 0, "Minimum number of required of unique rack IDs per replica set (0 requires that all are unique)")
+	rebuildCmd.Flags().String("weights", "", "If defined, use weighted placement scoring across dimensions [count, leaders, storage, throughput], e.g. 'storage=0.5,count=0.3,leaders=0.2' (overrides --placement)")
 	rebuildCmd.Flags().String("optimize", "distribution", "Optimization priority for the storage placement strategy: [distribution, storage]")
-	rebuildCmd.Flags().Float64("partition-size-factor", 1.0, "Factor by which to multiply partition sizes when using storage placement")
+	rebuildCmd.Flags().Bool("rack-leader-spread", false, "Spread partition leaders evenly across rack IDs")
+	rebuildCmd.Flags().String("policy-file", "", "YAML or JSON file of topic placement policies; policies take precedence over flags")
+	rebuildCmd.Flags().String("anti-affinity-groups", "", "Groups of topics that must not share brokers (semicolon delim. list of comma delim. topic lists)")
+	rebuildCmd.Flags().Float64("partition-size-factor", 1.0, "Factor by which to multiply partition sizes when using storage or throughput placement")
 	rebuildCmd.Flags().String("brokers", "", "Broker list to scope all partition placements to ('-1' for all currently mapped brokers, '-2' for all brokers in cluster)")
-	rebuildCmd.Flags().String("zk-metrics-prefix", "topicmappr", "ZooKeeper namespace prefix for Kafka metrics (when using storage placement)")
-	rebuildCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes) (when using storage placement)")
+	rebuildCmd.Flags().String("zk-metrics-prefix", "topicmappr", "ZooKeeper namespace prefix for Kafka metrics (when using storage or throughput placement)")
+	rebuildCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes) (when using storage or throughput placement)")
+	rebuildCmd.Flags().Bool("log-dirs", false, "Assign a log dir to each newly placed replica using per log dir broker metrics (requires kafka-reassign-partitions to apply)")
 	rebuildCmd.Flags().Bool("skip-no-ops", false, "Skip no-op partition assigments")
+	rebuildCmd.Flags().Bool("interactive", false, "Review and edit the plan interactively before writing maps")
 	rebuildCmd.Flags().Bool("optimize-leadership", false, "Rebalance all broker leader/follower ratios")
 	rebuildCmd.Flags().Bool("phased-reassignment", false, "Create two-phase output maps")
+	rebuildCmd.Flags().Int("batch-partitions", 0, "Split output maps into batches of at most this many partition moves (0 disables)")
+	rebuildCmd.Flags().Float64("batch-size-gb", 0.00, "Split output maps into batches replicating at most this many gigabytes (0 disables)")
+	rebuildCmd.Flags().Int("batch-broker-concurrency", 0, "Split output maps into batches with at most this many partition moves per broker (0 disables)")
+	rebuildCmd.Flags().String("report-format", "", "If defined, write a plan report in the specified format: [json, csv]")
+	rebuildCmd.Flags().Float64("throttle-rate", 0.00, "If defined, estimate the reassignment duration under the replication throttle rate in MB/s")
+	rebuildCmd.Flags().String("throttle-cap-map", "", "If defined, estimate the reassignment duration under per-broker replication throttle rates; autothrottle --cap-map JSON map of instance types to MB/s (keys of the form 'broker:<id>' set a broker rate)")
+	rebuildCmd.Flags().String("save-bundle", "", "If defined, save all plan inputs to a bundle in the specified directory")
+	rebuildCmd.Flags().String("from-bundle", "", "If defined, generate the plan offline from a bundle saved with --save-bundle; flags set on the command line take precedence")
 
 	// Required.
 	rebuildCmd.MarkFlagRequired("brokers")
@@ -56,19 +71,51 @@ func rebuild(cmd *cobra.Command, _ []string) {
 	fr, _ := cmd.Flags().GetBool("force-rebuild")
 	sa, _ := cmd.Flags().GetBool("sub-affinity")
 	m, _ := cmd.Flags().GetBool("use-meta")
+	phased, _ := cmd.Flags().GetBool("phased-reassignment")
+	batched := getBatchParams(cmd).enabled()
+	bgb, _ := cmd.Flags().GetFloat64("batch-size-gb")
+	rf := cmd.Flag("report-format").Value.String()
+	tr, terr := getThrottleRates(cmd)
+	aag := cmd.Flag("anti-affinity-groups").Value.String()
+	w, werr := getPlacementWeights(cmd)
+	ld, _ := cmd.Flags().GetBool("log-dirs")
 
 	switch {
 	case ms == "" && t == "":
 		fmt.Println("\n[ERROR] must specify either --topics or --map-string")
 		defaultsAndExit()
-	case p != "count" && p != "storage":
-		fmt.Println("\n[ERROR] --placement must be either 'count' or 'storage'")
+	case p != "count" && p != "storage" && p != "throughput":
+		fmt.Println("\n[ERROR] --placement must be one of 'count', 'storage' or 'throughput'")
 		defaultsAndExit()
 	case o != "distribution" && o != "storage":
 		fmt.Println("\n[ERROR] --optimize must be either 'distribution' or 'storage'")
 		defaultsAndExit()
-	case !m && p == "storage":
-		fmt.Println("\n[ERROR] --placement=storage requires --use-meta=true")
+	case werr != nil:
+		fmt.Printf("\n[ERROR] %s\n", werr)
+		defaultsAndExit()
+	case w != nil && cmd.Flags().Changed("placement"):
+		fmt.Println("\n[ERROR] --weights cannot be used with --placement")
+		defaultsAndExit()
+	case w != nil && !m && usesStorageMetrics(cmd):
+		fmt.Println("\n[ERROR] --weights with storage or throughput requires --use-meta=true")
+		defaultsAndExit()
+	case !m && ld:
+		fmt.Println("\n[ERROR] --log-dirs requires --use-meta=true")
+		defaultsAndExit()
+	case !m && usesStorageMetrics(cmd):
+		fmt.Printf("\n[ERROR] --placement=%s requires --use-meta=true\n", p)
+		defaultsAndExit()
+	case phased && batched:
+		fmt.Println("\n[ERROR] --phased-reassignment cannot be used with --batch-* flags")
+		defaultsAndExit()
+	case !validReportFormat(rf):
+		fmt.Println("\n[ERROR] --report-format must be either 'json' or 'csv'")
+		defaultsAndExit()
+	case terr != nil:
+		fmt.Printf("\n[ERROR] %s\n", terr)
+		defaultsAndExit()
+	case !m && tr.byInstanceType():
+		fmt.Println("\n[ERROR] --throttle-cap-map instance types require --use-meta=true")
 		defaultsAndExit()
 	case fr && sa:
 		fmt.Println("\n[INFO] --force-rebuild disables --sub-affinity")
@@ -78,7 +125,7 @@ func rebuild(cmd *cobra.Command, _ []string) {
 
 	// ZooKeeper init.
 	var zk kafkazk.Handler
-	if m || len(Config.topics) > 0 || p == "storage" {
+	if m || len(Config.topics) > 0 || usesStorageMetrics(cmd) || bgb > 0 || tr.enabled() || aag != "" {
 		var err error
 		zk, err = initZooKeeper(cmd)
 		if err != nil {
@@ -102,9 +149,10 @@ func rebuild(cmd *cobra.Command, _ []string) {
 	//   are detected and reported.
 	// 5) The new PartitionMap is split by topic. Map(s) are written.
 
-	// Fetch broker metadata.
+	// Fetch broker metadata. Instance types and log
+	// dir metrics are stored with broker metrics.
 	var withMetrics bool
-	if cmd.Flag("placement").Value.String() == "storage" {
+	if usesStorageMetrics(cmd) || usesLogDirMetrics(cmd) || tr.byInstanceType() {
 		checkMetaAge(cmd, zk)
 		withMetrics = true
 	}
@@ -116,8 +164,14 @@ func rebuild(cmd *cobra.Command, _ []string) {
 
 	// Fetch partition metadata.
 	var partitionMeta kafkazk.PartitionMetaMap
-	if cmd.Flag("placement").Value.String() == "storage" {
+	var partitionMetaErr error
+	switch {
+	case usesStorageMetrics(cmd) || usesLogDirMetrics(cmd) || bgb > 0 || tr.enabled():
 		partitionMeta = getPartitionMeta(cmd, zk)
+	case rf != "" && zk != nil:
+		// Partition sizes are optional for reports; an
+		// unavailable metadata error is a warning.
+		partitionMeta, partitionMetaErr = zk.GetAllPartitionMeta()
 	}
 
 	// Build a partition map either from literal map text input or by fetching the
@@ -131,6 +185,12 @@ func rebuild(cmd *cobra.Command, _ []string) {
 	// Print if any topics were excluded due to pending deletion.
 	printExcludedTopics(pending)
 
+	// Brokers pinned by topic policies are included in the
+	// target broker list; placement policies limit them to
+	// the topics of the pinning policy.
+	policies := getPolicies(cmd)
+	Config.brokers = append(Config.brokers, policies.pinnedBrokers(partitionMapIn)...)
+
 	brokers, bs := getBrokers(cmd, partitionMapIn, brokerMeta)
 	brokersOrig := brokers.Copy()
 
@@ -154,18 +214,51 @@ func rebuild(cmd *cobra.Command, _ []string) {
 	// Print changes, actions.
 	printChangesActions(cmd, bs)
 
-	// Apply any replication factor settings.
+	// Get placement policies for the input topics
+	// and report any violations in the current map.
+	var placementPolicies kafkazk.PlacementPolicies
+	if policies != nil {
+		var err error
+		placementPolicies, err = policies.placementPolicies(partitionMapIn, zk)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		printViolations("Policy", policies.violations(originalMap, brokersOrig, placementPolicies))
+	}
+
+	// Get topic anti-affinities and report
+	// any violations in the current map.
+	topicAntiAffinities := getAntiAffinities(cmd, partitionMapIn, zk)
+	if topicAntiAffinities != nil {
+		printViolations("Anti-affinity", antiAffinityViolations(originalMap, topicAntiAffinities))
+	}
+
+	// Apply any replication factor settings. Policy
+	// replication factors take precedence.
 	updateReplicationFactor(cmd, partitionMapIn)
+	policies.setReplication(partitionMapIn)
 
 	// Build a new map using the provided list of brokers.
 	// This is OK to run even when a no-op is intended.
-	partitionMapOut, errs := buildMap(cmd, partitionMapIn, partitionMeta, brokers, affinities)
+	partitionMapOut, errs := buildMap(cmd, partitionMapIn, partitionMeta, brokers, affinities, placementPolicies, topicAntiAffinities, getClusterMap(cmd, zk))
+
+	// Ensure the output map satisfies all policies
+	// and anti-affinities.
+	errs = append(errs, policies.violations(partitionMapOut, brokers, placementPolicies)...)
+	errs = append(errs, antiAffinityViolations(partitionMapOut, topicAntiAffinities)...)
 
 	// Optimize leaders.
 	if t, _ := cmd.Flags().GetBool("optimize-leadership"); t {
 		partitionMapOut.OptimizeLeaderFollower()
 	}
 
+	// Assign log dirs if configured.
+	if ld {
+		errs = append(errs, assignLogDirs(originalMap, partitionMapOut, partitionMeta, brokerMeta)...)
+	}
+
 	// Count missing brokers as a warning.
 	if bs.Missing > 0 {
 		errs = append(errs, fmt.Errorf("%d provided brokers not found in ZooKeeper", bs.Missing))
@@ -178,25 +271,61 @@ func rebuild(cmd *cobra.Command, _ []string) {
 		)
 	}
 
-	// Generate phased map if enabled.
-	var phasedMap *kafkazk.PartitionMap
-	if phased, _ := cmd.Flags().GetBool("phased-reassignment"); phased {
-		phasedMap = phasedReassignment(originalMap, partitionMapOut)
-	}
-
 	// Print map change results.
 	printMapChanges(originalMap, partitionMapOut)
 
+	// Print log dir assignments if configured.
+	if ld {
+		printLogDirAssignments(partitionMapOut)
+	}
+
 	// Print broker assignment statistics.
 	printBrokerAssignmentStats(cmd, originalMap, partitionMapOut, brokersOrig, brokers)
 
+	// Print broker throughput changes.
+	printBrokerThroughput(cmd, originalMap, partitionMapOut, partitionMeta)
+
+	// Print weighted placement scores.
+	printPlacementScores(cmd, originalMap, partitionMapOut, brokersOrig, brokers, partitionMeta)
+
+	// Warn if the plan report would understate bytes moved.
+	if rf != "" {
+		if partitionMetaErr != nil {
+			errs = append(errs, fmt.Errorf("partition metadata unavailable for plan report: %s", partitionMetaErr))
+		}
+		errs = append(errs, reportSizeViolations(originalMap, partitionMapOut, partitionMeta)...)
+	}
+
+	// Save a bundle of plan inputs if configured.
+	saveBundle(cmd, zk)
+
 	// Print error/warnings.
 	handleOverridableErrs(cmd, errs)
 
+	// Review and edit the plan if configured.
+	partitionMapOut, brokers = reviewPlan(cmd, originalMap, partitionMapOut, brokersOrig, brokers, partitionMeta, policies, placementPolicies)
+
+	// Generate phased map if enabled.
+	var phasedMap *kafkazk.PartitionMap
+	if phased {
+		phasedMap = phasedReassignment(originalMap, partitionMapOut)
+	}
+
+	// Write a plan report if configured.
+	writeReport(cmd, originalMap, partitionMapOut, partitionMeta, brokersOrig, brokers)
+
 	// Skip no-ops if configured.
 	if sno, _ := cmd.Flags().GetBool("skip-no-ops"); sno {
 		originalMap, partitionMapOut = skipReassignmentNoOps(originalMap, partitionMapOut)
 	}
 
-	writeMaps(cmd, partitionMapOut, phasedMap)
+	// Split the output map into batches if enabled.
+	batches := getBatches(cmd, originalMap, partitionMapOut, partitionMeta)
+
+	// Estimate the reassignment duration if configured.
+	phases := reassignmentPhases(originalMap, partitionMapOut, phasedMap, batches)
+	printReassignmentEstimate(cmd, phases, partitionMeta, brokerMeta)
+
+	writeMaps(cmd, partitionMapOut, phasedMap, originalMap)
+	writeBatches(cmd, batches)
 }
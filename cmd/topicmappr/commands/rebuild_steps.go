@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 	"os"
+	"regexp"
 
 	"github.com/DataDog/kafka-kit/kafkazk"
 
@@ -49,6 +50,25 @@ func getPartitionMap(cmd *cobra.Command, zk kafkazk.Handler) (*kafkazk.Partition
 	return nil, nil
 }
 
+// getClusterMap returns a partition map of all topics in the cluster if
+// throughput is a placement dimension, used to seed broker throughput
+// loads with partitions outside the map being rebuilt. A nil map is
+// returned otherwise or if no ZooKeeper connection is available.
+func getClusterMap(cmd *cobra.Command, zk kafkazk.Handler) *kafkazk.PartitionMap {
+	if zk == nil || !usesThroughputMetrics(cmd) {
+		return nil
+	}
+
+	all := []*regexp.Regexp{regexp.MustCompile(".*")}
+	pm, err := kafkazk.PartitionMapFromZK(all, zk)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	return pm
+}
+
 // getSubAffinities, if enabled via --sub-affinity, takes reference broker maps
 // and a partition map and attempts to return a complete SubstitutionAffinities.
 func getSubAffinities(cmd *cobra.Command, bm kafkazk.BrokerMap, bmo kafkazk.BrokerMap, pm *kafkazk.PartitionMap) kafkazk.SubstitutionAffinities {
@@ -177,11 +197,20 @@ func updateReplicationFactor(cmd *cobra.Command, pm *kafkazk.PartitionMap) {
 
 // buildMap takes an input PartitionMap, rebuild parameters, and all partition/broker
 // metadata structures required to generate the output PartitionMap. A []string of
-// warnings / advisories is returned if any are encountered.
-func buildMap(cmd *cobra.Command, pm *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap, bm kafkazk.BrokerMap, af kafkazk.SubstitutionAffinities) (*kafkazk.PartitionMap, errors) {
+// warnings / advisories is returned if any are encountered. The optional cluster
+// PartitionMap seeds broker throughput loads, see getClusterMap.
+func buildMap(cmd *cobra.Command, pm *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap, bm kafkazk.BrokerMap, af kafkazk.SubstitutionAffinities, pp kafkazk.PlacementPolicies, aa *kafkazk.TopicAntiAffinities, cluster *kafkazk.PartitionMap) (*kafkazk.PartitionMap, errors) {
 	placement := cmd.Flag("placement").Value.String()
 	psf, _ := cmd.Flags().GetFloat64("partition-size-factor")
 	mrrid, _ := cmd.Flags().GetInt("min-rack-ids")
+	rls, _ := cmd.Flags().GetBool("rack-leader-spread")
+
+	// Weighted placement overrides the
+	// placement strategy.
+	weights, _ := getPlacementWeights(cmd)
+	if weights != nil {
+		placement = "weighted"
+	}
 
 	rebuildParams := kafkazk.RebuildParams{
 		PMM:              pmm,
@@ -190,6 +219,11 @@ func buildMap(cmd *cobra.Command, pm *kafkazk.PartitionMap, pmm kafkazk.Partitio
 		Optimization:     cmd.Flag("optimize").Value.String(),
 		PartnSzFactor:    psf,
 		MinUniqueRackIDs: mrrid,
+		RackLeaderSpread: rls,
+		Policies:         pp,
+		AntiAffinities:   aa,
+		Weights:          weights,
+		Cluster:          cluster,
 	}
 
 	if af != nil {
@@ -213,9 +247,9 @@ func buildMap(cmd *cobra.Command, pm *kafkazk.PartitionMap, pmm kafkazk.Partitio
 	if fr, _ := cmd.Flags().GetBool("force-rebuild"); fr {
 		// Get a stripped map that we'll call rebuild on.
 		partitionMapInStripped := pm.Strip()
-		// If the storage placement strategy is being used,
+		// If the storage or throughput placement strategy is used,
 		// update the broker StorageFree values.
-		if placement == "storage" {
+		if usesStorageMetrics(cmd) {
 			allBrokers := func(b *kafkazk.Broker) bool { return true }
 			err := rebuildParams.BM.SubStorage(pm, pmm, allBrokers)
 			if err != nil {
@@ -230,7 +264,7 @@ func buildMap(cmd *cobra.Command, pm *kafkazk.PartitionMap, pmm kafkazk.Partitio
 
 	// Update the StorageFree only on brokers
 	// marked for replacement.
-	if placement == "storage" {
+	if usesStorageMetrics(cmd) {
 		replacedBrokers := func(b *kafkazk.Broker) bool { return b.Replace }
 		err := rebuildParams.BM.SubStorage(pm, pmm, replacedBrokers)
 		if err != nil {
@@ -269,6 +303,10 @@ func phasedReassignment(pm1, pm2 *kafkazk.PartitionMap) *kafkazk.PartitionMap {
 				leader := rs[partn.Partition][0]
 				if notInReplicaSet(leader, partn.Replicas) {
 					phase1pm.Partitions[i].Replicas = append([]int{leader}, partn.Replicas...)
+					// The original leader stays in its current log dir.
+					if partn.LogDirs != nil {
+						phase1pm.Partitions[i].LogDirs = append([]string{kafkazk.AnyLogDir}, partn.LogDirs...)
+					}
 				}
 			}
 		}
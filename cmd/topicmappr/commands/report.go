@@ -0,0 +1,403 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+// planReport is a structured summary of the changes
+// between an input and output PartitionMap.
+type planReport struct {
+	Partitions []partitionReport `json:"partitions"`
+	Brokers    []brokerReport    `json:"brokers"`
+	Degree     degreeReport      `json:"degree_distribution"`
+	BytesMoved float64           `json:"bytes_moved"`
+	// StorageUsed is only set if the storage
+	// capacity of all brokers is known.
+	StorageUsed *storageUsedReport `json:"storage_used,omitempty"`
+}
+
+// partitionReport describes the change to a single partition.
+type partitionReport struct {
+	Topic       string `json:"topic"`
+	Partition   int    `json:"partition"`
+	OldReplicas []int  `json:"old_replicas"`
+	NewReplicas []int  `json:"new_replicas"`
+	// Change is the whatChanged description.
+	Change string `json:"change"`
+	// BytesMoved is the partition size multiplied by the
+	// number of replicas placed on brokers that didn't
+	// previously hold the partition.
+	BytesMoved float64 `json:"bytes_moved"`
+}
+
+// brokerReport describes a broker before and after the change.
+type brokerReport struct {
+	ID     int         `json:"id"`
+	Before brokerState `json:"before"`
+	After  brokerState `json:"after"`
+}
+
+// brokerState holds broker assignment counts and, if broker
+// storage metrics are in use, the storage free in bytes and,
+// if the broker storage capacity is known, the storage used
+// percentage.
+type brokerState struct {
+	Leader      int      `json:"leader"`
+	Follower    int      `json:"follower"`
+	Total       int      `json:"total"`
+	StorageFree *float64 `json:"storage_free,omitempty"`
+	StorageUsed *float64 `json:"storage_used_percent,omitempty"`
+}
+
+// storageUsedReport holds before and after storage used
+// percentage statistics.
+type storageUsedReport struct {
+	Before storageUsedStats `json:"before"`
+	After  storageUsedStats `json:"after"`
+}
+
+// storageUsedStats describes the storage used
+// percentages of a set of brokers.
+type storageUsedStats struct {
+	Min         float64 `json:"min"`
+	Max         float64 `json:"max"`
+	RangeSpread float64 `json:"range_spread"`
+	StdDev      float64 `json:"std_dev"`
+}
+
+func newStorageUsedStats(bm kafkazk.BrokerMap) storageUsedStats {
+	s := storageUsedStats{
+		RangeSpread: bm.StorageRangeSpread(),
+		StdDev:      bm.StorageUsedStdDev(),
+	}
+
+	s.Min, s.Max = bm.StorageUsedMinMax()
+
+	return s
+}
+
+// degreeReport holds before and after DegreeDistributionStats.
+type degreeReport struct {
+	Before kafkazk.DegreeDistributionStats `json:"before"`
+	After  kafkazk.DegreeDistributionStats `json:"after"`
+}
+
+// validReportFormat returns whether the --report-format value is valid.
+func validReportFormat(f string) bool {
+	switch f {
+	case "", "json", "csv":
+		return true
+	}
+
+	return false
+}
+
+// buildReport takes the original input PartitionMap and the final output
+// PartitionMap along with a PartitionMetaMap and the before and after
+// BrokerMaps and returns a planReport. Broker storage is only included
+// if withStorage is true. A nil PartitionMetaMap results in zero values
+// for bytes moved.
+func buildReport(pm1, pm2 *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap, bm1, bm2 kafkazk.BrokerMap, withStorage bool) planReport {
+	r := planReport{
+		Partitions: []partitionReport{},
+		Brokers:    []brokerReport{},
+	}
+
+	// Per-partition changes.
+	for i := range pm1.Partitions {
+		p1, p2 := pm1.Partitions[i], pm2.Partitions[i]
+
+		pr := partitionReport{
+			Topic:       p1.Topic,
+			Partition:   p1.Partition,
+			OldReplicas: p1.Replicas,
+			NewReplicas: p2.Replicas,
+			Change:      whatChanged(p1.Replicas, p2.Replicas),
+		}
+
+		existing := map[int]struct{}{}
+		for _, id := range p1.Replicas {
+			existing[id] = struct{}{}
+		}
+
+		size, _ := pmm.Size(p2)
+
+		for _, id := range p2.Replicas {
+			if _, exist := existing[id]; !exist {
+				pr.BytesMoved += size
+			}
+		}
+
+		r.BytesMoved += pr.BytesMoved
+		r.Partitions = append(r.Partitions, pr)
+	}
+
+	// Per-broker changes.
+	use1, use2 := pm1.UseStats(), pm2.UseStats()
+
+	ids := map[int]struct{}{}
+	for _, m := range []kafkazk.BrokerUseStatsMap{use1, use2} {
+		for id := range m {
+			ids[id] = struct{}{}
+		}
+	}
+
+	for id := range bm2 {
+		if id != kafkazk.StubBrokerID {
+			ids[id] = struct{}{}
+		}
+	}
+
+	sorted := []int{}
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+
+	sort.Ints(sorted)
+
+	state := func(id int, use kafkazk.BrokerUseStatsMap, bm kafkazk.BrokerMap) brokerState {
+		var s brokerState
+		if u, exist := use[id]; exist {
+			s.Leader, s.Follower = u.Leader, u.Follower
+			s.Total = u.Leader + u.Follower
+		}
+
+		if b, exist := bm[id]; exist && withStorage {
+			free := b.StorageFree
+			s.StorageFree = &free
+
+			if b.StorageTotal > 0 {
+				used := b.StorageUsedPercent()
+				s.StorageUsed = &used
+			}
+		}
+
+		return s
+	}
+
+	for _, id := range sorted {
+		r.Brokers = append(r.Brokers, brokerReport{
+			ID:     id,
+			Before: state(id, use1, bm1),
+			After:  state(id, use2, bm2),
+		})
+	}
+
+	// Degree distribution.
+	r.Degree.Before = pm1.DegreeDistribution().Stats()
+	r.Degree.After = pm2.DegreeDistribution().Stats()
+
+	// Storage used statistics.
+	if mb1, mb2 := storageStatsBrokers(pm1, bm1, bm2); withStorage && mb1.HasStorageTotal() && mb2.HasStorageTotal() {
+		r.StorageUsed = &storageUsedReport{
+			Before: newStorageUsedStats(mb1),
+			After:  newStorageUsedStats(mb2),
+		}
+	}
+
+	return r
+}
+
+// reportSizeViolations takes the original input PartitionMap, the final
+// output PartitionMap and a PartitionMetaMap and returns an error if any
+// partitions with replicas moved have no size in the PartitionMetaMap, as
+// their bytes moved are reported as 0.
+func reportSizeViolations(pm1, pm2 *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap) errors {
+	var missing int
+	for i := range pm1.Partitions {
+		p1, p2 := pm1.Partitions[i], pm2.Partitions[i]
+
+		var moved bool
+		for _, id := range p2.Replicas {
+			moved = moved || notInReplicaSet(id, p1.Replicas)
+		}
+
+		if _, err := pmm.Size(p2); moved && err != nil {
+			missing++
+		}
+	}
+
+	if missing == 0 {
+		return nil
+	}
+
+	return errors{fmt.Errorf("%d partitions with moved replicas have no size metadata; plan report bytes moved are understated", missing)}
+}
+
+// writeReport writes a planReport in the format specified by the
+// --report-format flag, if set. A JSON report is written as a single
+// file; a CSV report is written as separate partitions, brokers and
+// summary files. Reports are named after the --out-file value,
+// defaulting to "plan".
+func writeReport(cmd *cobra.Command, pm1, pm2 *kafkazk.PartitionMap, pmm kafkazk.PartitionMetaMap, bm1, bm2 kafkazk.BrokerMap) {
+	format := cmd.Flag("report-format").Value.String()
+	if format == "" {
+		return
+	}
+
+	r := buildReport(pm1, pm2, pmm, bm1, bm2, usesStorageMetrics(cmd))
+
+	name := cmd.Flag("out-file").Value.String()
+	if name == "" {
+		name = "plan"
+	}
+
+	base := fmt.Sprintf("%s%s-report", cmd.Flag("out-path").Value.String(), name)
+
+	var files []string
+	var err error
+
+	switch format {
+	case "json":
+		files, err = writeReportJSON(r, base)
+	case "csv":
+		files, err = writeReportCSV(r, base)
+	}
+
+	fmt.Println("\nPlan report:")
+
+	if err != nil {
+		fmt.Printf("%s%s\n", indent, err)
+		os.Exit(1)
+	}
+
+	for _, f := range files {
+		fmt.Printf("%s%s\n", indent, f)
+	}
+}
+
+// writeReportJSON writes the planReport to base.json.
+func writeReportJSON(r planReport, base string) ([]string, error) {
+	out, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	path := base + ".json"
+	if err := ioutil.WriteFile(path, append(out, '\n'), 0644); err != nil {
+		return nil, err
+	}
+
+	return []string{path}, nil
+}
+
+// writeReportCSV writes the planReport to base-partitions.csv,
+// base-brokers.csv and base-summary.csv.
+func writeReportCSV(r planReport, base string) ([]string, error) {
+	records := map[string][][]string{}
+	ff := func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) }
+
+	// Partitions.
+	partitions := [][]string{
+		{"topic", "partition", "old_replicas", "new_replicas", "change", "bytes_moved"},
+	}
+
+	for _, p := range r.Partitions {
+		partitions = append(partitions, []string{
+			p.Topic,
+			strconv.Itoa(p.Partition),
+			replicasString(p.OldReplicas),
+			replicasString(p.NewReplicas),
+			p.Change,
+			ff(p.BytesMoved),
+		})
+	}
+
+	records["partitions"] = partitions
+
+	// Brokers.
+	storage := func(f *float64) string {
+		if f == nil {
+			return ""
+		}
+		return ff(*f)
+	}
+
+	brokers := [][]string{
+		{"id",
+			"leader_before", "follower_before", "total_before", "storage_free_before", "storage_used_percent_before",
+			"leader_after", "follower_after", "total_after", "storage_free_after", "storage_used_percent_after"},
+	}
+
+	for _, b := range r.Brokers {
+		brokers = append(brokers, []string{
+			strconv.Itoa(b.ID),
+			strconv.Itoa(b.Before.Leader),
+			strconv.Itoa(b.Before.Follower),
+			strconv.Itoa(b.Before.Total),
+			storage(b.Before.StorageFree),
+			storage(b.Before.StorageUsed),
+			strconv.Itoa(b.After.Leader),
+			strconv.Itoa(b.After.Follower),
+			strconv.Itoa(b.After.Total),
+			storage(b.After.StorageFree),
+			storage(b.After.StorageUsed),
+		})
+	}
+
+	records["brokers"] = brokers
+
+	// Summary.
+	records["summary"] = [][]string{
+		{"metric", "before", "after"},
+		{"degree_min", ff(r.Degree.Before.Min), ff(r.Degree.After.Min)},
+		{"degree_max", ff(r.Degree.Before.Max), ff(r.Degree.After.Max)},
+		{"degree_avg", ff(r.Degree.Before.Avg), ff(r.Degree.After.Avg)},
+		{"bytes_moved", "", ff(r.BytesMoved)},
+	}
+
+	if s := r.StorageUsed; s != nil {
+		records["summary"] = append(records["summary"],
+			[]string{"storage_used_percent_min", ff(s.Before.Min), ff(s.After.Min)},
+			[]string{"storage_used_percent_max", ff(s.Before.Max), ff(s.After.Max)},
+			[]string{"storage_used_range_spread", ff(s.Before.RangeSpread), ff(s.After.RangeSpread)},
+			[]string{"storage_used_std_dev", ff(s.Before.StdDev), ff(s.After.StdDev)},
+		)
+	}
+
+	var files []string
+
+	for _, name := range []string{"partitions", "brokers", "summary"} {
+		path := fmt.Sprintf("%s-%s.csv", base, name)
+
+		f, err := os.Create(path)
+		if err != nil {
+			return files, err
+		}
+
+		w := csv.NewWriter(f)
+		w.WriteAll(records[name])
+		f.Close()
+
+		if err := w.Error(); err != nil {
+			return files, err
+		}
+
+		files = append(files, path)
+	}
+
+	return files, nil
+}
+
+// replicasString returns a space delimited string of replica IDs.
+func replicasString(r []int) string {
+	var s string
+	for i, id := range r {
+		if i > 0 {
+			s += " "
+		}
+		s += strconv.Itoa(id)
+	}
+
+	return s
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func TestBuildReport(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm1, _ := zk.GetPartitionMap("test_topic")
+	pmm, _ := zk.GetAllPartitionMeta()
+	bm1 := kafkazk.BrokerMapFromPartitionMap(pm1, kafkazk.BrokerMetaMap{}, false)
+
+	pm2 := pm1.Copy()
+	pm2.Partitions[0].Replicas = []int{1005, 1002}
+	pm2.Partitions[1].Replicas = []int{1001, 1002}
+
+	bm2 := kafkazk.BrokerMapFromPartitionMap(pm2, kafkazk.BrokerMetaMap{}, false)
+
+	r := buildReport(pm1, pm2, pmm, bm1, bm2, false)
+
+	if len(r.Partitions) != 4 {
+		t.Fatalf("Expected 4 partitions, got %d", len(r.Partitions))
+	}
+
+	expected := []string{"replaced broker", "preferred leader", "no-op", "no-op"}
+	for i, p := range r.Partitions {
+		if p.Change != expected[i] {
+			t.Errorf("Expected change '%s', got '%s'", expected[i], p.Change)
+		}
+	}
+
+	if r.Partitions[0].BytesMoved != 1000.00 {
+		t.Errorf("Expected 1000.00 bytes moved, got %.2f", r.Partitions[0].BytesMoved)
+	}
+
+	if r.BytesMoved != 1000.00 {
+		t.Errorf("Expected 1000.00 total bytes moved, got %.2f", r.BytesMoved)
+	}
+
+	// Brokers 1001-1005.
+	if len(r.Brokers) != 5 {
+		t.Fatalf("Expected 5 brokers, got %d", len(r.Brokers))
+	}
+
+	b := r.Brokers[4]
+	if b.ID != 1005 || b.Before.Total != 0 || b.After.Leader != 1 {
+		t.Errorf("Unexpected broker 1005 report: %+v", b)
+	}
+
+	if b.After.StorageFree != nil {
+		t.Error("Expected nil storage free")
+	}
+
+	if r.Degree.Before != pm1.DegreeDistribution().Stats() {
+		t.Errorf("Unexpected degree distribution stats")
+	}
+	if r.StorageUsed != nil {
+		t.Error("Expected nil storage used")
+	}
+
+	// With storage capacities.
+	for _, bm := range []kafkazk.BrokerMap{bm1, bm2} {
+		for id, b := range bm {
+			b.StorageFree, b.StorageTotal = 100, 400
+			if id == 1005 {
+				b.StorageFree = 300
+			}
+		}
+	}
+
+	r = buildReport(pm1, pm2, pmm, bm1, bm2, true)
+
+	if b := r.Brokers[4]; b.After.StorageUsed == nil || *b.After.StorageUsed != 25.00 {
+		t.Errorf("Expected broker 1005 storage used 25.00%%, got %v", b.After.StorageUsed)
+	}
+
+	if s := r.StorageUsed; s == nil || s.Before.RangeSpread != 0.00 || s.After.RangeSpread != 50.00 {
+		t.Errorf("Unexpected storage used report %+v", s)
+	}
+}
+
+func TestReportSizeViolations(t *testing.T) {
+	zk := &kafkazk.Mock{}
+	pm1, _ := zk.GetPartitionMap("test_topic")
+	pmm, _ := zk.GetAllPartitionMeta()
+
+	pm2 := pm1.Copy()
+	pm2.Partitions[0].Replicas = []int{1005, 1002}
+	pm2.Partitions[1].Replicas = []int{1001, 1002}
+
+	if errs := reportSizeViolations(pm1, pm2, pmm); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+
+	// Partitions without moved replicas don't need sizes.
+	delete(pmm["test_topic"], 1)
+	if errs := reportSizeViolations(pm1, pm2, pmm); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+
+	delete(pmm["test_topic"], 0)
+	if errs := reportSizeViolations(pm1, pm2, pmm); len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %v", errs)
+	}
+
+	if errs := reportSizeViolations(pm1, pm2, nil); len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %v", errs)
+	}
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+// mapMetadata describes when and how a map was generated. It's written
+// as a header in maps that include metadata. Tools consuming the
+// standard Kafka reassignment format ignore the header.
+type mapMetadata struct {
+	// Type describes the map, e.g. "rollback".
+	Type        string   `json:"type"`
+	GeneratedAt string   `json:"generated_at"`
+	Version     string   `json:"version"`
+	Command     string   `json:"command"`
+	Args        []string `json:"args"`
+	Topics      []string `json:"topics"`
+}
+
+// mapWithMetadata is a partition map in the Kafka
+// reassignment format with a metadata header.
+type mapWithMetadata struct {
+	Version    int                   `json:"version"`
+	Metadata   *mapMetadata          `json:"metadata,omitempty"`
+	Partitions kafkazk.PartitionList `json:"partitions"`
+}
+
+// newMapMetadata returns a *mapMetadata of type t for the
+// *PartitionMap as generated by the current command.
+func newMapMetadata(cmd *cobra.Command, t string, pm *kafkazk.PartitionMap) *mapMetadata {
+	return &mapMetadata{
+		Type:        t,
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Version:     version,
+		Command:     cmd.Name(),
+		Args:        os.Args[1:],
+		Topics:      pm.Topics(),
+	}
+}
+
+// rollbackMap takes the original input PartitionMap and the final output
+// PartitionMap and returns the inverse of the reassignment: the original
+// replica sets for each partition that differs in the output map.
+func rollbackMap(pm1, pm2 *kafkazk.PartitionMap) *kafkazk.PartitionMap {
+	rollback := kafkazk.NewPartitionMap()
+
+	output := map[string]map[int]kafkazk.Partition{}
+	for _, p := range pm2.Partitions {
+		if _, exist := output[p.Topic]; !exist {
+			output[p.Topic] = map[int]kafkazk.Partition{}
+		}
+		output[p.Topic][p.Partition] = p
+	}
+
+	for _, p := range pm1.Partitions {
+		if p2, exist := output[p.Topic][p.Partition]; exist && !p.Equal(p2) {
+			rollback.Partitions = append(rollback.Partitions, p)
+		}
+	}
+
+	return rollback
+}
+
+// writeMapWithMetadata writes the *PartitionMap with the
+// *mapMetadata header to the path with a .json suffix.
+func writeMapWithMetadata(pm *kafkazk.PartitionMap, meta *mapMetadata, path string) error {
+	out, err := json.Marshal(mapWithMetadata{
+		Version:    pm.Version,
+		Metadata:   meta,
+		Partitions: pm.Partitions,
+	})
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path+".json", append(out, '\n'), 0644)
+}
+
+// readMapMetadata returns the metadata header of a map.
+// A nil *mapMetadata is returned if no header is found.
+func readMapMetadata(data []byte) *mapMetadata {
+	m := mapWithMetadata{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+
+	return m.Metadata
+}
+
+// writeRollbackMaps takes the original input PartitionMap and the final
+// output PartitionMap and writes per-topic and combined rollback maps
+// for all changed partitions.
+func writeRollbackMaps(cmd *cobra.Command, pm1, pm2 *kafkazk.PartitionMap) {
+	rollback := rollbackMap(pm1, pm2)
+	if len(rollback.Partitions) == 0 {
+		return
+	}
+
+	outPath := cmd.Flag("out-path").Value.String()
+
+	combined := "rollback"
+	if outFile := cmd.Flag("out-file").Value.String(); outFile != "" {
+		combined = outFile + "-rollback"
+	}
+
+	meta := newMapMetadata(cmd, "rollback", rollback)
+
+	fmt.Println("\nRollback maps:")
+
+	fullPath := outPath + combined
+	if err := writeMapWithMetadata(rollback, meta, fullPath); err != nil {
+		fmt.Printf("%s%s\n", indent, err)
+	} else {
+		fmt.Printf("%s%s.json [combined map]\n", indent, fullPath)
+	}
+
+	for _, t := range rollback.Topics() {
+		tm := kafkazk.NewPartitionMap()
+		for _, p := range rollback.Partitions {
+			if p.Topic == t {
+				tm.Partitions = append(tm.Partitions, p)
+			}
+		}
+
+		topicMeta := *meta
+		topicMeta.Topics = []string{t}
+
+		fullPath := fmt.Sprintf("%s%s-rollback", outPath, t)
+		if err := writeMapWithMetadata(tm, &topicMeta, fullPath); err != nil {
+			fmt.Printf("%s%s\n", indent, err)
+		} else {
+			fmt.Printf("%s%s.json\n", indent, fullPath)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+var testRollbackMapString = `{"version":1,"partitions":[
+	{"topic":"test_topic","partition":0,"replicas":[1001,1002]},
+	{"topic":"test_topic","partition":1,"replicas":[1002,1001]},
+	{"topic":"test_topic","partition":2,"replicas":[1003,1004,1001]},
+	{"topic":"test_topic","partition":3,"replicas":[1004,1003,1002]}]}`
+
+func TestRollbackMap(t *testing.T) {
+	pm1, _ := kafkazk.PartitionMapFromString(testRollbackMapString)
+	pm2 := pm1.Copy()
+
+	pm2.Partitions[0].Replicas = []int{1003, 1002}
+	pm2.Partitions[3].Replicas = []int{1004, 1001, 1002}
+
+	rollback := rollbackMap(pm1, pm2)
+
+	if len(rollback.Partitions) != 2 {
+		t.Fatalf("Expected 2 partitions, got %d", len(rollback.Partitions))
+	}
+
+	for i, n := range []int{0, 3} {
+		if !rollback.Partitions[i].Equal(pm1.Partitions[n]) {
+			t.Errorf("Expected partition %v, got %v", pm1.Partitions[n], rollback.Partitions[i])
+		}
+	}
+}
+
+func TestWriteMapWithMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "topicmappr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	pm, _ := kafkazk.PartitionMapFromString(testRollbackMapString)
+	meta := newMapMetadata(rebalanceCmd, "rollback", pm)
+
+	path := filepath.Join(dir, "test_topic-rollback")
+	if err := writeMapWithMetadata(pm, meta, path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The header is ignored when reading
+	// the file as a partition map.
+	pm2, err := kafkazk.PartitionMapFromString(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if same, _ := pm.Equal(pm2); !same {
+		t.Errorf("Unexpected map inequality")
+	}
+
+	m := readMapMetadata(data)
+	if m == nil {
+		t.Fatal("Expected map metadata")
+	}
+
+	if m.Type != "rollback" || m.Command != "rebalance" || m.Version != version {
+		t.Errorf("Unexpected metadata %+v", m)
+	}
+
+	if len(m.Topics) != 1 || m.Topics[0] != "test_topic" {
+		t.Errorf("Expected topics [test_topic], got %v", m.Topics)
+	}
+
+	// Maps without a header.
+	if m := readMapMetadata([]byte(testRollbackMapString)); m != nil {
+		t.Errorf("Expected nil metadata, got %+v", m)
+	}
+}
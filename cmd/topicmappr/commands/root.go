@@ -25,5 +25,6 @@ func Execute() {
 func init() {
 	rootCmd.PersistentFlags().String("zk-addr", "localhost:2181", "ZooKeeper connect string")
 	rootCmd.PersistentFlags().String("zk-prefix", "", "ZooKeeper prefix (if Kafka is configured with a chroot path prefix)")
+	rootCmd.PersistentFlags().String("snapshot", "", "Read cluster state from a snapshot file written by 'topicmappr snapshot' in place of ZooKeeper (overrides --zk-addr)")
 	rootCmd.PersistentFlags().Bool("ignore-warns", false, "Produce a map even if warnings are encountered")
 }
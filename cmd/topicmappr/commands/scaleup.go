@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+var scaleUpCmd = &cobra.Command{
+	Use:   "scale-up",
+	Short: "Fill newly added brokers by relocating the minimum data needed",
+	Long: `scale-up takes a list of newly added broker IDs via the --brokers parameter
+and relocates individual replicas from the most loaded existing brokers onto them.
+Relocations stop once the new brokers reach the cluster mean (within --tolerance)
+by either storage utilization or replica count, as selected via --balance. Unlike
+a rebuild with --force-rebuild, no replicas are moved between existing brokers.`,
+	Run: scaleUp,
+}
+
+func init() {
+	rootCmd.AddCommand(scaleUpCmd)
+
+	scaleUpCmd.Flags().String("brokers", "", "Newly added broker IDs to fill (comma delim. list)")
+	scaleUpCmd.Flags().String("topics", "", "Limit the scope to topics (comma delim. list) by lookup in ZooKeeper (default: all topics)")
+	scaleUpCmd.Flags().String("balance", "storage", "Balance target for new brokers: [count, storage]")
+	scaleUpCmd.Flags().Float64("tolerance", 0.10, "Percent distance from the mean considered balanced")
+	scaleUpCmd.Flags().Int("partition-limit", 30, "Limit the number of partitions eligible for relocation per source broker")
+	scaleUpCmd.Flags().String("out-path", "", "Path to write output map files to")
+	scaleUpCmd.Flags().String("out-file", "", "If defined, write a combined map of all topics to a file")
+	scaleUpCmd.Flags().Bool("verbose", false, "Verbose output")
+	scaleUpCmd.Flags().String("zk-metrics-prefix", "topicmappr", "ZooKeeper namespace prefix for Kafka metrics")
+	scaleUpCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes)")
+
+	// Required.
+	scaleUpCmd.MarkFlagRequired("brokers")
+}
+
+func scaleUp(cmd *cobra.Command, _ []string) {
+	balance := cmd.Flag("balance").Value.String()
+	tol, _ := cmd.Flags().GetFloat64("tolerance")
+	limit, _ := cmd.Flags().GetInt("partition-limit")
+
+	switch {
+	case balance != "count" && balance != "storage":
+		fmt.Println("\n[ERROR] --balance must be either 'count' or 'storage'")
+		defaultsAndExit()
+	case tol < 0 || tol >= 1:
+		fmt.Println("\n[ERROR] --tolerance must be >= 0 and < 1")
+		defaultsAndExit()
+	case limit <= 0:
+		fmt.Println("\n[ERROR] --partition-limit must be greater than 0")
+		defaultsAndExit()
+	}
+
+	bootstrap(cmd)
+
+	targets := map[int]struct{}{}
+	for _, id := range Config.brokers {
+		targets[id] = struct{}{}
+	}
+
+	// Default to all topics.
+	if len(Config.topics) == 0 {
+		Config.topics = []*regexp.Regexp{regexp.MustCompile(".*")}
+	}
+
+	// ZooKeeper init.
+	zk, err := initZooKeeper(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	defer zk.Close()
+
+	// Get broker and partition metadata.
+	checkMetaAge(cmd, zk)
+	brokerMeta := getBrokerMeta(cmd, zk, true)
+	partitionMeta := getPartitionMeta(cmd, zk)
+
+	// Get the current partition map.
+	partitionMapIn, err := kafkazk.PartitionMapFromZK(Config.topics, zk)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Exclude any topics that are pending deletion.
+	pending := stripPendingDeletes(partitionMapIn, zk)
+
+	printTopics(partitionMapIn)
+	printExcludedTopics(pending)
+
+	// Get a broker map of all existing brokers
+	// plus the new brokers.
+	brokersIn := kafkazk.BrokerMapFromPartitionMap(partitionMapIn, brokerMeta, false)
+	validateBrokersForScaleUp(cmd, brokersIn, brokerMeta)
+
+	verbose, _ := cmd.Flags().GetBool("verbose")
+
+	params := scaleUpParams{
+		balance:        balance,
+		tolerance:      tol,
+		partitionLimit: limit,
+		targets:        targets,
+		verbose:        verbose,
+	}
+
+	brokersOut := brokersIn.Copy()
+	relos, plan := planScaleUp(partitionMapIn, brokersOut, partitionMeta, params)
+
+	partitionMapOut := partitionMapIn.Copy()
+	applyRelocationPlan(cmd, partitionMapOut, plan)
+
+	// Get the source broker IDs.
+	sources := []int{}
+	for id := range relos {
+		sources = append(sources, id)
+	}
+
+	sort.Ints(sources)
+
+	// Print planned relocations.
+	printPlannedRelocations(sources, relos, nil, partitionMeta)
+
+	// Print map change results.
+	printMapChanges(partitionMapIn, partitionMapOut)
+
+	// Print broker assignment statistics and
+	// handle any warnings.
+	handleOverridableErrs(cmd, scaleUpWarnings(cmd, partitionMapIn, partitionMapOut, brokersIn, brokersOut, params))
+
+	_, partitionMapOut = skipReassignmentNoOps(partitionMapIn, partitionMapOut)
+
+	writeMaps(cmd, partitionMapOut, nil, partitionMapIn)
+}
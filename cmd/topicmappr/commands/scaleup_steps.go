@@ -0,0 +1,297 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+// scaleUpParams holds parameters for planning a scale-up.
+type scaleUpParams struct {
+	// Balance by "count" or "storage".
+	balance        string
+	tolerance      float64
+	partitionLimit int
+	// New broker IDs to fill.
+	targets map[int]struct{}
+	verbose bool
+}
+
+// load returns the load of a broker in the units of the configured
+// balance target: replica counts or bytes used (expressed as negative
+// storage free so that greater values are more loaded).
+func (p scaleUpParams) load(b *kafkazk.Broker) float64 {
+	if p.balance == "count" {
+		return float64(b.Used)
+	}
+
+	return -b.StorageFree
+}
+
+// meanLoad returns the mean load for all brokers in the BrokerMap.
+func (p scaleUpParams) meanLoad(bm kafkazk.BrokerMap) float64 {
+	var t, c float64
+
+	for _, b := range bm {
+		if b.ID == kafkazk.StubBrokerID {
+			continue
+		}
+		c++
+		t += p.load(b)
+	}
+
+	return t / c
+}
+
+// unit returns the change in load when relocating the partition.
+func (p scaleUpParams) unit(partn kafkazk.Partition, pmm kafkazk.PartitionMetaMap) float64 {
+	if p.balance == "count" {
+		return 1
+	}
+
+	size, _ := pmm.Size(partn)
+	return size
+}
+
+// validateBrokersForScaleUp ensures that only broker additions are
+// being requested and that all new brokers are registered.
+func validateBrokersForScaleUp(cmd *cobra.Command, brokers kafkazk.BrokerMap, bm kafkazk.BrokerMetaMap) {
+	fmt.Println("\nValidating broker list:")
+
+	// All existing brokers plus the new brokers.
+	c, msgs := brokers.Update(append([]int{-1}, Config.brokers...), bm)
+	for m := range msgs {
+		fmt.Printf("%s%s\n", indent, m)
+	}
+
+	if c.Changes() {
+		fmt.Printf("%s-\n", indent)
+	}
+
+	// Check if any referenced brokers are marked as having
+	// missing/partial metrics data.
+	ensureBrokerMetrics(cmd, brokers, bm)
+
+	switch {
+	case c.Missing > 0, c.OldMissing > 0, c.Replace > 0:
+		fmt.Printf("%s[ERROR] scale-up only allows broker additions\n", indent)
+		os.Exit(1)
+	default:
+		fmt.Printf("%sOK\n", indent)
+	}
+}
+
+// planScaleUp takes a *PartitionMap, BrokerMap, PartitionMetaMap and
+// scaleUpParams. Individual replicas are planned for relocation from the
+// most loaded existing brokers to the target brokers until each target
+// is within the tolerance of the mean load. Relocations that would push
+// either the source or destination beyond the tolerance are skipped,
+// avoiding data movement beyond what's needed to balance the target
+// brokers. The BrokerMap is updated in place to reflect the planned
+// relocations. Relocations are returned keyed by source broker ID along
+// with the relocationPlan.
+func planScaleUp(pm *kafkazk.PartitionMap, bm kafkazk.BrokerMap, pmm kafkazk.PartitionMetaMap, p scaleUpParams) (map[int][]relocation, relocationPlan) {
+	relos := map[int][]relocation{}
+	plan := relocationPlan{}
+	mappings := pm.Copy().Mappings()
+
+	mean := p.meanLoad(bm)
+	tolLoad := mean * p.tolerance
+	if tolLoad < 0 {
+		tolLoad = -tolLoad
+	}
+
+	exhausted := map[int]struct{}{}
+
+	for {
+		// Get the targets still below the mean.
+		var targets kafkazk.BrokerList
+		for id := range p.targets {
+			if b, exist := bm[id]; exist && mean-p.load(b) > tolLoad {
+				targets = append(targets, b)
+			}
+		}
+
+		if len(targets) == 0 {
+			break
+		}
+
+		// Get the most loaded source above the mean.
+		var source *kafkazk.Broker
+		for _, b := range bm {
+			if _, isTarget := p.targets[b.ID]; isTarget || b.ID == kafkazk.StubBrokerID {
+				continue
+			}
+
+			if _, done := exhausted[b.ID]; done || p.load(b) <= mean {
+				continue
+			}
+
+			if source == nil || p.load(b) > p.load(source) ||
+				(p.load(b) == p.load(source) && b.ID < source.ID) {
+				source = b
+			}
+		}
+
+		if source == nil {
+			break
+		}
+
+		if !planScaleUpForBroker(source, targets, mappings, bm, pmm, plan, relos, mean, tolLoad, p) {
+			exhausted[source.ID] = struct{}{}
+		}
+	}
+
+	return relos, plan
+}
+
+// planScaleUpForBroker attempts to plan a single relocation from the source
+// broker to any of the target brokers. Whether a relocation was planned
+// is returned.
+func planScaleUpForBroker(source *kafkazk.Broker, targets kafkazk.BrokerList, mappings kafkazk.Mappings, bm kafkazk.BrokerMap, pmm kafkazk.PartitionMetaMap, plan relocationPlan, relos map[int][]relocation, mean, tolLoad float64, p scaleUpParams) bool {
+	// Get all partitions held by the source broker, largest first.
+	var held int
+	for _, pl := range mappings[source.ID] {
+		held += len(pl)
+	}
+
+	candidates, err := mappings.LargestPartitions(source.ID, held, pmm)
+	if err != nil {
+		return false
+	}
+
+	// When balancing by count, relocating the smallest
+	// partitions results in the least data moved.
+	if p.balance == "count" {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			si, _ := pmm.Size(candidates[i])
+			sj, _ := pmm.Size(candidates[j])
+			return si < sj
+		})
+	}
+
+	if len(candidates) > p.partitionLimit {
+		candidates = candidates[:p.partitionLimit]
+	}
+
+	for _, partn := range candidates {
+		u := p.unit(partn, pmm)
+		if u <= 0 {
+			continue
+		}
+
+		// Skip if the source would fall below the mean
+		// by more than the tolerance.
+		if p.load(source)-u < mean-tolLoad {
+			continue
+		}
+
+		// Get targets that wouldn't exceed the mean
+		// by more than the tolerance.
+		var eligible kafkazk.BrokerList
+		for _, b := range targets {
+			if p.load(b)+u <= mean+tolLoad {
+				eligible = append(eligible, b)
+			}
+		}
+
+		if len(eligible) == 0 {
+			continue
+		}
+
+		// Get constraints for all brokers in the
+		// partition replica set, excluding the
+		// source broker, along with any brokers
+		// already scheduled to receive this partition.
+		replicaSet := kafkazk.BrokerList{}
+		for _, id := range partn.Replicas {
+			if id != source.ID {
+				replicaSet = append(replicaSet, bm[id])
+			}
+		}
+
+		if pairs, planned := plan.isPlanned(partn); planned {
+			for _, pair := range pairs {
+				replicaSet = append(replicaSet, bm[pair[1]])
+			}
+		}
+
+		c := kafkazk.NewConstraints()
+		c.MergeConstraints(replicaSet)
+
+		size, _ := pmm.Size(partn)
+
+		dest, err := c.SelectBroker(eligible, kafkazk.ConstraintsParams{
+			SelectorMethod: p.balance,
+			RequestSize:    size,
+			SeedVal:        int64(partn.Partition + 1),
+		})
+
+		if err != nil {
+			if p.verbose {
+				fmt.Printf("%s%s p%d: no eligible destination from broker %d\n",
+					indent, partn.Topic, partn.Partition, source.ID)
+			}
+			continue
+		}
+
+		// SelectBroker accounts the destination;
+		// update the source.
+		source.StorageFree += size
+		source.Used--
+
+		relos[source.ID] = append(relos[source.ID], relocation{partition: partn, destination: dest.ID})
+		plan.add(partn, [2]int{source.ID, dest.ID})
+		mappings.Remove(source.ID, partn)
+
+		if p.verbose {
+			fmt.Printf("%sPlanning %s p%d: %d -> %d\n",
+				indent, partn.Topic, partn.Partition, source.ID, dest.ID)
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// scaleUpWarnings prints the broker assignment statistics of a planned
+// scale-up and returns the overridable errors: any from the statistics
+// along with any new brokers that couldn't be filled.
+func scaleUpWarnings(cmd *cobra.Command, pm1, pm2 *kafkazk.PartitionMap, bm1, bm2 kafkazk.BrokerMap, p scaleUpParams) errors {
+	errs := printBrokerAssignmentStats(cmd, pm1, pm2, bm1, bm2)
+
+	// Report any new brokers that couldn't be filled.
+	return append(errs, unbalancedTargets(bm2, p)...)
+}
+
+// unbalancedTargets returns an error for each target broker
+// that remains below the mean load beyond the tolerance.
+func unbalancedTargets(bm kafkazk.BrokerMap, p scaleUpParams) errors {
+	var errs errors
+
+	mean := p.meanLoad(bm)
+	tolLoad := mean * p.tolerance
+	if tolLoad < 0 {
+		tolLoad = -tolLoad
+	}
+
+	ids := []int{}
+	for id := range p.targets {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		if b, exist := bm[id]; exist && mean-p.load(b) > tolLoad {
+			errs = append(errs, fmt.Errorf("broker %d could not be balanced within the %.2f%% tolerance", id, p.tolerance*100))
+		}
+	}
+
+	return errs
+}
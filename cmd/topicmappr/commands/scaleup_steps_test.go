@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func TestPlanScaleUp(t *testing.T) {
+	pm, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":1,"replicas":[1002,1001]},
+		{"topic":"test_topic","partition":2,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":3,"replicas":[1002,1001]}]}`)
+
+	pmm := kafkazk.NewPartitionMetaMap()
+	pmm["test_topic"] = map[int]*kafkazk.PartitionMeta{
+		0: &kafkazk.PartitionMeta{Size: 100},
+		1: &kafkazk.PartitionMeta{Size: 200},
+		2: &kafkazk.PartitionMeta{Size: 300},
+		3: &kafkazk.PartitionMeta{Size: 400},
+	}
+
+	bm := kafkazk.BrokerMapFromPartitionMap(pm, kafkazk.BrokerMetaMap{}, false)
+	bm[1001].StorageFree, bm[1001].Locality = 1000, "a"
+	bm[1002].StorageFree, bm[1002].Locality = 1000, "b"
+	bm[1003] = &kafkazk.Broker{ID: 1003, StorageFree: 2000, Locality: "c", New: true}
+
+	params := scaleUpParams{
+		balance:        "count",
+		tolerance:      0.00,
+		partitionLimit: 10,
+		targets:        map[int]struct{}{1003: struct{}{}},
+	}
+
+	bmCount := bm.Copy()
+	relos, plan := planScaleUp(pm, bmCount, pmm, params)
+
+	// 8 replicas across 3 brokers; the target
+	// should be filled to the mean of 2.67 using
+	// the smallest partitions.
+	var planned int
+	for _, r := range relos {
+		planned += len(r)
+	}
+
+	if planned != 2 {
+		t.Errorf("Expected 2 relocations, got %d", planned)
+	}
+
+	for _, partn := range []kafkazk.Partition{pm.Partitions[0], pm.Partitions[1]} {
+		if _, exist := plan.isPlanned(partn); !exist {
+			t.Errorf("Expected relocation for %s p%d", partn.Topic, partn.Partition)
+		}
+	}
+
+	if bmCount[1003].Used != 2 {
+		t.Errorf("Expected broker 1003 used count of 2, got %d", bmCount[1003].Used)
+	}
+
+	// Balance by storage. Brokers 1001 and 1002 each
+	// have 1000 bytes free and 1003 2000; the mean is
+	// 1333.33. The target can take at most 666.67 bytes
+	// without exceeding the mean.
+	params.balance = "storage"
+	params.tolerance = 0.10
+
+	bmStorage := bm.Copy()
+	relos, _ = planScaleUp(pm, bmStorage, pmm, params)
+
+	var moved float64
+	for _, r := range relos {
+		for _, relo := range r {
+			s, _ := pmm.Size(relo.partition)
+			moved += s
+			if relo.destination != 1003 {
+				t.Errorf("Expected destination 1003, got %d", relo.destination)
+			}
+		}
+	}
+
+	if moved == 0 || 2000-moved < 1333.33*0.90 {
+		t.Errorf("Unexpected relocation volume %.2f", moved)
+	}
+}
+
+func TestScaleUpWarnings(t *testing.T) {
+	pm, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":1,"replicas":[1002,1001]},
+		{"topic":"test_topic","partition":2,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":3,"replicas":[1002,1001]}]}`)
+
+	pmm := kafkazk.NewPartitionMetaMap()
+	pmm["test_topic"] = map[int]*kafkazk.PartitionMeta{
+		0: &kafkazk.PartitionMeta{Size: 100 * div},
+		1: &kafkazk.PartitionMeta{Size: 100 * div},
+		2: &kafkazk.PartitionMeta{Size: 100 * div},
+		3: &kafkazk.PartitionMeta{Size: 100 * div},
+	}
+
+	// Two new, empty brokers.
+	bm := kafkazk.BrokerMapFromPartitionMap(pm, kafkazk.BrokerMetaMap{}, false)
+	bm[1001].StorageFree, bm[1001].Locality = 100*div, "a"
+	bm[1002].StorageFree, bm[1002].Locality = 120*div, "b"
+	bm[1003] = &kafkazk.Broker{ID: 1003, StorageFree: 500 * div, Locality: "a", New: true}
+	bm[1004] = &kafkazk.Broker{ID: 1004, StorageFree: 540 * div, Locality: "b", New: true}
+
+	params := scaleUpParams{
+		balance:        "storage",
+		tolerance:      0.10,
+		partitionLimit: 10,
+		targets:        map[int]struct{}{1003: struct{}{}, 1004: struct{}{}},
+	}
+
+	bmOut := bm.Copy()
+	_, plan := planScaleUp(pm, bmOut, pmm, params)
+
+	pmOut := pm.Copy()
+	applyRelocationPlan(scaleUpCmd, pmOut, plan)
+
+	// A balanced scale-up has no warnings, so maps
+	// are written without --ignore-warns.
+	if errs := scaleUpWarnings(scaleUpCmd, pm, pmOut, bm, bmOut, params); len(errs) != 0 {
+		t.Errorf("Expected no warnings, got %v", errs)
+	}
+}
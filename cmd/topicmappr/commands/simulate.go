@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Simulate the impact of broker or rack failures on the current partition maps",
+	Long: `simulate reads the current state of all topics (or those matching the optional
+--topics parameter) from ZooKeeper and reports the impact of losing the brokers
+specified via --fail-brokers and/or all brokers with the rack ID specified via
+--fail-rack: partitions that go offline, partitions that lose their leader along with
+the new leader under the preferred replica order, partitions that fall below the topic
+min.insync.replicas and the resulting partition leadership per broker. Offline
+partitions exclude unclean leader elections. No changes are made. simulate exits 1 if
+any partitions go offline or fall below min.insync.replicas.`,
+	Run: simulate,
+}
+
+func init() {
+	rootCmd.AddCommand(simulateCmd)
+
+	simulateCmd.Flags().String("topics", "", "Limit the scope to topics (comma delim. list) by lookup in ZooKeeper (default: all topics)")
+	simulateCmd.Flags().String("fail-brokers", "", "Broker IDs to fail (comma delim. list)")
+	simulateCmd.Flags().String("fail-rack", "", "Fail all brokers with this rack ID")
+	simulateCmd.Flags().Int("min-insync-replicas", 1, "min.insync.replicas for topics without a configured value (the broker default)")
+}
+
+func simulate(cmd *cobra.Command, _ []string) {
+	fb := cmd.Flag("fail-brokers").Value.String()
+	rack := cmd.Flag("fail-rack").Value.String()
+	defMinISR, _ := cmd.Flags().GetInt("min-insync-replicas")
+
+	switch {
+	case fb == "" && rack == "":
+		fmt.Println("\n[ERROR] must specify either --fail-brokers or --fail-rack")
+		defaultsAndExit()
+	case defMinISR < 1:
+		fmt.Println("\n[ERROR] --min-insync-replicas must be > 0")
+		defaultsAndExit()
+	}
+
+	bootstrap(cmd)
+
+	var ids []int
+	if fb != "" {
+		ids = brokerStringToSlice(fb)
+	}
+
+	// Default to all topics.
+	if len(Config.topics) == 0 {
+		Config.topics = []*regexp.Regexp{regexp.MustCompile(".*")}
+	}
+
+	// ZooKeeper init.
+	zk, err := initZooKeeper(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	defer zk.Close()
+
+	brokerMeta := getBrokerMeta(cmd, zk, false)
+
+	failed, err := failedBrokers(ids, rack, brokerMeta)
+	if err != nil {
+		fmt.Printf("\n[ERROR] %s\n", err)
+		os.Exit(1)
+	}
+
+	// Get the current partition map.
+	partitionMap, err := kafkazk.PartitionMapFromZK(Config.topics, zk)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Exclude any topics that are pending deletion.
+	pending := stripPendingDeletes(partitionMap, zk)
+
+	printTopics(partitionMap)
+	printExcludedTopics(pending)
+
+	// Get the current partition states
+	// and min.insync.replicas configs.
+	topics := partitionMap.Topics()
+	states := map[string]kafkazk.TopicStateISR{}
+	for _, topic := range topics {
+		states[topic], err = zk.GetTopicStateISR(topic)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	minISR, err := minInsyncReplicas(zk, topics, defMinISR)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	failedIDs := []int{}
+	for id := range failed {
+		failedIDs = append(failedIDs, id)
+	}
+	sort.Ints(failedIDs)
+
+	fmt.Println("\nFailed brokers:")
+	for _, id := range failedIDs {
+		fmt.Printf("%s%d (rack: %s)\n", indent, id, brokerMeta[id].Rack)
+	}
+
+	impacts := simulateFailure(partitionMap, states, failed, minISR)
+	before, after := leadershipDistribution(partitionMap, states, impacts)
+
+	printSimulateResults(impacts, before, after, failed)
+
+	for _, i := range impacts {
+		if i.offline() || i.underMinISR() {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+// partitionImpact describes the effect of
+// simulated broker failures on a partition.
+type partitionImpact struct {
+	partition kafkazk.Partition
+	// The leader before and after the failures;
+	// newLeader is -1 if the partition is offline.
+	leader    int
+	newLeader int
+	// The ISR before and after the failures.
+	isr    []int
+	newISR []int
+	// Replicas that remain available but
+	// aren't in the ISR.
+	outOfSync []int
+	minISR    int
+}
+
+func (p partitionImpact) offline() bool {
+	return p.newLeader < 0
+}
+
+func (p partitionImpact) underMinISR() bool {
+	return !p.offline() && len(p.newISR) < p.minISR
+}
+
+// failedBrokers takes a list of broker IDs, a rack ID and a BrokerMetaMap
+// and returns the set of all brokers listed or located in the rack. An
+// error is returned if a listed broker isn't registered or if no
+// brokers are located in the rack.
+func failedBrokers(ids []int, rack string, bmm kafkazk.BrokerMetaMap) (map[int]bool, error) {
+	failed := map[int]bool{}
+
+	for _, id := range ids {
+		if _, exist := bmm[id]; !exist {
+			return nil, fmt.Errorf("broker %d is not registered", id)
+		}
+		failed[id] = true
+	}
+
+	if rack != "" {
+		var found bool
+		for id, m := range bmm {
+			if m.Rack == rack {
+				failed[id] = true
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("no brokers found with rack ID %s", rack)
+		}
+	}
+
+	return failed, nil
+}
+
+// minInsyncReplicas takes a kafkazk.Handler, a list of topics and a
+// default and returns the min.insync.replicas for each topic. Topics
+// without a min.insync.replicas config are assigned the default.
+func minInsyncReplicas(zk kafkazk.Handler, topics []string, def int) (map[string]int, error) {
+	minISR := map[string]int{}
+
+	for _, t := range topics {
+		minISR[t] = def
+
+		config, err := zk.GetTopicConfig(t)
+		if err != nil {
+			// Topics that never had configs
+			// applied may not have a config.
+			if _, ok := err.(kafkazk.ErrNoNode); ok {
+				continue
+			}
+			return nil, err
+		}
+
+		v, exist := config.Config["min.insync.replicas"]
+		if !exist {
+			continue
+		}
+
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid min.insync.replicas %s", t, v)
+		}
+
+		minISR[t] = n
+	}
+
+	return minISR, nil
+}
+
+// simulateFailure takes a *PartitionMap, the current TopicStateISR of each
+// topic, a set of failed brokers and the min.insync.replicas of each topic
+// and returns a partitionImpact for each partition with a replica on a
+// failed broker. Partitions without a state are assumed to have the
+// preferred leader and a full ISR. A new leader is elected as the first
+// replica in the preferred order that remains in the ISR; partitions with
+// no remaining ISR are offline (excluding unclean leader elections).
+func simulateFailure(pm *kafkazk.PartitionMap, states map[string]kafkazk.TopicStateISR, failed map[int]bool, minISR map[string]int) []partitionImpact {
+	var impacts []partitionImpact
+
+	for _, partn := range pm.Partitions {
+		var affected bool
+		for _, id := range partn.Replicas {
+			if failed[id] {
+				affected = true
+				break
+			}
+		}
+
+		if !affected || len(partn.Replicas) == 0 {
+			continue
+		}
+
+		impact := partitionImpact{
+			partition: partn,
+			leader:    partn.Replicas[0],
+			isr:       partn.Replicas,
+			minISR:    minISR[partn.Topic],
+		}
+
+		if state, exist := states[partn.Topic][strconv.Itoa(partn.Partition)]; exist {
+			impact.leader, impact.isr = state.Leader, state.ISR
+		}
+
+		inISR := map[int]bool{}
+		for _, id := range impact.isr {
+			if !failed[id] {
+				impact.newISR = append(impact.newISR, id)
+				inISR[id] = true
+			}
+		}
+
+		impact.newLeader = -1
+		if impact.leader >= 0 && !failed[impact.leader] && inISR[impact.leader] {
+			impact.newLeader = impact.leader
+		}
+
+		for _, id := range partn.Replicas {
+			if impact.newLeader < 0 && inISR[id] {
+				impact.newLeader = id
+			}
+			if !failed[id] && !inISR[id] {
+				impact.outOfSync = append(impact.outOfSync, id)
+			}
+		}
+
+		impacts = append(impacts, impact)
+	}
+
+	return impacts
+}
+
+// leadershipDistribution takes a *PartitionMap, the current TopicStateISR
+// of each topic and a []partitionImpact and returns the count of partitions
+// led by each broker before and after the simulated failures.
+func leadershipDistribution(pm *kafkazk.PartitionMap, states map[string]kafkazk.TopicStateISR, impacts []partitionImpact) (map[int]int, map[int]int) {
+	before, after := map[int]int{}, map[int]int{}
+
+	newLeaders := map[string]map[int]int{}
+	for _, i := range impacts {
+		if _, exist := newLeaders[i.partition.Topic]; !exist {
+			newLeaders[i.partition.Topic] = map[int]int{}
+		}
+		newLeaders[i.partition.Topic][i.partition.Partition] = i.newLeader
+	}
+
+	for _, partn := range pm.Partitions {
+		if len(partn.Replicas) == 0 {
+			continue
+		}
+
+		// Include brokers that lead no partitions.
+		for _, id := range partn.Replicas {
+			before[id] += 0
+			after[id] += 0
+		}
+
+		leader := partn.Replicas[0]
+		if state, exist := states[partn.Topic][strconv.Itoa(partn.Partition)]; exist {
+			leader = state.Leader
+		}
+
+		if leader >= 0 {
+			before[leader]++
+		}
+
+		if l, exist := newLeaders[partn.Topic][partn.Partition]; exist {
+			leader = l
+		}
+
+		if leader >= 0 {
+			after[leader]++
+		}
+	}
+
+	return before, after
+}
+
+// printSimulateResults prints the results of a failure simulation.
+func printSimulateResults(impacts []partitionImpact, before, after map[int]int, failed map[int]bool) {
+	var offline, underMinISR, leaderChanges []partitionImpact
+	for _, i := range impacts {
+		switch {
+		case i.offline():
+			offline = append(offline, i)
+		case i.newLeader != i.leader:
+			leaderChanges = append(leaderChanges, i)
+		}
+
+		if i.underMinISR() {
+			underMinISR = append(underMinISR, i)
+		}
+	}
+
+	fmt.Println("\nOffline partitions:")
+	if len(offline) == 0 {
+		fmt.Printf("%s[none]\n", indent)
+	}
+	for _, i := range offline {
+		fmt.Printf("%s%s p%d: replicas %v, ISR %v",
+			indent, i.partition.Topic, i.partition.Partition, i.partition.Replicas, i.isr)
+		if len(i.outOfSync) > 0 {
+			fmt.Printf(" (out-of-sync replicas available: %v)", i.outOfSync)
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("\nLeadership changes:")
+	if len(leaderChanges) == 0 {
+		fmt.Printf("%s[none]\n", indent)
+	}
+	for _, i := range leaderChanges {
+		fmt.Printf("%s%s p%d: %d -> %d\n",
+			indent, i.partition.Topic, i.partition.Partition, i.leader, i.newLeader)
+	}
+
+	fmt.Println("\nPartitions under min.insync.replicas:")
+	if len(underMinISR) == 0 {
+		fmt.Printf("%s[none]\n", indent)
+	}
+	for _, i := range underMinISR {
+		fmt.Printf("%s%s p%d: ISR %v -> %v, min.insync.replicas %d\n",
+			indent, i.partition.Topic, i.partition.Partition, i.isr, i.newISR, i.minISR)
+	}
+
+	ids := []int{}
+	for id := range before {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	fmt.Println("\nLeadership distribution:")
+	for _, id := range ids {
+		var note string
+		if failed[id] {
+			note = " [failed]"
+		}
+		fmt.Printf("%sBroker %d: %d -> %d%s\n", indent, id, before[id], after[id], note)
+	}
+
+	fmt.Println("\nSummary:")
+	fmt.Printf("%spartitions affected: %d\n", indent, len(impacts))
+	fmt.Printf("%spartitions offline: %d\n", indent, len(offline))
+	fmt.Printf("%sleadership changes: %d\n", indent, len(leaderChanges))
+	fmt.Printf("%spartitions under min.insync.replicas: %d\n", indent, len(underMinISR))
+}
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func testSimulateInput() (*kafkazk.PartitionMap, map[string]kafkazk.TopicStateISR) {
+	pm, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002,1003]},
+		{"topic":"test_topic","partition":1,"replicas":[1002,1003,1001]},
+		{"topic":"test_topic","partition":2,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":3,"replicas":[1003,1004]}]}`)
+
+	states := map[string]kafkazk.TopicStateISR{
+		"test_topic": kafkazk.TopicStateISR{
+			"0": kafkazk.PartitionState{Leader: 1001, ISR: []int{1001, 1002, 1003}},
+			"1": kafkazk.PartitionState{Leader: 1002, ISR: []int{1002, 1001}},
+			"2": kafkazk.PartitionState{Leader: 1001, ISR: []int{1001}},
+			// p3 has no state.
+		},
+	}
+
+	return pm, states
+}
+
+func TestFailedBrokers(t *testing.T) {
+	bmm := kafkazk.BrokerMetaMap{
+		1001: &kafkazk.BrokerMeta{Rack: "a"},
+		1002: &kafkazk.BrokerMeta{Rack: "b"},
+		1003: &kafkazk.BrokerMeta{Rack: "a"},
+	}
+
+	failed, err := failedBrokers([]int{1002}, "a", bmm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(failed) != 3 {
+		t.Errorf("Expected 3 failed brokers, got %v", failed)
+	}
+
+	if _, err := failedBrokers([]int{1004}, "", bmm); err == nil {
+		t.Error("Expected error for unregistered broker")
+	}
+
+	if _, err := failedBrokers(nil, "c", bmm); err == nil {
+		t.Error("Expected error for unknown rack")
+	}
+}
+
+func TestMinInsyncReplicas(t *testing.T) {
+	zk := &kafkazk.Mock{}
+
+	// The mock config has no min.insync.replicas.
+	minISR, err := minInsyncReplicas(zk, []string{"test_topic"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if minISR["test_topic"] != 2 {
+		t.Errorf("Expected min.insync.replicas 2, got %d", minISR["test_topic"])
+	}
+}
+
+func TestSimulateFailure(t *testing.T) {
+	pm, states := testSimulateInput()
+	failed := map[int]bool{1001: true}
+	minISR := map[string]int{"test_topic": 2}
+
+	impacts := simulateFailure(pm, states, failed, minISR)
+
+	// p3 doesn't reference 1001.
+	if len(impacts) != 3 {
+		t.Fatalf("Expected 3 impacted partitions, got %d", len(impacts))
+	}
+
+	// p0: leadership moves to 1002 with an ISR of 2.
+	if i := impacts[0]; i.newLeader != 1002 || i.offline() || i.underMinISR() {
+		t.Errorf("Unexpected p0 impact %+v", i)
+	}
+
+	// p1: leadership is unchanged; the ISR falls below 2.
+	if i := impacts[1]; i.newLeader != 1002 || !i.underMinISR() {
+		t.Errorf("Unexpected p1 impact %+v", i)
+	}
+
+	// p2: offline; 1002 is alive but out of sync.
+	i := impacts[2]
+	if !i.offline() || i.underMinISR() {
+		t.Errorf("Expected p2 offline, got %+v", i)
+	}
+
+	if len(i.outOfSync) != 1 || i.outOfSync[0] != 1002 {
+		t.Errorf("Expected out-of-sync replicas [1002], got %v", i.outOfSync)
+	}
+}
+
+func TestLeadershipDistribution(t *testing.T) {
+	pm, states := testSimulateInput()
+	failed := map[int]bool{1001: true}
+
+	impacts := simulateFailure(pm, states, failed, map[string]int{})
+	before, after := leadershipDistribution(pm, states, impacts)
+
+	expectedBefore := map[int]int{1001: 2, 1002: 1, 1003: 1, 1004: 0}
+	expectedAfter := map[int]int{1001: 0, 1002: 2, 1003: 1, 1004: 0}
+
+	for id, n := range expectedBefore {
+		if before[id] != n {
+			t.Errorf("Expected broker %d to lead %d before, got %d", id, n, before[id])
+		}
+	}
+
+	for id, n := range expectedAfter {
+		if after[id] != n {
+			t.Errorf("Expected broker %d to lead %d after, got %d", id, n, after[id])
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Write a snapshot of the cluster state for offline use",
+	Long: `snapshot reads all registered brokers, topic and partition states, topic
+configs and any topicmappr broker metrics and partition metadata from ZooKeeper
+and writes them to a JSON file. The file can be passed to any topicmappr command
+via the --snapshot global flag in place of --zk-addr to plan without ZooKeeper
+access. Commands that write to ZooKeeper (such as execute) fail when reading from
+a snapshot.`,
+	Run: snapshot,
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+
+	snapshotCmd.Flags().String("out-file", "snapshot.json", "Path to write the snapshot file to")
+	snapshotCmd.Flags().String("zk-metrics-prefix", "topicmappr", "ZooKeeper namespace prefix for Kafka metrics")
+}
+
+func snapshot(cmd *cobra.Command, _ []string) {
+	// ZooKeeper init.
+	zk, err := initZooKeeper(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	defer zk.Close()
+
+	s, err := kafkazk.TakeSnapshot(zk)
+	if err != nil {
+		fmt.Printf("Error taking snapshot: %s\n", err)
+		os.Exit(1)
+	}
+
+	out, err := json.Marshal(s)
+	if err != nil {
+		fmt.Printf("Error marshalling snapshot: %s\n", err)
+		os.Exit(1)
+	}
+
+	path := cmd.Flag("out-file").Value.String()
+	if err := ioutil.WriteFile(path, append(out, '\n'), 0644); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Println("\nSnapshot:")
+	fmt.Printf("%sbrokers: %d\n", indent, len(s.Brokers))
+	fmt.Printf("%stopics: %d\n", indent, len(s.Topics))
+
+	if s.BrokerMetrics == nil || s.PartitionMeta == nil {
+		fmt.Printf("%s[WARN] broker metrics or partition metadata not found; storage placement is unavailable\n", indent)
+	}
+
+	fmt.Printf("\n%s\n", path)
+}
@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Audit the current partition placement against placement invariants",
+	Long: `verify reads the current state of all topics (or those matching the optional
+--topics parameter) from ZooKeeper and reports replica sets that break rack ID
+constraints, replicas on brokers that aren't registered, duplicate replicas,
+inconsistent replication factors, under-replicated or leaderless partitions and
+skewed partition leadership. No changes are made. verify exits 1 if any problems
+are found.`,
+	Run: verify,
+}
+
+// verifyCheck holds the name and
+// results of a verify check.
+type verifyCheck struct {
+	name string
+	errs errors
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().String("topics", "", "Limit the scope to topics (comma delim. list) by lookup in ZooKeeper (default: all topics)")
+	verifyCmd.Flags().Int("min-rack-ids", 0, "Minimum number of required of unique rack IDs per replica set (0 requires that all are unique)")
+	verifyCmd.Flags().String("policy-file", "", "YAML or JSON file of topic placement policies to verify; policy min-rack-ids take precedence over flags")
+	verifyCmd.Flags().Float64("leader-skew", 0.25, "Percent distance from the mean partition leaders per broker considered skewed")
+}
+
+func verify(cmd *cobra.Command, _ []string) {
+	minRackIDs, _ := cmd.Flags().GetInt("min-rack-ids")
+	skew, _ := cmd.Flags().GetFloat64("leader-skew")
+
+	switch {
+	case minRackIDs < 0:
+		fmt.Println("\n[ERROR] --min-rack-ids must be >= 0")
+		defaultsAndExit()
+	case skew <= 0:
+		fmt.Println("\n[ERROR] --leader-skew must be greater than 0")
+		defaultsAndExit()
+	}
+
+	bootstrap(cmd)
+
+	// Default to all topics.
+	if len(Config.topics) == 0 {
+		Config.topics = []*regexp.Regexp{regexp.MustCompile(".*")}
+	}
+
+	// ZooKeeper init.
+	zk, err := initZooKeeper(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	defer zk.Close()
+
+	brokerMeta := getBrokerMeta(cmd, zk, false)
+
+	// Get the current partition map.
+	partitionMap, err := kafkazk.PartitionMapFromZK(Config.topics, zk)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Exclude any topics that are pending deletion.
+	pending := stripPendingDeletes(partitionMap, zk)
+
+	printTopics(partitionMap)
+	printExcludedTopics(pending)
+
+	// Get a broker map for rack IDs.
+	brokers := kafkazk.BrokerMapFromPartitionMap(partitionMap, brokerMeta, false)
+
+	// Get the current partition states.
+	states := map[string]kafkazk.TopicStateISR{}
+	for _, topic := range partitionMap.Topics() {
+		states[topic], err = zk.GetTopicStateISR(topic)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	policies := getPolicies(cmd)
+
+	var placementPolicies kafkazk.PlacementPolicies
+	if policies != nil {
+		placementPolicies, err = policies.placementPolicies(partitionMap, zk)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	checks := []verifyCheck{
+		{"Rack IDs", rackIDViolations(partitionMap, brokers, minRackIDs, placementPolicies)},
+		{"Missing brokers", missingBrokerViolations(partitionMap, brokerMeta)},
+		{"Duplicate replicas", duplicateReplicaViolations(partitionMap)},
+		{"Replication factor", replicationFactorViolations(partitionMap)},
+		{"Under-replicated partitions", underReplicatedViolations(partitionMap, states)},
+		{"Leadership skew", leadershipSkewViolations(partitionMap, states, skew)},
+	}
+
+	if policies != nil {
+		checks = append(checks, verifyCheck{"Placement policies", policies.violations(partitionMap, brokers, placementPolicies)})
+	}
+
+	var problems int
+	for _, c := range checks {
+		printVerifyResults(c.name, c.errs)
+		problems += len(c.errs)
+	}
+
+	if problems > 0 {
+		fmt.Printf("\n%d problem(s) found\n", problems)
+		os.Exit(1)
+	}
+
+	fmt.Println("\nOK")
+}
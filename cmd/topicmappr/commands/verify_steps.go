@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+// rackIDViolations returns an error for each partition with fewer unique
+// rack IDs than required. A minRackIDs of 0 requires that all rack IDs in
+// a replica set are unique. Any placement policy min-rack-ids take
+// precedence over minRackIDs. No errors are returned if no brokers have
+// a rack ID.
+func rackIDViolations(pm *kafkazk.PartitionMap, bm kafkazk.BrokerMap, minRackIDs int, pp kafkazk.PlacementPolicies) errors {
+	var errs errors
+
+	var hasRacks bool
+	for _, b := range bm {
+		if b.Locality != "" {
+			hasRacks = true
+			break
+		}
+	}
+
+	if !hasRacks {
+		return errs
+	}
+
+	for _, partn := range pm.Partitions {
+		racks := map[string]struct{}{}
+		for _, id := range partn.Replicas {
+			if b, exist := bm[id]; exist && b.Locality != "" {
+				racks[b.Locality] = struct{}{}
+			}
+		}
+
+		min := pp.Get(partn.Topic).MinRackIDs(minRackIDs)
+		if min == 0 || min > len(partn.Replicas) {
+			min = len(partn.Replicas)
+		}
+
+		if len(racks) < min {
+			errs = append(errs, fmt.Errorf("%s p%d: %d unique rack IDs for replicas %v, %d required",
+				partn.Topic, partn.Partition, len(racks), partn.Replicas, min))
+		}
+	}
+
+	return errs
+}
+
+// missingBrokerViolations returns an error for each partition replica
+// held by a broker not found in the BrokerMetaMap of registered brokers.
+func missingBrokerViolations(pm *kafkazk.PartitionMap, bmm kafkazk.BrokerMetaMap) errors {
+	var errs errors
+
+	for _, partn := range pm.Partitions {
+		for _, id := range partn.Replicas {
+			if _, exist := bmm[id]; !exist {
+				errs = append(errs, fmt.Errorf("%s p%d: broker %d is not registered",
+					partn.Topic, partn.Partition, id))
+			}
+		}
+	}
+
+	return errs
+}
+
+// duplicateReplicaViolations returns an error for each partition
+// that references a broker more than once in the replica set.
+func duplicateReplicaViolations(pm *kafkazk.PartitionMap) errors {
+	var errs errors
+
+	for _, partn := range pm.Partitions {
+		seen := map[int]bool{}
+		for _, id := range partn.Replicas {
+			if seen[id] {
+				errs = append(errs, fmt.Errorf("%s p%d: broker %d appears more than once in replicas %v",
+					partn.Topic, partn.Partition, id, partn.Replicas))
+			}
+			seen[id] = true
+		}
+	}
+
+	return errs
+}
+
+// replicationFactorViolations returns an error for each partition with
+// a replication factor that differs from the most common replication
+// factor of the topic. Ties are broken by the greater replication factor.
+func replicationFactorViolations(pm *kafkazk.PartitionMap) errors {
+	var errs errors
+
+	counts := map[string]map[int]int{}
+	for _, partn := range pm.Partitions {
+		if _, exist := counts[partn.Topic]; !exist {
+			counts[partn.Topic] = map[int]int{}
+		}
+		counts[partn.Topic][len(partn.Replicas)]++
+	}
+
+	rf := map[string]int{}
+	for topic, c := range counts {
+		for n, count := range c {
+			if count > c[rf[topic]] || (count == c[rf[topic]] && n > rf[topic]) {
+				rf[topic] = n
+			}
+		}
+	}
+
+	for _, partn := range pm.Partitions {
+		if n := len(partn.Replicas); n != rf[partn.Topic] {
+			errs = append(errs, fmt.Errorf("%s p%d: replication factor %d, topic majority is %d",
+				partn.Topic, partn.Partition, n, rf[partn.Topic]))
+		}
+	}
+
+	return errs
+}
+
+// underReplicatedViolations takes a *PartitionMap and the current
+// TopicStateISR of each topic and returns an error for each partition
+// that has no leader or fewer in-sync replicas than assigned replicas.
+func underReplicatedViolations(pm *kafkazk.PartitionMap, states map[string]kafkazk.TopicStateISR) errors {
+	var errs errors
+
+	for _, partn := range pm.Partitions {
+		state, exist := states[partn.Topic][strconv.Itoa(partn.Partition)]
+
+		switch {
+		case !exist:
+			errs = append(errs, fmt.Errorf("%s p%d: no partition state found",
+				partn.Topic, partn.Partition))
+		case state.Leader < 0:
+			errs = append(errs, fmt.Errorf("%s p%d: no leader", partn.Topic, partn.Partition))
+		case len(state.ISR) < len(partn.Replicas):
+			errs = append(errs, fmt.Errorf("%s p%d: ISR %v, replicas %v",
+				partn.Topic, partn.Partition, state.ISR, partn.Replicas))
+		}
+	}
+
+	return errs
+}
+
+// leadershipSkewViolations takes a *PartitionMap and the current
+// TopicStateISR of each topic and returns an error for each broker
+// where the count of partitions led is beyond the skew percent
+// distance from the mean of all brokers holding replicas.
+func leadershipSkewViolations(pm *kafkazk.PartitionMap, states map[string]kafkazk.TopicStateISR, skew float64) errors {
+	var errs errors
+
+	leaders := map[int]int{}
+	for _, partn := range pm.Partitions {
+		// Include brokers that lead no partitions.
+		for _, id := range partn.Replicas {
+			if _, exist := leaders[id]; !exist {
+				leaders[id] = 0
+			}
+		}
+
+		state, exist := states[partn.Topic][strconv.Itoa(partn.Partition)]
+		if exist && state.Leader >= 0 {
+			leaders[state.Leader]++
+		}
+	}
+
+	if len(leaders) == 0 {
+		return errs
+	}
+
+	var total int
+	ids := []int{}
+	for id, n := range leaders {
+		total += n
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	mean := float64(total) / float64(len(leaders))
+	if mean == 0 {
+		return errs
+	}
+
+	for _, id := range ids {
+		if d := absDistance(float64(leaders[id]), mean); d > skew {
+			errs = append(errs, fmt.Errorf("broker %d: %d leaders, %.2f%% from mean of %.2f",
+				id, leaders[id], d*100, mean))
+		}
+	}
+
+	return errs
+}
+
+// printVerifyResults prints the results of a verify check.
+func printVerifyResults(check string, e errors) {
+	fmt.Printf("\n%s:\n", check)
+
+	if len(e) == 0 {
+		fmt.Printf("%s[none]\n", indent)
+		return
+	}
+
+	sort.Sort(e)
+	for _, err := range e {
+		fmt.Printf("%s%s\n", indent, err)
+	}
+}
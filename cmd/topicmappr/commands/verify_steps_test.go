@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+)
+
+func testVerifyMap() *kafkazk.PartitionMap {
+	pm, _ := kafkazk.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":1,"replicas":[1002,1001]},
+		{"topic":"test_topic","partition":2,"replicas":[1001,1004]},
+		{"topic":"test_topic","partition":3,"replicas":[1003,1003,1002]}]}`)
+
+	return pm
+}
+
+func TestRackIDViolations(t *testing.T) {
+	pm := testVerifyMap()
+	bm := kafkazk.BrokerMapFromPartitionMap(pm, kafkazk.BrokerMetaMap{
+		1001: &kafkazk.BrokerMeta{Rack: "a"},
+		1002: &kafkazk.BrokerMeta{Rack: "b"},
+		1003: &kafkazk.BrokerMeta{Rack: "c"},
+		1004: &kafkazk.BrokerMeta{Rack: "a"},
+	}, false)
+
+	// p2 shares rack a; p3 has 2 rack IDs for 3 replicas.
+	if errs := rackIDViolations(pm, bm, 0, nil); len(errs) != 2 {
+		t.Errorf("Expected 2 violations, got %d: %v", len(errs), errs)
+	}
+
+	// p2 only has 1 rack ID.
+	if errs := rackIDViolations(pm, bm, 2, nil); len(errs) != 1 {
+		t.Errorf("Expected 1 violation, got %d: %v", len(errs), errs)
+	}
+
+	min := 1
+	pp := kafkazk.PlacementPolicies{"test_topic": &kafkazk.PlacementPolicy{MinUniqueRackIDs: &min}}
+
+	if errs := rackIDViolations(pm, bm, 0, pp); len(errs) != 0 {
+		t.Errorf("Expected no violations, got %v", errs)
+	}
+
+	// No rack IDs are known.
+	bm = kafkazk.BrokerMapFromPartitionMap(pm, kafkazk.BrokerMetaMap{}, false)
+	if errs := rackIDViolations(pm, bm, 0, nil); len(errs) != 0 {
+		t.Errorf("Expected no violations, got %v", errs)
+	}
+}
+
+func TestMissingBrokerViolations(t *testing.T) {
+	pm := testVerifyMap()
+	bmm := kafkazk.BrokerMetaMap{
+		1001: &kafkazk.BrokerMeta{},
+		1002: &kafkazk.BrokerMeta{},
+		1003: &kafkazk.BrokerMeta{},
+	}
+
+	errs := missingBrokerViolations(pm, bmm)
+	if len(errs) != 1 || errs[0].Error() != "test_topic p2: broker 1004 is not registered" {
+		t.Errorf("Unexpected violations %v", errs)
+	}
+}
+
+func TestDuplicateReplicaViolations(t *testing.T) {
+	errs := duplicateReplicaViolations(testVerifyMap())
+	if len(errs) != 1 || errs[0].Error() != "test_topic p3: broker 1003 appears more than once in replicas [1003 1003 1002]" {
+		t.Errorf("Unexpected violations %v", errs)
+	}
+}
+
+func TestReplicationFactorViolations(t *testing.T) {
+	errs := replicationFactorViolations(testVerifyMap())
+	if len(errs) != 1 || errs[0].Error() != "test_topic p3: replication factor 3, topic majority is 2" {
+		t.Errorf("Unexpected violations %v", errs)
+	}
+}
+
+func TestUnderReplicatedViolations(t *testing.T) {
+	pm := testVerifyMap()
+	states := map[string]kafkazk.TopicStateISR{
+		"test_topic": kafkazk.TopicStateISR{
+			"0": kafkazk.PartitionState{Leader: 1001, ISR: []int{1001, 1002}},
+			"1": kafkazk.PartitionState{Leader: 1002, ISR: []int{1002}},
+			"2": kafkazk.PartitionState{Leader: -1, ISR: []int{}},
+		},
+	}
+
+	expected := map[string]bool{
+		"test_topic p1: ISR [1002], replicas [1002 1001]": true,
+		"test_topic p2: no leader":                        true,
+		"test_topic p3: no partition state found":         true,
+	}
+
+	errs := underReplicatedViolations(pm, states)
+	if len(errs) != len(expected) {
+		t.Errorf("Expected %d violations, got %d: %v", len(expected), len(errs), errs)
+	}
+
+	for _, e := range errs {
+		if !expected[e.Error()] {
+			t.Errorf("Unexpected violation %s", e)
+		}
+	}
+}
+
+func TestLeadershipSkewViolations(t *testing.T) {
+	pm := testVerifyMap()
+	states := map[string]kafkazk.TopicStateISR{
+		"test_topic": kafkazk.TopicStateISR{
+			"0": kafkazk.PartitionState{Leader: 1001},
+			"1": kafkazk.PartitionState{Leader: 1001},
+			"2": kafkazk.PartitionState{Leader: 1001},
+			"3": kafkazk.PartitionState{Leader: 1003},
+		},
+	}
+
+	// 4 leaders across 4 brokers; 1001 leads 3, 1002
+	// and 1004 lead none.
+	errs := leadershipSkewViolations(pm, states, 0.25)
+	if len(errs) != 3 {
+		t.Errorf("Expected 3 violations, got %d: %v", len(errs), errs)
+	}
+
+	if errs := leadershipSkewViolations(pm, states, 2.00); len(errs) != 0 {
+		t.Errorf("Expected no violations, got %v", errs)
+	}
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/DataDog/kafka-kit/kafkazk"
+
+	"github.com/spf13/cobra"
+)
+
+// getPlacementWeights returns the kafkazk.PlacementWeights from the
+// --weights flag. A nil kafkazk.PlacementWeights is returned if the
+// command doesn't have the flag or it's unset.
+func getPlacementWeights(cmd *cobra.Command) (kafkazk.PlacementWeights, error) {
+	f := cmd.Flag("weights")
+	if f == nil || f.Value.String() == "" {
+		return nil, nil
+	}
+
+	w, err := kafkazk.ParsePlacementWeights(f.Value.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid --weights: %s", err)
+	}
+
+	return w, nil
+}
+
+// printPlacementScores prints the per-dimension and weighted scores of
+// the input and output maps if --weights is set. Each score is the mean
+// absolute deviation of brokers from the mean, normalized by the mean.
+func printPlacementScores(cmd *cobra.Command, pm1, pm2 *kafkazk.PartitionMap, bm1, bm2 kafkazk.BrokerMap, pmm kafkazk.PartitionMetaMap) {
+	w, _ := getPlacementWeights(cmd)
+	if w == nil {
+		return
+	}
+
+	s1, s2 := w.DimensionScores(pm1, bm1, pmm), w.DimensionScores(pm2, bm2, pmm)
+
+	fmt.Println("\nPlacement scores (lower is better):")
+
+	for _, dim := range w.Dimensions() {
+		fmt.Printf("%s%s (weight %.2f): %.4f -> %.4f\n", indent, dim, w[dim], s1[dim], s2[dim])
+	}
+
+	fmt.Printf("%s-\n", indent)
+	fmt.Printf("%sweighted: %.4f -> %.4f\n", indent, s1["weighted"], s2["weighted"])
+}
@@ -12,7 +12,7 @@ require (
 	github.com/masterminds/semver v1.5.0
 	github.com/samuel/go-zookeeper v0.0.0-20190923202752-2cc03de413da
 	github.com/spf13/cobra v1.0.0
-	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.2.2
 	github.com/zorkian/go-datadog-api v2.28.0+incompatible
 	golang.org/x/net v0.0.0-20200421231249-e086a090c8fd // indirect
@@ -20,4 +20,5 @@ require (
 	golang.org/x/text v0.3.2 // indirect
 	google.golang.org/genproto v0.0.0-20200420144010-e5e8543f8aeb
 	google.golang.org/grpc v1.29.0
+	gopkg.in/yaml.v2 v2.4.0
 )
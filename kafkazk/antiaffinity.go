@@ -0,0 +1,133 @@
+package kafkazk
+
+import (
+	"sort"
+)
+
+// TopicAntiAffinities holds groups of topics that must not share
+// brokers along with the brokers hosting each grouped topic.
+type TopicAntiAffinities struct {
+	// Group indexes by topic.
+	groups map[string][]int
+	// Topics by group index.
+	members [][]string
+	// Broker IDs hosting each grouped topic.
+	hosts map[string]map[int]bool
+}
+
+// NewTopicAntiAffinities takes groups of topic names and returns a
+// *TopicAntiAffinities. A topic may belong to multiple groups.
+func NewTopicAntiAffinities(groups [][]string) *TopicAntiAffinities {
+	t := &TopicAntiAffinities{
+		groups: map[string][]int{},
+		hosts:  map[string]map[int]bool{},
+	}
+
+	for i, g := range groups {
+		members := []string{}
+		seen := map[string]bool{}
+
+		for _, topic := range g {
+			if seen[topic] {
+				continue
+			}
+			seen[topic] = true
+
+			members = append(members, topic)
+			t.groups[topic] = append(t.groups[topic], i)
+		}
+
+		sort.Strings(members)
+		t.members = append(t.members, members)
+	}
+
+	return t
+}
+
+// Set takes a *PartitionMap and sets the hosting brokers for all grouped
+// topics in the map, replacing any previously known placements for those
+// topics. Stub broker IDs are ignored.
+func (t *TopicAntiAffinities) Set(pm *PartitionMap) {
+	if t == nil {
+		return
+	}
+
+	for _, topic := range pm.Topics() {
+		if _, exist := t.groups[topic]; exist {
+			t.hosts[topic] = map[int]bool{}
+		}
+	}
+
+	for _, partn := range pm.Partitions {
+		for _, id := range partn.Replicas {
+			t.Add(partn.Topic, id)
+		}
+	}
+}
+
+// Add records that the broker ID hosts the topic.
+func (t *TopicAntiAffinities) Add(topic string, id int) {
+	if t == nil || id == StubBrokerID {
+		return
+	}
+
+	if _, exist := t.groups[topic]; !exist {
+		return
+	}
+
+	if t.hosts[topic] == nil {
+		t.hosts[topic] = map[int]bool{}
+	}
+
+	t.hosts[topic][id] = true
+}
+
+// Conflict returns the name of a topic sharing an anti-affinity group
+// with the provided topic that's hosted by the broker ID. An empty
+// string is returned if there's no conflict. A nil *TopicAntiAffinities
+// never conflicts.
+func (t *TopicAntiAffinities) Conflict(topic string, id int) string {
+	if t == nil {
+		return ""
+	}
+
+	for _, g := range t.groups[topic] {
+		for _, other := range t.members[g] {
+			if other != topic && t.hosts[other][id] {
+				return other
+			}
+		}
+	}
+
+	return ""
+}
+
+// Copy returns a copy of the *TopicAntiAffinities.
+func (t *TopicAntiAffinities) Copy() *TopicAntiAffinities {
+	if t == nil {
+		return nil
+	}
+
+	c := &TopicAntiAffinities{
+		groups:  map[string][]int{},
+		members: make([][]string, len(t.members)),
+		hosts:   map[string]map[int]bool{},
+	}
+
+	for topic, g := range t.groups {
+		c.groups[topic] = append([]int{}, g...)
+	}
+
+	for i, m := range t.members {
+		c.members[i] = append([]string{}, m...)
+	}
+
+	for topic, ids := range t.hosts {
+		c.hosts[topic] = map[int]bool{}
+		for id := range ids {
+			c.hosts[topic][id] = true
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,188 @@
+package kafkazk
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTopicAntiAffinities(t *testing.T) {
+	aa := NewTopicAntiAffinities([][]string{
+		{"test_topic", "test_topic2"},
+		{"test_topic2", "test_topic3"},
+	})
+
+	pm, _ := PartitionMapFromString(testGetMapString("test_topic"))
+	aa.Set(pm)
+	aa.Add("test_topic3", 1005)
+	aa.Add("ungrouped", 1006)
+
+	tests := []struct {
+		topic    string
+		id       int
+		expected string
+	}{
+		{"test_topic2", 1001, "test_topic"},
+		{"test_topic2", 1005, "test_topic3"},
+		{"test_topic2", 1006, ""},
+		{"test_topic", 1005, ""},
+		{"test_topic3", 1001, ""},
+		{"ungrouped", 1001, ""},
+	}
+
+	for _, test := range tests {
+		if c := aa.Conflict(test.topic, test.id); c != test.expected {
+			t.Errorf("%s/%d: expected conflict '%s', got '%s'", test.topic, test.id, test.expected, c)
+		}
+	}
+
+	// Set replaces previous placements.
+	cpy := aa.Copy()
+	pm2, _ := PartitionMapFromString(testGetMapString("test_topic"))
+	for i := range pm2.Partitions {
+		pm2.Partitions[i].Replicas = []int{1007}
+	}
+
+	cpy.Set(pm2)
+
+	if c := cpy.Conflict("test_topic2", 1001); c != "" {
+		t.Errorf("Expected no conflict, got '%s'", c)
+	}
+
+	if c := cpy.Conflict("test_topic2", 1007); c != "test_topic" {
+		t.Errorf("Expected conflict 'test_topic', got '%s'", c)
+	}
+
+	// The original is unchanged.
+	if c := aa.Conflict("test_topic2", 1001); c != "test_topic" {
+		t.Errorf("Expected conflict 'test_topic', got '%s'", c)
+	}
+
+	// A nil *TopicAntiAffinities never conflicts.
+	var n *TopicAntiAffinities
+	n.Add("test_topic", 1001)
+	if c := n.Conflict("test_topic2", 1001); c != "" {
+		t.Errorf("Expected no conflict, got '%s'", c)
+	}
+}
+
+func TestSelectBrokerAntiAffinity(t *testing.T) {
+	bl := BrokerList{
+		&Broker{ID: 1001, Locality: "a"},
+		&Broker{ID: 1002, Locality: "b"},
+		&Broker{ID: 1003, Locality: "c"},
+	}
+
+	aa := NewTopicAntiAffinities([][]string{{"test_topic", "test_topic2"}})
+	aa.Add("test_topic2", 1002)
+	aa.Add("test_topic2", 1003)
+
+	c := NewConstraints()
+	c.MergeConstraints(BrokerList{bl[0]})
+
+	p := ConstraintsParams{
+		SelectorMethod: "count",
+		Topic:          "test_topic",
+		AntiAffinities: aa,
+	}
+
+	_, err := c.SelectBroker(bl, p)
+	if !errors.Is(err, ErrNoBrokers) {
+		t.Fatalf("Expected ErrNoBrokers, got %v", err)
+	}
+
+	expected := "[already in replica set: 1001; anti-affinity with test_topic2: 1002,1003]"
+	if !strings.HasSuffix(err.Error(), expected) {
+		t.Errorf("Expected error suffix '%s', got '%s'", expected, err)
+	}
+
+	// Unrelated topics are unaffected.
+	p.Topic = "test_topic3"
+	b, err := c.SelectBroker(bl, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.ID == 1001 {
+		t.Errorf("Unexpected candidate %d", b.ID)
+	}
+}
+
+func TestRebuildAntiAffinity(t *testing.T) {
+	zk := &Mock{}
+	bm, _ := zk.GetAllBrokerMeta(false)
+	pm, _ := PartitionMapFromString(testGetMapString("test_topic"))
+
+	for _, strategy := range []string{"count", "storage"} {
+		brokers := BrokerMapFromPartitionMap(pm, bm, false)
+		brokers.Update([]int{1001, 1002, 1003, 1005}, bm)
+		for _, b := range brokers {
+			b.StorageFree = 10000
+		}
+
+		aa := NewTopicAntiAffinities([][]string{{"test_topic", "test_topic2"}})
+		aa.Add("test_topic2", 1005)
+		// Placements of the topic being rebuilt
+		// are taken from the input map.
+		aa.Add("test_topic", 1007)
+
+		pmm := NewPartitionMetaMap()
+		pmm["test_topic"] = map[int]*PartitionMeta{}
+		for i := 0; i < 4; i++ {
+			pmm["test_topic"][i] = &PartitionMeta{Size: 1000}
+		}
+
+		rebuildParams := RebuildParams{
+			PMM:            pmm,
+			BM:             brokers,
+			Strategy:       strategy,
+			Optimization:   "storage",
+			PartnSzFactor:  1,
+			AntiAffinities: aa,
+		}
+
+		out, errs := pm.Copy().Rebuild(rebuildParams)
+		if errs != nil {
+			t.Errorf("[%s] Unexpected error(s): %s", strategy, errs)
+		}
+
+		for _, partn := range out.Partitions {
+			for _, id := range partn.Replicas {
+				if id == 1005 || id == 1004 {
+					t.Errorf("[%s] %s p%d: unexpected broker %d", strategy, partn.Topic, partn.Partition, id)
+				}
+			}
+		}
+
+		// The provided *TopicAntiAffinities is unchanged.
+		if c := aa.Conflict("test_topic2", 1007); c != "test_topic" {
+			t.Errorf("[%s] Expected conflict 'test_topic', got '%s'", strategy, c)
+		}
+	}
+
+	// No qualifying brokers.
+	brokers := BrokerMapFromPartitionMap(pm, bm, false)
+	brokers.Update([]int{1001, 1002, 1003, 1005}, bm)
+
+	aa := NewTopicAntiAffinities([][]string{{"test_topic", "test_topic2"}})
+	aa.Add("test_topic2", 1002)
+	aa.Add("test_topic2", 1005)
+
+	rebuildParams := RebuildParams{
+		PMM:            NewPartitionMetaMap(),
+		BM:             brokers,
+		Strategy:       "count",
+		AntiAffinities: aa,
+	}
+
+	_, errs := pm.Copy().Rebuild(rebuildParams)
+	if len(errs) == 0 {
+		t.Fatal("Expected non-nil errors")
+	}
+
+	for _, err := range errs {
+		if !strings.Contains(err.Error(), "anti-affinity with test_topic2") {
+			t.Errorf("Expected anti-affinity reason, got '%s'", err)
+		}
+	}
+}
@@ -20,6 +20,9 @@ type BrokerMetaMap map[int]*BrokerMeta
 // used in satisfying constraints.
 type BrokerMeta struct {
 	StorageFree       float64 // In bytes.
+	StorageTotal      float64 // In bytes; 0 if unknown.
+	LogDirs           LogDirMetaMap
+	InstanceType      string // From metrics; "" if unknown.
 	MetricsIncomplete bool
 	// Metadata from ZooKeeper.
 	ListenerSecurityProtocolMap map[string]string `json:"listener_security_protocol_map"`
@@ -32,6 +35,33 @@ type BrokerMeta struct {
 	Version                     int               `json:"version"`
 }
 
+// LogDirMetaMap is a map of log dir
+// paths to LogDirMeta metadata.
+type LogDirMetaMap map[string]*LogDirMeta
+
+// LogDirMeta holds storage metrics for
+// a log dir of a broker.
+type LogDirMeta struct {
+	StorageFree float64 // In bytes.
+	// StorageTotal is optional.
+	StorageTotal float64 `json:",omitempty"`
+}
+
+// Copy returns a copy of a LogDirMetaMap.
+func (l LogDirMetaMap) Copy() LogDirMetaMap {
+	if l == nil {
+		return nil
+	}
+
+	c := LogDirMetaMap{}
+	for dir, m := range l {
+		meta := *m
+		c[dir] = &meta
+	}
+
+	return c
+}
+
 // BrokerMetricsMap holds a mapping of broker
 // ID to BrokerMetrics.
 type BrokerMetricsMap map[int]*BrokerMetrics
@@ -40,6 +70,12 @@ type BrokerMetricsMap map[int]*BrokerMetrics
 // data fetched from ZK.
 type BrokerMetrics struct {
 	StorageFree float64
+	// StorageTotal is optional.
+	StorageTotal float64 `json:",omitempty"`
+	// LogDirs is optional.
+	LogDirs LogDirMetaMap `json:",omitempty"`
+	// InstanceType is optional.
+	InstanceType string `json:",omitempty"`
 }
 
 // BrokerUseStats holds counts
@@ -100,9 +136,15 @@ type Broker struct {
 	Locality    string
 	Used        int
 	StorageFree float64
-	Replace     bool
-	Missing     bool
-	New         bool
+	// Storage capacity in bytes; 0
+	// if unknown.
+	StorageTotal float64
+	// Throughput in bytes/s; only set
+	// for throughput placements.
+	Throughput float64
+	Replace    bool
+	Missing    bool
+	New        bool
 }
 
 // BrokerMap holds a mapping of broker IDs to *Broker.
@@ -121,6 +163,8 @@ type BrokerList []*Broker
 // Wrapper types for sort by methods.
 type brokersByCount BrokerList
 type brokersByStorage BrokerList
+type brokersByStorageUsed BrokerList
+type brokersByThroughput BrokerList
 type brokersByID BrokerList
 
 // Satisfy the sort interface for BrokerList types.
@@ -153,6 +197,41 @@ func (b brokersByStorage) Less(i, j int) bool {
 	return b[i].ID < b[j].ID
 }
 
+// By storage used percentage ascending. Brokers
+// with an unknown StorageTotal are sorted last.
+func (b brokersByStorageUsed) Len() int      { return len(b) }
+func (b brokersByStorageUsed) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b brokersByStorageUsed) Less(i, j int) bool {
+	k1, k2 := b[i].StorageTotal > 0, b[j].StorageTotal > 0
+	if k1 != k2 {
+		return k1
+	}
+
+	u1, u2 := b[i].StorageUsedPercent(), b[j].StorageUsedPercent()
+	if u1 < u2 {
+		return true
+	}
+	if u1 > u2 {
+		return false
+	}
+
+	return b[i].ID < b[j].ID
+}
+
+// By Throughput value ascending.
+func (b brokersByThroughput) Len() int      { return len(b) }
+func (b brokersByThroughput) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b brokersByThroughput) Less(i, j int) bool {
+	if b[i].Throughput < b[j].Throughput {
+		return true
+	}
+	if b[i].Throughput > b[j].Throughput {
+		return false
+	}
+
+	return b[i].ID < b[j].ID
+}
+
 // By ID value ascending.
 func (b brokersByID) Len() int           { return len(b) }
 func (b brokersByID) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
@@ -170,6 +249,31 @@ func (b BrokerList) SortByStorage() {
 	sort.Sort(brokersByStorage(b))
 }
 
+// SortByStorageUsed sorts the BrokerList by
+// storage used percentages ascending.
+func (b BrokerList) SortByStorageUsed() {
+	sort.Sort(brokersByStorageUsed(b))
+}
+
+// SortByThroughput sorts the BrokerList by Throughput values.
+func (b BrokerList) SortByThroughput() {
+	sort.Sort(brokersByThroughput(b))
+}
+
+// SortByScore sorts the BrokerList by ascending values of
+// the score function f, then by ID.
+func (b BrokerList) SortByScore(f func(*Broker) float64) {
+	scores := map[int]float64{}
+	for _, br := range b {
+		scores[br.ID] = f(br)
+	}
+
+	sort.Sort(brokersByID(b))
+	sort.SliceStable(b, func(i, j int) bool {
+		return scores[b[i].ID] < scores[b[j].ID]
+	})
+}
+
 // SortByID sorts the BrokerList by ID values.
 func (b BrokerList) SortByID() {
 	sort.Sort(brokersByID(b))
@@ -318,12 +422,13 @@ func (b BrokerMap) Update(bl []int, bm BrokerMetaMap) (*BrokerStatus, <-chan str
 			// the broker metadata map.
 			if meta, exists := bm[id]; exists {
 				b[id] = &Broker{
-					Used:        0,
-					ID:          id,
-					Replace:     false,
-					Locality:    meta.Rack,
-					StorageFree: meta.StorageFree,
-					New:         true,
+					Used:         0,
+					ID:           id,
+					Replace:      false,
+					Locality:     meta.Rack,
+					StorageFree:  meta.StorageFree,
+					StorageTotal: meta.StorageTotal,
+					New:          true,
 				}
 				bs.New++
 			} else {
@@ -388,6 +493,30 @@ func (b BrokerMap) SubStorage(pm *PartitionMap, pmm PartitionMetaMap, f func(*Br
 	return nil
 }
 
+// SetThroughput takes a PartitionMap and PartitionMetaMap and sets the
+// Throughput of each broker in the BrokerMap to the sum of the throughput
+// of all partitions it holds in the PartitionMap.
+func (b BrokerMap) SetThroughput(pm *PartitionMap, pmm PartitionMetaMap) error {
+	for _, broker := range b {
+		broker.Throughput = 0
+	}
+
+	for _, partn := range pm.Partitions {
+		t, err := pmm.Throughput(partn)
+		if err != nil {
+			return err
+		}
+
+		for _, bid := range partn.Replicas {
+			if broker, exists := b[bid]; exists {
+				broker.Throughput += t
+			}
+		}
+	}
+
+	return nil
+}
+
 // Filter returns a BrokerMap of brokers that return
 // true as an input to function f.
 func (b BrokerMap) Filter(f BrokerFilterFn) BrokerMap {
@@ -469,6 +598,7 @@ func BrokerMapFromPartitionMap(pm *PartitionMap, bm BrokerMetaMap, force bool) B
 			if meta, exists := bm[id]; exists {
 				bmap[id].Locality = meta.Rack
 				bmap[id].StorageFree = meta.StorageFree
+				bmap[id].StorageTotal = meta.StorageTotal
 			}
 		}
 	}
@@ -484,13 +614,15 @@ func (b BrokerMap) Copy() BrokerMap {
 	c := BrokerMap{}
 	for id, br := range b {
 		c[id] = &Broker{
-			ID:          br.ID,
-			Locality:    br.Locality,
-			Used:        br.Used,
-			StorageFree: br.StorageFree,
-			Replace:     br.Replace,
-			Missing:     br.Missing,
-			New:         br.New,
+			ID:           br.ID,
+			Locality:     br.Locality,
+			Used:         br.Used,
+			StorageFree:  br.StorageFree,
+			StorageTotal: br.StorageTotal,
+			Throughput:   br.Throughput,
+			Replace:      br.Replace,
+			Missing:      br.Missing,
+			New:          br.New,
 		}
 	}
 
@@ -500,12 +632,14 @@ func (b BrokerMap) Copy() BrokerMap {
 // Copy returns a copy of a Broker.
 func (b Broker) Copy() Broker {
 	return Broker{
-		ID:          b.ID,
-		Locality:    b.Locality,
-		Used:        b.Used,
-		StorageFree: b.StorageFree,
-		Replace:     b.Replace,
-		Missing:     b.Missing,
-		New:         b.New,
+		ID:           b.ID,
+		Locality:     b.Locality,
+		Used:         b.Used,
+		StorageFree:  b.StorageFree,
+		StorageTotal: b.StorageTotal,
+		Throughput:   b.Throughput,
+		Replace:      b.Replace,
+		Missing:      b.Missing,
+		New:          b.New,
 	}
 }
@@ -443,3 +443,59 @@ func newMockBrokerMap2() BrokerMap {
 		1007:         &Broker{ID: 1007, Locality: "a", Used: 3, Replace: false, StorageFree: 400.00},
 	}
 }
+
+func TestSortBrokerListByThroughput(t *testing.T) {
+	b := newMockBrokerMap2()
+	for id, br := range b {
+		br.Throughput = float64(id % 3)
+	}
+
+	bl := b.Filter(func(b *Broker) bool { return true }).List()
+
+	bl.SortByThroughput()
+
+	var blIDs []int
+	for _, br := range bl {
+		blIDs = append(blIDs, br.ID)
+	}
+
+	expected := []int{1002, 1005, 1003, 1006, 1001, 1004, 1007}
+
+	for i, br := range bl {
+		if br.ID != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, blIDs)
+		}
+	}
+}
+
+func TestSetThroughput(t *testing.T) {
+	zk := &Mock{}
+	pm, _ := zk.GetPartitionMap("test_topic")
+	pmm, _ := zk.GetAllPartitionMeta()
+	bm := BrokerMapFromPartitionMap(pm, nil, false)
+
+	for i, partn := range pmm["test_topic"] {
+		partn.BytesIn = float64(i + 1)
+		partn.BytesOut = float64(i + 1)
+	}
+
+	bm[1001].Throughput = 100
+
+	if err := bm.SetThroughput(pm, pmm); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[int]float64{1001: 12, 1002: 14, 1003: 14, 1004: 14}
+
+	for id, e := range expected {
+		if bm[id].Throughput != e {
+			t.Errorf("Expected broker %d throughput %.0f, got %.0f", id, e, bm[id].Throughput)
+		}
+	}
+
+	// Missing partition meta.
+	delete(pmm["test_topic"], 3)
+	if err := bm.SetThroughput(pm, pmm); err == nil {
+		t.Error("Expected non-nil error")
+	}
+}
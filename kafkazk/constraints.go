@@ -2,6 +2,10 @@ package kafkazk
 
 import (
 	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 var (
@@ -34,11 +38,32 @@ type ConstraintsParams struct {
 	MinUniqueRackIDs int
 	RequestSize      float64
 	SeedVal          int64
+	// RequestThroughput is added to the selected
+	// broker Throughput.
+	RequestThroughput float64
+	// Weights are used by the weighted selector method.
+	// Leader indicates a leader placement and LeaderCounts
+	// holds the current leader count by broker ID.
+	Weights      PlacementWeights
+	Leader       bool
+	LeaderCounts map[int]int
+	// LocalityRank optionally orders candidates by
+	// ascending rank of their Locality, taking
+	// precedence over the SelectorMethod ordering.
+	LocalityRank map[string]int
+	// Policy optionally limits eligible brokers.
+	Policy *PlacementPolicy
+	// Topic is the topic being placed. If AntiAffinities
+	// is set, brokers hosting any topic in an anti-affinity
+	// group with Topic are ineligible.
+	Topic          string
+	AntiAffinities *TopicAntiAffinities
 }
 
 // SelectBroker takes a BrokerList and a ConstraintsParams and
 // selects the most suitable broker that passes all specified
-// constraints.
+// constraints. If no broker passes, the returned error wraps
+// ErrNoBrokers and lists the reasons candidates were rejected.
 func (c *Constraints) SelectBroker(b BrokerList, p ConstraintsParams) (*Broker, error) {
 	// Sort type based on the
 	// desired placement criteria.
@@ -49,26 +74,72 @@ func (c *Constraints) SelectBroker(b BrokerList, p ConstraintsParams) (*Broker,
 		b.SortPseudoShuffle(p.SeedVal)
 	case "storage":
 		b.SortByStorage()
+	case "throughput":
+		b.SortByThroughput()
+	case "weighted":
+		if p.Weights == nil {
+			return nil, ErrInvalidSelectionMethod
+		}
+		b.SortByScore(func(c *Broker) float64 { return p.Weights.score(c, b, p) })
 	default:
 		return nil, ErrInvalidSelectionMethod
 	}
 
+	// Order by locality rank if set. The
+	// stable sort preserves the selector
+	// method ordering within a locality.
+	if p.LocalityRank != nil {
+		sort.SliceStable(b, func(i, j int) bool {
+			return p.LocalityRank[b[i].Locality] < p.LocalityRank[b[j].Locality]
+		})
+	}
+
 	var candidate *Broker
+	rejected := map[string][]int{}
 
 	// Iterate over candidates.
 	for _, candidate = range b.Filter(AllBrokersFn) {
 		// Candidate passes, return.
-		if c.passesWithParams(candidate, p) {
+		r := c.rejectionWithParams(candidate, p)
+		if r == "" {
 			c.requestSize = p.RequestSize
 			c.Add(candidate)
 			candidate.Used++
+			candidate.Throughput += p.RequestThroughput
 
 			return candidate, nil
 		}
+
+		rejected[r] = append(rejected[r], candidate.ID)
 	}
 
 	// List exhausted, no brokers passed.
-	return nil, ErrNoBrokers
+	return nil, noBrokersError(rejected)
+}
+
+// noBrokersError takes a mapping of rejection reasons to broker
+// IDs and returns an error wrapping ErrNoBrokers that describes
+// the reasons. ErrNoBrokers is returned if no reasons are given.
+func noBrokersError(rejected map[string][]int) error {
+	if len(rejected) == 0 {
+		return ErrNoBrokers
+	}
+
+	var reasons []string
+	for r, ids := range rejected {
+		sort.Ints(ids)
+
+		var s []string
+		for _, id := range ids {
+			s = append(s, strconv.Itoa(id))
+		}
+
+		reasons = append(reasons, fmt.Sprintf("%s: %s", r, strings.Join(s, ",")))
+	}
+
+	sort.Strings(reasons)
+
+	return fmt.Errorf("%w [%s]", ErrNoBrokers, strings.Join(reasons, "; "))
 }
 
 // TODO deprecate.
@@ -86,6 +157,8 @@ func (b BrokerList) BestCandidate(c *Constraints, by string, p int64) (*Broker,
 		b.SortPseudoShuffle(p)
 	case "storage":
 		b.SortByStorage()
+	case "storage_used":
+		b.SortByStorageUsed()
 	default:
 		return nil, ErrInvalidSelectionMethod
 	}
@@ -163,32 +236,50 @@ func (c *Constraints) passes(b *Broker) bool {
 	return true
 }
 
+// passesWithParams takes a *Broker and ConstraintsParams
+// and returns whether or not it passes Constraints.
 func (c *Constraints) passesWithParams(b *Broker, p ConstraintsParams) bool {
+	return c.rejectionWithParams(b, p) == ""
+}
+
+// rejectionWithParams takes a *Broker and ConstraintsParams and
+// returns the reason the *Broker fails Constraints. An empty
+// string is returned if the *Broker passes.
+func (c *Constraints) rejectionWithParams(b *Broker, p ConstraintsParams) string {
 	var uniqueRackIDsSatisfied bool
 	if len(c.locality) >= p.MinUniqueRackIDs {
 		uniqueRackIDsSatisfied = true
 	}
 
+	// Topic anti-affinity conflict, if any.
+	conflict := p.AntiAffinities.Conflict(p.Topic, b.ID)
+
 	switch {
 	// Check the candidate against already used IDs.
 	case c.id[b.ID]:
-		return false
+		return "already in replica set"
+	// Check the candidate against any placement policy.
+	case !p.Policy.Allows(b.ID):
+		return "not allowed by placement policy"
+	// Check the candidate against topic anti-affinities.
+	case conflict != "":
+		return fmt.Sprintf("anti-affinity with %s", conflict)
 	// Check the candidate against rack ID constraints
 	// where all rack IDs must be unique.
 	case c.locality[b.Locality] && p.MinUniqueRackIDs == 0:
-		return false
+		return "rack ID in use"
 	// Check the candidate against rack ID constraints
 	// where a non-zero MinUniqueRackIDs is set.
 	case c.locality[b.Locality] && p.MinUniqueRackIDs > 0:
 		if !uniqueRackIDsSatisfied {
-			return false
+			return "min unique rack IDs not met"
 		}
 	// Check the candidate against storage capacity.
 	case b.StorageFree-p.RequestSize < 0:
-		return false
+		return "insufficient storage"
 	}
 
-	return true
+	return ""
 }
 
 // TODO deprecate.
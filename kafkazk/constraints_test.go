@@ -101,6 +101,40 @@ func TestSelectBrokerByStorage(t *testing.T) {
 	}
 }
 
+func TestSelectBrokerLocalityRank(t *testing.T) {
+	localities := []string{"a", "b", "c"}
+	bl := BrokerList{}
+
+	for i := 0; i < 4; i++ {
+		b := &Broker{
+			ID:       1000 + i,
+			Locality: localities[i%3],
+			Used:     i,
+		}
+
+		bl = append(bl, b)
+	}
+
+	c := NewConstraints()
+
+	p := ConstraintsParams{
+		SelectorMethod: "count",
+		LocalityRank:   map[string]int{"a": 2, "b": 0, "c": 1},
+	}
+
+	b, _ := c.SelectBroker(bl, p)
+	// 1001 is the only broker with locality "b".
+	if b.ID != 1001 {
+		t.Errorf("Expected candidate with ID 1001, got %d", b.ID)
+	}
+
+	b, _ = c.SelectBroker(bl, p)
+	// 1002 with locality "c" should be next.
+	if b.ID != 1002 {
+		t.Errorf("Expected candidate with ID 1002, got %d", b.ID)
+	}
+}
+
 func TestBestCandidateByCount(t *testing.T) {
 	localities := []string{"a", "b", "c"}
 	bl := BrokerList{}
@@ -10,11 +10,18 @@ import (
 	"sort"
 )
 
+// AnyLogDir is the log dir value that leaves the choice
+// of log dir for a replica to the broker.
+const AnyLogDir = "any"
+
 // Partition represents the Kafka partition structure.
 type Partition struct {
 	Topic     string `json:"topic"`
 	Partition int    `json:"partition"`
 	Replicas  []int  `json:"replicas"`
+	// LogDirs is optional; if set, it holds
+	// the log dir for each replica position.
+	LogDirs []string `json:"log_dirs,omitempty"`
 }
 
 // PartitionList is a []Partition.
@@ -107,6 +114,29 @@ func (p PartitionList) SortBySize(m PartitionMetaMap) {
 	sort.Sort(partitionsBySize{pl: p, pm: m})
 }
 
+// PartitionMap sort by partition throughput.
+
+type partitionsByThroughput struct {
+	pl PartitionList
+	pm PartitionMetaMap
+}
+
+func (p partitionsByThroughput) Len() int      { return len(p.pl) }
+func (p partitionsByThroughput) Swap(i, j int) { p.pl[i], p.pl[j] = p.pl[j], p.pl[i] }
+func (p partitionsByThroughput) Less(i, j int) bool {
+	t1, _ := p.pm.Throughput(p.pl[i])
+	t2, _ := p.pm.Throughput(p.pl[j])
+
+	if t1 > t2 {
+		return true
+	}
+	if t1 < t2 {
+		return false
+	}
+
+	return p.pl[i].Partition < p.pl[j].Partition
+}
+
 // replicasByLeaderFollowerRatio is used to shuffle replica
 // sets according to the broker leader to follower ratio.
 type replicasByLeaderFollowerRatio struct {
@@ -145,7 +175,9 @@ func (r replicasByLeaderFollowerRatio) Less(i, j int) bool {
 
 // PartitionMeta holds partition metadata.
 type PartitionMeta struct {
-	Size float64 // In bytes.
+	Size     float64 // In bytes.
+	BytesIn  float64 // In bytes/s.
+	BytesOut float64 // In bytes/s.
 }
 
 // PartitionMetaMap is a mapping of topic, partition number to PartitionMeta.
@@ -164,19 +196,41 @@ type ReplicaSets map[int][]int
 // Size takes a Partition and returns the size. An error is returned if
 // the partition isn't in the PartitionMetaMap.
 func (pmm PartitionMetaMap) Size(p Partition) (float64, error) {
+	partn, err := pmm.get(p)
+	if err != nil {
+		return 0.00, err
+	}
+
+	return partn.Size, nil
+}
+
+// Throughput takes a Partition and returns the sum of the bytes in
+// and bytes out rates. An error is returned if the partition isn't
+// in the PartitionMetaMap.
+func (pmm PartitionMetaMap) Throughput(p Partition) (float64, error) {
+	partn, err := pmm.get(p)
+	if err != nil {
+		return 0.00, err
+	}
+
+	return partn.BytesIn + partn.BytesOut, nil
+}
+
+// get returns the *PartitionMeta for a Partition.
+func (pmm PartitionMetaMap) get(p Partition) (*PartitionMeta, error) {
 	// Check for the topic.
 	t, exists := pmm[p.Topic]
 	if !exists {
-		return 0.00, fmt.Errorf("topic '%s' not found in partition metadata", p.Topic)
+		return nil, fmt.Errorf("topic '%s' not found in partition metadata", p.Topic)
 	}
 
 	// Check for the partition.
 	partn, exists := t[p.Partition]
 	if !exists {
-		return 0.00, fmt.Errorf("topic '%s' p%d not found in partition metadata", p.Topic, p.Partition)
+		return nil, fmt.Errorf("topic '%s' p%d not found in partition metadata", p.Topic, p.Partition)
 	}
 
-	return partn.Size, nil
+	return partn, nil
 }
 
 // RebuildParams holds required parameters to call the Rebuild
@@ -190,6 +244,92 @@ type RebuildParams struct {
 	Affinities       SubstitutionAffinities
 	PartnSzFactor    float64
 	MinUniqueRackIDs int
+	// RackLeaderSpread enables spreading
+	// leaders evenly across rack IDs.
+	RackLeaderSpread bool
+	// Policies holds per-topic placement policies.
+	// Brokers not allowed by a topic's policy are
+	// replaced as if marked for replacement.
+	Policies PlacementPolicies
+	// Weights holds the dimension weights
+	// for the weighted strategy.
+	Weights PlacementWeights
+	// AntiAffinities optionally holds topic anti-affinity
+	// groups along with current placements of grouped topics
+	// not in the map being rebuilt.
+	AntiAffinities *TopicAntiAffinities
+	// Cluster optionally holds current placements of partitions
+	// across the cluster. Partitions not in the map being rebuilt
+	// are included when seeding broker throughput.
+	Cluster *PartitionMap
+}
+
+// replaced returns whether the broker ID should be replaced in
+// partitions of the topic, either because the broker is marked for
+// replacement or because it's not allowed by the topic's policy.
+func (params RebuildParams) replaced(topic string, id int) bool {
+	if b, exist := params.BM[id]; exist && b.Replace {
+		return true
+	}
+
+	return !params.Policies.Get(topic).Allows(id)
+}
+
+// retained returns a copy of the *PartitionMap being rebuilt
+// with all brokers to be replaced removed from replica sets.
+func (params RebuildParams) retained() *PartitionMap {
+	retained := params.pm.Copy()
+	for i, partn := range retained.Partitions {
+		var replicas []int
+		for _, id := range partn.Replicas {
+			if !params.replaced(partn.Topic, id) {
+				replicas = append(replicas, id)
+			}
+		}
+		retained.Partitions[i].Replicas = replicas
+	}
+
+	return retained
+}
+
+// load returns the partitions that make up broker throughput loads prior
+// to placement: the retained map being rebuilt along with any Cluster
+// partitions not in it. Cluster partitions without metadata are skipped.
+func (params RebuildParams) load() *PartitionMap {
+	load := params.retained()
+	if params.Cluster == nil {
+		return load
+	}
+
+	type key struct {
+		topic     string
+		partition int
+	}
+
+	inMap := map[key]bool{}
+	for _, partn := range load.Partitions {
+		inMap[key{partn.Topic, partn.Partition}] = true
+	}
+
+	for _, partn := range params.Cluster.Partitions {
+		if inMap[key{partn.Topic, partn.Partition}] {
+			continue
+		}
+
+		if _, err := params.PMM.get(partn); err != nil {
+			continue
+		}
+
+		load.Partitions = append(load.Partitions, partn)
+	}
+
+	return load
+}
+
+// weighted returns whether the weighted strategy is
+// in use with a non-zero weight for the dimension.
+func (params RebuildParams) weighted(dim string) bool {
+	return params.Strategy == "weighted" && params.Weights[dim] > 0
 }
 
 // NewRebuildParams initializes a RebuildParams.
@@ -226,6 +366,13 @@ func (pm *PartitionMap) Rebuild(params RebuildParams) (*PartitionMap, []error) {
 
 	params.pm = pm
 
+	// Track anti-affinities on a copy. Brokers being
+	// replaced no longer host their topics.
+	if params.AntiAffinities != nil {
+		params.AntiAffinities = params.AntiAffinities.Copy()
+		params.AntiAffinities.Set(params.retained())
+	}
+
 	switch params.Strategy {
 	case "count":
 		// Standard sort
@@ -246,27 +393,96 @@ func (pm *PartitionMap) Rebuild(params RebuildParams) (*PartitionMap, []error) {
 			newMap, errs = placeByPosition(params)
 		case "storage":
 			newMap, errs = placeByPartition(params)
-			// Shuffle replica sets. placeByPartition suffers from suboptimal
-			// leadership distribution because of the requirement to choose all
-			// brokers for each partition at a time (in contrast to placeByPosition).
-			// Shuffling has proven so far to distribute leadership even though
-			// it's purely by probability. Eventually, write a real optimizer.
-			newMap.shuffle(func(_ Partition) bool { return true })
+			newMap.distributeLeaders(params)
 		// Invalid optimization.
 		default:
 			return nil, []error{fmt.Errorf("Invalid optimization '%s'", params.Optimization)}
 		}
+	case "throughput":
+		// Set broker throughput from the partitions
+		// being retained and the rest of the cluster.
+		if err := params.BM.SetThroughput(params.load(), params.PMM); err != nil {
+			return nil, []error{err}
+		}
+		// Sort by throughput.
+		sort.Sort(partitionsByThroughput{
+			pl: params.pm.Partitions,
+			pm: params.PMM,
+		})
+		// Perform placements.
+		newMap, errs = placeByPartition(params)
+		newMap.distributeLeaders(params)
+	case "weighted":
+		if err := params.Weights.Validate(); err != nil {
+			return nil, []error{err}
+		}
+		// Set broker throughput from the partitions
+		// being retained and the rest of the cluster.
+		if params.Weights["throughput"] > 0 {
+			if err := params.BM.SetThroughput(params.load(), params.PMM); err != nil {
+				return nil, []error{err}
+			}
+		}
+		// Sort by size if storage is weighted.
+		if params.Weights["storage"] > 0 {
+			sort.Sort(partitionsBySize{
+				pl: params.pm.Partitions,
+				pm: params.PMM,
+			})
+		} else {
+			sort.Sort(params.pm.Partitions)
+		}
+		// Perform placements.
+		newMap, errs = placeByPosition(params)
 	// Invalid placement.
 	default:
 		return nil, []error{fmt.Errorf("Invalid rebuild strategy '%s'", params.Strategy)}
 	}
 
+	// Leader placements in placeByPosition are limited to racks not
+	// already in the replica set; reorder replica sets that had any
+	// replacements to further spread leaders across racks.
+	byPosition := params.Strategy == "count" || params.Strategy == "weighted" ||
+		(params.Strategy == "storage" && params.Optimization == "distribution")
+	if params.RackLeaderSpread && newMap != nil && byPosition {
+		replaced := map[string]map[int]bool{}
+		for _, partn := range params.pm.Partitions {
+			if replaced[partn.Topic] == nil {
+				replaced[partn.Topic] = map[int]bool{}
+			}
+			for _, id := range partn.Replicas {
+				if params.replaced(partn.Topic, id) {
+					replaced[partn.Topic][partn.Partition] = true
+				}
+			}
+		}
+
+		newMap.spreadLeadersByRack(params.BM, func(p Partition) bool {
+			return replaced[p.Topic][p.Partition]
+		})
+	}
+
 	// Final sort.
 	sort.Sort(newMap.Partitions)
 
 	return newMap, errs
 }
 
+// distributeLeaders shuffles replica sets in a *PartitionMap built by
+// placeByPartition. placeByPartition suffers from suboptimal leadership
+// distribution because of the requirement to choose all brokers for each
+// partition at a time (in contrast to placeByPosition). Shuffling has proven
+// so far to distribute leadership even though it's purely by probability.
+// Eventually, write a real optimizer. If rack leader spread is enabled,
+// leaders are instead chosen explicitly by rack and broker leadership counts.
+func (pm *PartitionMap) distributeLeaders(params RebuildParams) {
+	if params.RackLeaderSpread {
+		pm.spreadLeadersByRack(params.BM, func(_ Partition) bool { return true })
+	} else {
+		pm.shuffle(func(_ Partition) bool { return true })
+	}
+}
+
 // placeByPosition builds a PartitionMap by doing placements for all
 // partitions, one broker index at a time. For instance, if all partitions
 // required a broker set length of 3 (aka a replication factor of 3), we'd
@@ -291,6 +507,14 @@ func placeByPosition(params RebuildParams) (*PartitionMap, []error) {
 	var errs []error
 	var pass int
 
+	// Leader counts by rack ID for leaders
+	// not marked for replacement.
+	rackLeaders := rackLeaderCounts(params)
+
+	// Leader counts by broker ID for
+	// weighted placements.
+	brokerLeaders := brokerLeaderCounts(params)
+
 	// Check if we need more passes.
 	// If we've just counted as many skips
 	// as there are partitions to handle,
@@ -321,17 +545,22 @@ func placeByPosition(params RebuildParams) (*PartitionMap, []error) {
 			// If the current broker isn't
 			// marked for removal, just add it
 			// to the same position in the new map.
-			if !params.BM[bid].Replace {
+			if !params.replaced(partn.Topic, bid) {
 				newMap.Partitions[n].Replicas = append(newMap.Partitions[n].Replicas, bid)
 			} else {
 				// Otherwise, we need to find a replacement.
 
 				// Build a BrokerList from the
 				// IDs in the old replica set to
-				// get a *constraints.
+				// get a *constraints. Brokers not
+				// allowed by the topic policy are
+				// being replaced and are excluded.
+				policy := params.Policies.Get(partn.Topic)
 				replicaSet := BrokerList{}
 				for _, bid := range partn.Replicas {
-					replicaSet = append(replicaSet, params.BM[bid])
+					if policy.Allows(bid) {
+						replicaSet = append(replicaSet, params.BM[bid])
+					}
 				}
 				// Add existing brokers in the
 				// new replica set as well.
@@ -343,13 +572,25 @@ func placeByPosition(params RebuildParams) (*PartitionMap, []error) {
 				constraints := NewConstraints()
 				constraintsParams := ConstraintsParams{
 					SelectorMethod:   params.Strategy,
-					MinUniqueRackIDs: params.MinUniqueRackIDs,
+					MinUniqueRackIDs: policy.MinRackIDs(params.MinUniqueRackIDs),
+					Policy:           policy,
+					Topic:            partn.Topic,
+					AntiAffinities:   params.AntiAffinities,
+					Weights:          params.Weights,
+					Leader:           pass == 0,
+					LeaderCounts:     brokerLeaders,
 				}
 				constraints.MergeConstraints(replicaSet)
 
+				// Prefer racks with the fewest
+				// leaders for leader placements.
+				if pass == 0 {
+					constraintsParams.LocalityRank = rackLeaders
+				}
+
 				// Add any necessary meta from current partition
 				// to the constraints.
-				if params.Strategy == "storage" {
+				if params.Strategy == "storage" || params.weighted("storage") {
 					s, err := params.PMM.Size(partn)
 					if err != nil {
 						e := fmt.Errorf("%s p%d: %s", partn.Topic, partn.Partition, err.Error())
@@ -360,6 +601,11 @@ func placeByPosition(params RebuildParams) (*PartitionMap, []error) {
 					constraintsParams.RequestSize = s * params.PartnSzFactor
 				}
 
+				if params.weighted("throughput") {
+					t, _ := params.PMM.Throughput(partn)
+					constraintsParams.RequestThroughput = t
+				}
+
 				// Fetch the best candidate and append.
 				var replacement *Broker
 				var err error
@@ -376,8 +622,8 @@ func placeByPosition(params RebuildParams) (*PartitionMap, []error) {
 					// from ZooKeeper, its rack ID is unknown and a suitable
 					// sub has to be inferred. We're checking that it passes
 					// here in case the inference logic is faulty.
-					if passes := constraints.passesWithParams(replacement, constraintsParams); !passes {
-						err = ErrNoBrokers
+					if r := constraints.rejectionWithParams(replacement, constraintsParams); r != "" {
+						err = noBrokersError(map[string][]int{r: {replacement.ID}})
 					}
 				} else {
 					// Otherwise, use the standard
@@ -395,6 +641,15 @@ func placeByPosition(params RebuildParams) (*PartitionMap, []error) {
 
 				// Add the replacement to the map.
 				newMap.Partitions[n].Replicas = append(newMap.Partitions[n].Replicas, replacement.ID)
+				params.AntiAffinities.Add(partn.Topic, replacement.ID)
+
+				if pass == 0 && rackLeaders != nil {
+					rackLeaders[replacement.Locality]++
+				}
+
+				if pass == 0 && brokerLeaders != nil {
+					brokerLeaders[replacement.ID]++
+				}
 			}
 		}
 
@@ -432,6 +687,10 @@ func placeByPartition(params RebuildParams) (*PartitionMap, []error) {
 
 	var errs []error
 
+	// Leader counts by rack ID for leaders
+	// not marked for replacement.
+	rackLeaders := rackLeaderCounts(params)
+
 	for _, partn := range params.pm.Partitions {
 		// Create the partition in
 		// the new map.
@@ -441,21 +700,26 @@ func placeByPartition(params RebuildParams) (*PartitionMap, []error) {
 		// partition replica list to the new,
 		// selecting replacemnt for those marked
 		// for replacement.
-		for _, bid := range partn.Replicas {
+		for i, bid := range partn.Replicas {
 			// If the current broker isn't
 			// marked for removal, just add it
 			// to the same position in the new map.
-			if !params.BM[bid].Replace {
+			if !params.replaced(partn.Topic, bid) {
 				newPartn.Replicas = append(newPartn.Replicas, bid)
 			} else {
 				// Otherwise, we need to find a replacement.
 
 				// Build a BrokerList from the
 				// IDs in the old replica set to
-				// get a *constraints.
+				// get a *constraints. Brokers not
+				// allowed by the topic policy are
+				// being replaced and are excluded.
+				policy := params.Policies.Get(partn.Topic)
 				replicaSet := BrokerList{}
 				for _, bid := range partn.Replicas {
-					replicaSet = append(replicaSet, params.BM[bid])
+					if policy.Allows(bid) {
+						replicaSet = append(replicaSet, params.BM[bid])
+					}
 				}
 				// Add existing brokers in the
 				// new replica set as well.
@@ -467,14 +731,23 @@ func placeByPartition(params RebuildParams) (*PartitionMap, []error) {
 				constraints := NewConstraints()
 				constraintsParams := ConstraintsParams{
 					SelectorMethod:   params.Strategy,
-					MinUniqueRackIDs: params.MinUniqueRackIDs,
+					MinUniqueRackIDs: policy.MinRackIDs(params.MinUniqueRackIDs),
 					SeedVal:          1,
+					Policy:           policy,
+					Topic:            partn.Topic,
+					AntiAffinities:   params.AntiAffinities,
 				}
 				constraints.MergeConstraints(replicaSet)
 
+				// Prefer racks with the fewest
+				// leaders for leader placements.
+				if i == 0 {
+					constraintsParams.LocalityRank = rackLeaders
+				}
+
 				// Add any necessary meta from current partition
 				// to the constraints.
-				if params.Strategy == "storage" {
+				if params.Strategy == "storage" || params.Strategy == "throughput" {
 					s, err := params.PMM.Size(partn)
 					if err != nil {
 						e := fmt.Errorf("%s p%d: %s", partn.Topic, partn.Partition, err.Error())
@@ -485,6 +758,11 @@ func placeByPartition(params RebuildParams) (*PartitionMap, []error) {
 					constraintsParams.RequestSize = s * params.PartnSzFactor
 				}
 
+				if params.Strategy == "throughput" {
+					t, _ := params.PMM.Throughput(partn)
+					constraintsParams.RequestThroughput = t
+				}
+
 				// Fetch the best candidate and append.
 				replacement, err := constraints.SelectBroker(bl, constraintsParams)
 
@@ -496,6 +774,11 @@ func placeByPartition(params RebuildParams) (*PartitionMap, []error) {
 				}
 
 				newPartn.Replicas = append(newPartn.Replicas, replacement.ID)
+				params.AntiAffinities.Add(partn.Topic, replacement.ID)
+
+				if i == 0 && rackLeaders != nil {
+					rackLeaders[replacement.Locality]++
+				}
 			}
 		}
 
@@ -581,6 +864,115 @@ func (pm *PartitionMap) LocalitiesAvailable(bm BrokerMap, b *Broker) []string {
 	return diff
 }
 
+// rackLeaderCounts returns the number of leaders per rack ID in the
+// RebuildParams PartitionMap, excluding brokers being replaced.
+// If RackLeaderSpread isn't enabled, nil is returned.
+func rackLeaderCounts(params RebuildParams) map[string]int {
+	if !params.RackLeaderSpread {
+		return nil
+	}
+
+	counts := map[string]int{}
+
+	for _, partn := range params.pm.Partitions {
+		if len(partn.Replicas) == 0 {
+			continue
+		}
+
+		id := partn.Replicas[0]
+		if b, exist := params.BM[id]; exist && !params.replaced(partn.Topic, id) {
+			counts[b.Locality]++
+		}
+	}
+
+	return counts
+}
+
+// brokerLeaderCounts returns the number of leaders by broker ID for
+// leaders in the map being rebuilt that aren't marked for replacement.
+// A nil map is returned if the weighted strategy isn't in use.
+func brokerLeaderCounts(params RebuildParams) map[int]int {
+	if params.Strategy != "weighted" {
+		return nil
+	}
+
+	counts := map[int]int{}
+
+	for _, partn := range params.pm.Partitions {
+		if len(partn.Replicas) == 0 {
+			continue
+		}
+
+		id := partn.Replicas[0]
+		if !params.replaced(partn.Topic, id) {
+			counts[id]++
+		}
+	}
+
+	return counts
+}
+
+// spreadLeadersByRack takes a BrokerMap and a partition filter function
+// and reorders the replica sets of matching partitions so that leadership
+// is spread evenly across rack IDs. Leaders of non-matching partitions are
+// counted but left as-is. For each matching partition, the replica from the
+// rack with the fewest leaders (and the broker with the fewest leaders
+// within that rack) is moved to the first position. Counts are accumulated
+// as partitions are visited.
+func (pm *PartitionMap) spreadLeadersByRack(bm BrokerMap, f func(Partition) bool) {
+	rackLeaders := map[string]int{}
+	brokerLeaders := map[int]int{}
+
+	// Count fixed leaders.
+	for _, partn := range pm.Partitions {
+		if len(partn.Replicas) == 0 || f(partn) {
+			continue
+		}
+
+		if b, exist := bm[partn.Replicas[0]]; exist {
+			rackLeaders[b.Locality]++
+		}
+		brokerLeaders[partn.Replicas[0]]++
+	}
+
+	for n := range pm.Partitions {
+		replicas := pm.Partitions[n].Replicas
+		if len(replicas) == 0 || !f(pm.Partitions[n]) {
+			continue
+		}
+
+		best := 0
+		for i, id := range replicas {
+			b, exist := bm[id]
+			if !exist {
+				continue
+			}
+
+			bb, exist := bm[replicas[best]]
+			if !exist {
+				best = i
+				continue
+			}
+
+			r, rb := rackLeaders[b.Locality], rackLeaders[bb.Locality]
+			if r < rb || (r == rb && brokerLeaders[id] < brokerLeaders[replicas[best]]) {
+				best = i
+			}
+		}
+
+		// Move the selected leader to the front,
+		// preserving the order of the followers.
+		leader := replicas[best]
+		copy(replicas[1:best+1], replicas[:best])
+		replicas[0] = leader
+
+		if b, exist := bm[leader]; exist {
+			rackLeaders[b.Locality]++
+		}
+		brokerLeaders[leader]++
+	}
+}
+
 func (pm *PartitionMap) shuffle(f func(Partition) bool) {
 	var s int
 	for n := range pm.Partitions {
@@ -649,21 +1041,44 @@ func (pm *PartitionMap) SetReplication(r int) {
 		return
 	}
 
+	for n := range pm.Partitions {
+		pm.setReplication(n, r)
+	}
+}
+
+// SetTopicReplication takes a topic name and replication factor r and
+// normalizes the replica sets of the topic's partitions to length r in
+// the same manner as SetReplication.
+func (pm *PartitionMap) SetTopicReplication(topic string, r int) {
+	// 0 is a no-op.
+	if r == 0 {
+		return
+	}
+
 	for n, p := range pm.Partitions {
-		l := len(p.Replicas)
+		if p.Topic == topic {
+			pm.setReplication(n, r)
+		}
+	}
+}
 
-		switch {
-		// Truncate replicas beyond r.
-		case l > r:
-			pm.Partitions[n].Replicas = p.Replicas[:r]
-		// Add stub brokers to meet r.
-		case l < r:
-			r := make([]int, r-l)
-			for i := 0; i < len(r); i++ {
-				r[i] = StubBrokerID
-			}
-			pm.Partitions[n].Replicas = append(p.Replicas, r...)
+// setReplication resets the replica set of the
+// partition at index n to the replication factor r.
+func (pm *PartitionMap) setReplication(n, r int) {
+	p := pm.Partitions[n]
+	l := len(p.Replicas)
+
+	switch {
+	// Truncate replicas beyond r.
+	case l > r:
+		pm.Partitions[n].Replicas = p.Replicas[:r]
+	// Add stub brokers to meet r.
+	case l < r:
+		r := make([]int, r-l)
+		for i := 0; i < len(r); i++ {
+			r[i] = StubBrokerID
 		}
+		pm.Partitions[n].Replicas = append(p.Replicas, r...)
 	}
 }
 
@@ -711,6 +1126,12 @@ func (pm *PartitionMap) Copy() *PartitionMap {
 		}
 
 		copy(part.Replicas, p.Replicas)
+
+		if p.LogDirs != nil {
+			part.LogDirs = make([]string, len(p.LogDirs))
+			copy(part.LogDirs, p.LogDirs)
+		}
+
 		cpy.Partitions = append(cpy.Partitions, part)
 	}
 
@@ -823,7 +1244,7 @@ func (pm *PartitionMap) UseStats() BrokerUseStatsMap {
 }
 
 // Equal defines equalty between two Partition objects
-// as an equality of topic, partition and replicas.
+// as an equality of topic, partition, replicas and log dirs.
 func (p Partition) Equal(p2 Partition) bool {
 	switch {
 	case p.Topic != p2.Topic:
@@ -838,7 +1259,33 @@ func (p Partition) Equal(p2 Partition) bool {
 		if p.Replicas[i] != p2.Replicas[i] {
 			return false
 		}
+
+		if p.LogDir(i) != p2.LogDir(i) {
+			return false
+		}
 	}
 
 	return true
 }
+
+// LogDir returns the log dir for the replica at position i.
+// AnyLogDir is returned if no log dir is set.
+func (p Partition) LogDir(i int) string {
+	if i < len(p.LogDirs) && p.LogDirs[i] != "" {
+		return p.LogDirs[i]
+	}
+
+	return AnyLogDir
+}
+
+// HasLogDirs returns whether a log dir other
+// than AnyLogDir is set for any replica.
+func (p Partition) HasLogDirs() bool {
+	for i := range p.LogDirs {
+		if p.LogDir(i) != AnyLogDir {
+			return true
+		}
+	}
+
+	return false
+}
@@ -55,6 +55,38 @@ func TestPartitionEquality(t *testing.T) {
 	if p1.Equal(p5) {
 		t.Error("Unexpected equality between p1 and p5")
 	}
+
+	// Unset log dirs are equal to AnyLogDir.
+	p2.LogDirs = []string{AnyLogDir, AnyLogDir, AnyLogDir}
+	if !p1.Equal(p2) {
+		t.Error("Unexpected inequality between p1 and p2")
+	}
+
+	p2.LogDirs[1] = "/data2"
+	if p1.Equal(p2) {
+		t.Error("Unexpected equality between p1 and p2")
+	}
+}
+
+func TestPartitionLogDir(t *testing.T) {
+	p := Partition{Topic: "test_topic", Partition: 1, Replicas: []int{1, 2, 3}}
+
+	if p.LogDir(0) != AnyLogDir || p.HasLogDirs() {
+		t.Error("Expected no log dirs")
+	}
+
+	p.LogDirs = []string{AnyLogDir, "/data1", ""}
+
+	expected := []string{AnyLogDir, "/data1", AnyLogDir}
+	for i := range p.Replicas {
+		if dir := p.LogDir(i); dir != expected[i] {
+			t.Errorf("Expected log dir %s, got %s", expected[i], dir)
+		}
+	}
+
+	if !p.HasLogDirs() {
+		t.Error("Expected log dirs")
+	}
 }
 
 func testGetMapString(n string) string {
@@ -135,6 +167,31 @@ func TestSize(t *testing.T) {
 	}
 }
 
+func TestThroughput(t *testing.T) {
+	z := &Mock{}
+
+	pm, _ := z.GetPartitionMap("test_topic")
+	pmm, _ := z.GetAllPartitionMeta()
+	pmm["test_topic"][0].BytesIn = 100
+	pmm["test_topic"][0].BytesOut = 250
+
+	tp, err := pmm.Throughput(pm.Partitions[0])
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	if tp != 350.00 {
+		t.Errorf("Expected throughput result 350.00, got %f", tp)
+	}
+
+	// Missing partition.
+	delete(pmm["test_topic"], 3)
+	_, err = pmm.Throughput(pm.Partitions[3])
+	if err == nil {
+		t.Error("Expected error")
+	}
+}
+
 func TestSortBySize(t *testing.T) {
 	z := &Mock{}
 
@@ -257,6 +314,15 @@ func TestPartitionMapCopy(t *testing.T) {
 	if same, _ := pm.Equal(pm2); same {
 		t.Error("Unexpected equality")
 	}
+
+	// Log dirs are copied.
+	pm.Partitions[0].LogDirs = []string{"/data1", AnyLogDir}
+	pm3 := pm.Copy()
+	pm.Partitions[0].LogDirs[0] = "/data2"
+
+	if dir := pm3.Partitions[0].LogDir(0); dir != "/data1" {
+		t.Errorf("Expected log dir /data1, got %s", dir)
+	}
 }
 
 func TestPartitionMapFromString(t *testing.T) {
@@ -355,6 +421,29 @@ func TestSetReplication(t *testing.T) {
 	}
 }
 
+func TestSetTopicReplication(t *testing.T) {
+	pm, _ := PartitionMapFromString(testGetMapString5("test_topic"))
+
+	pm.SetTopicReplication("test_topic1", 3)
+	pm.SetTopicReplication("test_topic2", 1)
+
+	for _, partn := range pm.Partitions {
+		expected := 3
+		if partn.Topic == "test_topic2" {
+			expected = 1
+		}
+
+		if len(partn.Replicas) != expected {
+			t.Errorf("%s p%d: expected replication %d, got %d",
+				partn.Topic, partn.Partition, expected, len(partn.Replicas))
+		}
+	}
+
+	if pm.Partitions[0].Replicas[2] != StubBrokerID {
+		t.Errorf("Expected stub broker, got %d", pm.Partitions[0].Replicas[2])
+	}
+}
+
 func TestStrip(t *testing.T) {
 	pm, _ := PartitionMapFromString(testGetMapString("test_topic"))
 
@@ -643,6 +732,134 @@ func TestRebuildByStorageStorage(t *testing.T) {
 	}
 }
 
+func TestRebuildByThroughput(t *testing.T) {
+	zk := &Mock{}
+	bm, _ := zk.GetAllBrokerMeta(false)
+	pm, _ := PartitionMapFromString(testGetMapString4("test_topic"))
+	pmm, _ := zk.GetAllPartitionMeta()
+
+	// p5 carries as much throughput
+	// as all other partitions combined.
+	for i, partn := range pmm["test_topic"] {
+		partn.BytesIn = 50
+		partn.BytesOut = 50
+		if i == 5 {
+			partn.BytesIn, partn.BytesOut = 250, 250
+		}
+	}
+
+	brokers := BrokerMapFromPartitionMap(pm, bm, true)
+	for _, b := range brokers {
+		b.StorageFree = 100000.00
+	}
+
+	rebuildParams := RebuildParams{
+		PMM:           pmm,
+		BM:            brokers,
+		Strategy:      "throughput",
+		PartnSzFactor: 1,
+	}
+
+	out, errs := pm.Strip().Rebuild(rebuildParams)
+	if errs != nil {
+		t.Fatalf("Unexpected error(s): %s", errs)
+	}
+
+	// Brokers holding p5 should hold nothing else.
+	p5 := map[int]bool{}
+	for _, id := range out.Partitions[5].Replicas {
+		p5[id] = true
+	}
+
+	for _, partn := range out.Partitions[:5] {
+		for _, id := range partn.Replicas {
+			if p5[id] {
+				t.Errorf("p%d: unexpected broker %d", partn.Partition, id)
+			}
+		}
+	}
+
+	// Disk limits hold.
+	brokers = BrokerMapFromPartitionMap(pm, bm, true)
+	for _, b := range brokers {
+		b.StorageFree = 100000.00
+	}
+	brokers[1003].StorageFree = 500.00
+
+	rebuildParams.BM = brokers
+
+	out, errs = pm.Strip().Rebuild(rebuildParams)
+	if errs != nil {
+		t.Fatalf("Unexpected error(s): %s", errs)
+	}
+
+	for _, partn := range out.Partitions {
+		for _, id := range partn.Replicas {
+			if id == 1003 {
+				t.Errorf("p%d: unexpected broker 1003", partn.Partition)
+			}
+		}
+	}
+}
+
+func TestRebuildByThroughputCluster(t *testing.T) {
+	zk := &Mock{}
+	bm, _ := zk.GetAllBrokerMeta(false)
+	pm, _ := PartitionMapFromString(testGetMapString4("test_topic"))
+	pmm, _ := zk.GetAllPartitionMeta()
+
+	for _, partn := range pmm["test_topic"] {
+		partn.BytesIn, partn.BytesOut = 50, 50
+	}
+
+	// test_topic2 p0 on 1001 and 1002 carries more
+	// throughput than all of test_topic combined.
+	pmm["test_topic2"] = map[int]*PartitionMeta{
+		0: &PartitionMeta{Size: 1000.00, BytesIn: 1000},
+	}
+
+	// test_topic partitions are ignored and
+	// test_topic3 has no metadata.
+	cluster, _ := PartitionMapFromString(testGetMapString4("test_topic"))
+	cluster.Partitions = append(cluster.Partitions,
+		Partition{Topic: "test_topic2", Partition: 0, Replicas: []int{1001, 1002}},
+		Partition{Topic: "test_topic3", Partition: 0, Replicas: []int{1003, 1004}},
+	)
+
+	brokers := BrokerMapFromPartitionMap(pm, bm, true)
+	for _, b := range brokers {
+		b.StorageFree = 100000.00
+	}
+
+	rebuildParams := RebuildParams{
+		PMM:           pmm,
+		BM:            brokers,
+		Strategy:      "throughput",
+		PartnSzFactor: 1,
+		Cluster:       cluster,
+		pm:            pm.Strip(),
+	}
+
+	if l := len(rebuildParams.load().Partitions); l != 7 {
+		t.Errorf("Expected 7 load partitions, got %d", l)
+	}
+
+	out, errs := pm.Strip().Rebuild(rebuildParams)
+	if errs != nil {
+		t.Fatalf("Unexpected error(s): %s", errs)
+	}
+
+	// Brokers holding test_topic2 p0 are
+	// already loaded beyond all others.
+	for _, partn := range out.Partitions {
+		for _, id := range partn.Replicas {
+			if id == 1001 || id == 1002 {
+				t.Errorf("p%d: unexpected broker %d", partn.Partition, id)
+			}
+		}
+	}
+}
+
 func TestLocalitiesAvailable(t *testing.T) {
 	pm, _ := PartitionMapFromString(testGetMapString("test_topic"))
 	bm := newMockBrokerMap()
@@ -679,6 +896,78 @@ func TestOptimizeLeaderFollower(t *testing.T) {
 	}
 }
 
+func TestRebuildRackLeaderSpread(t *testing.T) {
+	pm, _ := PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":1,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":2,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":3,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":4,"replicas":[1001,1002]},
+		{"topic":"test_topic","partition":5,"replicas":[1001,1002]}]}`)
+
+	for _, optimization := range []string{"distribution", "storage"} {
+		bm := newMockBrokerMap2()
+		// Replace all leaders.
+		bm[1001].Replace = true
+
+		pmm := NewPartitionMetaMap()
+		pmm["test_topic"] = map[int]*PartitionMeta{}
+		for i := 0; i < 6; i++ {
+			pmm["test_topic"][i] = &PartitionMeta{Size: 10}
+		}
+
+		rebuildParams := RebuildParams{
+			PMM:              pmm,
+			BM:               bm,
+			Strategy:         "storage",
+			Optimization:     optimization,
+			RackLeaderSpread: true,
+		}
+
+		out, errs := pm.Copy().Rebuild(rebuildParams)
+		if errs != nil {
+			t.Errorf("Unexpected error(s): %s", errs)
+		}
+
+		leaders := map[string]int{}
+		for _, partn := range out.Partitions {
+			leaders[bm[partn.Replicas[0]].Locality]++
+		}
+
+		// Racks a, b and c should have
+		// 2 leaders each.
+		for _, rack := range []string{"a", "b", "c"} {
+			if leaders[rack] != 2 {
+				t.Errorf("[%s] Expected 2 leaders for rack %s, got %d", optimization, rack, leaders[rack])
+			}
+		}
+	}
+}
+
+func TestSpreadLeadersByRack(t *testing.T) {
+	pm, _ := PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test_topic","partition":0,"replicas":[1001,1002,1003]},
+		{"topic":"test_topic","partition":1,"replicas":[1001,1002,1003]},
+		{"topic":"test_topic","partition":2,"replicas":[1001,1002,1003]},
+		{"topic":"test_topic","partition":3,"replicas":[1004,1005,1006]},
+		{"topic":"test_topic","partition":4,"replicas":[1004,1005,1006]},
+		{"topic":"test_topic","partition":5,"replicas":[1004,1005,1006]}]}`)
+
+	bm := newMockBrokerMap2()
+	pm.spreadLeadersByRack(bm, func(_ Partition) bool { return true })
+
+	expected := []int{1001, 1002, 1003, 1004, 1005, 1006}
+	for i, partn := range pm.Partitions {
+		if partn.Replicas[0] != expected[i] {
+			t.Errorf("Expected leader %d for p%d, got %d", expected[i], i, partn.Replicas[0])
+		}
+
+		if len(partn.Replicas) != 3 {
+			t.Errorf("Unexpected replica set length for p%d", i)
+		}
+	}
+}
+
 func TestShuffle(t *testing.T) {
 	pm, _ := PartitionMapFromString(testGetMapString("test_topic"))
 
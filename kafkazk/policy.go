@@ -0,0 +1,51 @@
+package kafkazk
+
+// PlacementPolicy holds topic level placement constraints.
+type PlacementPolicy struct {
+	// If non-empty, only these broker IDs are eligible.
+	Brokers map[int]bool
+	// Broker IDs that are never eligible.
+	ExcludedBrokers map[int]bool
+	// Broker IDs reserved for other topics; never eligible.
+	ReservedBrokers map[int]bool
+	// If non-nil, overrides the global MinUniqueRackIDs.
+	MinUniqueRackIDs *int
+}
+
+// PlacementPolicies is a mapping of topic names to *PlacementPolicy.
+type PlacementPolicies map[string]*PlacementPolicy
+
+// Get returns the *PlacementPolicy for a topic. A nil
+// *PlacementPolicy is returned if no policy is set.
+func (p PlacementPolicies) Get(topic string) *PlacementPolicy {
+	if p == nil {
+		return nil
+	}
+
+	return p[topic]
+}
+
+// Allows returns whether the broker ID is eligible
+// for placement under the *PlacementPolicy. A nil
+// *PlacementPolicy allows all brokers.
+func (p *PlacementPolicy) Allows(id int) bool {
+	if p == nil {
+		return true
+	}
+
+	if len(p.Brokers) > 0 && !p.Brokers[id] {
+		return false
+	}
+
+	return !p.ExcludedBrokers[id] && !p.ReservedBrokers[id]
+}
+
+// MinRackIDs returns the MinUniqueRackIDs override if set,
+// otherwise the provided default value.
+func (p *PlacementPolicy) MinRackIDs(d int) int {
+	if p == nil || p.MinUniqueRackIDs == nil {
+		return d
+	}
+
+	return *p.MinUniqueRackIDs
+}
@@ -0,0 +1,77 @@
+package kafkazk
+
+import (
+	"testing"
+)
+
+func TestPlacementPolicyAllows(t *testing.T) {
+	var nilPolicy *PlacementPolicy
+	if !nilPolicy.Allows(1001) {
+		t.Error("Expected nil policy to allow all brokers")
+	}
+
+	p := &PlacementPolicy{
+		Brokers:         map[int]bool{1001: true, 1002: true},
+		ExcludedBrokers: map[int]bool{1002: true},
+	}
+
+	expected := map[int]bool{1001: true, 1002: false, 1003: false}
+	for id, allowed := range expected {
+		if p.Allows(id) != allowed {
+			t.Errorf("Expected Allows(%d) == %v", id, allowed)
+		}
+	}
+}
+
+func TestPlacementPolicyMinRackIDs(t *testing.T) {
+	var nilPolicy *PlacementPolicy
+	if nilPolicy.MinRackIDs(2) != 2 {
+		t.Error("Expected default value")
+	}
+
+	n := 3
+	p := &PlacementPolicy{MinUniqueRackIDs: &n}
+	if p.MinRackIDs(2) != 3 {
+		t.Error("Expected override value")
+	}
+}
+
+func TestRebuildWithPolicy(t *testing.T) {
+	zk := &Mock{}
+	bm, _ := zk.GetAllBrokerMeta(false)
+	pm, _ := PartitionMapFromString(testGetMapString("test_topic"))
+	brokers := BrokerMapFromPartitionMap(pm, bm, false)
+	brokers.Update([]int{1001, 1002, 1003, 1004, 1005}, bm)
+
+	rebuildParams := RebuildParams{
+		PMM:          NewPartitionMetaMap(),
+		BM:           brokers,
+		Strategy:     "count",
+		Optimization: "distribution",
+		Policies: PlacementPolicies{
+			"test_topic": &PlacementPolicy{
+				ExcludedBrokers: map[int]bool{1004: true},
+			},
+		},
+	}
+
+	out, errs := pm.Rebuild(rebuildParams)
+	if errs != nil {
+		t.Errorf("Unexpected error(s): %s", errs)
+	}
+
+	for _, partn := range out.Partitions {
+		for _, id := range partn.Replicas {
+			if id == 1004 {
+				t.Errorf("%s p%d: unexpected broker 1004", partn.Topic, partn.Partition)
+			}
+		}
+	}
+
+	// Unaffected partitions should be unchanged.
+	for i := 0; i < 2; i++ {
+		if !out.Partitions[i].Equal(pm.Partitions[i]) {
+			t.Errorf("Unexpected change to p%d", i)
+		}
+	}
+}
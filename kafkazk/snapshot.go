@@ -0,0 +1,389 @@
+package kafkazk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrSnapshotReadOnly error.
+	ErrSnapshotReadOnly = errors.New("Snapshots are read-only")
+	// ErrSnapshotNoZnodes error.
+	ErrSnapshotNoZnodes = errors.New("Snapshots do not support raw znode operations")
+)
+
+// Snapshot is a point in time copy of the ZooKeeper data
+// referenced by kafkazk: registered brokers, topic states,
+// partition states, topic configs and any topicmappr broker
+// metrics and partition metadata.
+type Snapshot struct {
+	Version int `json:"version"`
+	// Unix epoch ns.
+	CreatedAt       int64                    `json:"created_at"`
+	Brokers         BrokerMetaMap            `json:"brokers"`
+	Topics          map[string]*TopicState   `json:"topics"`
+	PartitionStates map[string]TopicStateISR `json:"partition_states"`
+	TopicConfigs    map[string]*TopicConfig  `json:"topic_configs"`
+	Reassignments   Reassignments            `json:"reassignments"`
+	PendingDeletion []string                 `json:"pending_deletion"`
+	BrokerMetrics   BrokerMetricsMap         `json:"brokermetrics,omitempty"`
+	PartitionMeta   PartitionMetaMap         `json:"partitionmeta,omitempty"`
+	// The oldest brokermetrics and partitionmeta
+	// mtime as a unix epoch ns.
+	MetaTimestamp int64 `json:"meta_timestamp,omitempty"`
+}
+
+// TakeSnapshot takes a Handler and returns a *Snapshot of all
+// brokers and topics. Broker metrics and partition metadata are
+// optional and omitted from the *Snapshot if unavailable.
+func TakeSnapshot(zk Handler) (*Snapshot, error) {
+	s := &Snapshot{
+		Version:         1,
+		CreatedAt:       time.Now().UnixNano(),
+		Topics:          map[string]*TopicState{},
+		PartitionStates: map[string]TopicStateISR{},
+		TopicConfigs:    map[string]*TopicConfig{},
+		Reassignments:   zk.GetReassignments(),
+	}
+
+	// Brokers. Metrics are stored separately
+	// from the broker metadata.
+	bmm, errs := zk.GetAllBrokerMeta(true)
+	if bmm == nil {
+		bmm, errs = zk.GetAllBrokerMeta(false)
+		if len(errs) > 0 {
+			return nil, errs[0]
+		}
+	} else {
+		s.BrokerMetrics = BrokerMetricsMap{}
+	}
+
+	s.Brokers = BrokerMetaMap{}
+	for id, m := range bmm {
+		meta := *m
+		if s.BrokerMetrics != nil && !meta.MetricsIncomplete {
+			s.BrokerMetrics[id] = &BrokerMetrics{
+				StorageFree:  meta.StorageFree,
+				StorageTotal: meta.StorageTotal,
+				LogDirs:      meta.LogDirs.Copy(),
+				InstanceType: meta.InstanceType,
+			}
+		}
+		meta.StorageFree, meta.StorageTotal, meta.MetricsIncomplete = 0, 0, false
+		meta.LogDirs, meta.InstanceType = nil, ""
+		s.Brokers[id] = &meta
+	}
+
+	// Topics.
+	topics, err := zk.GetTopics([]*regexp.Regexp{regexp.MustCompile(".*")})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range topics {
+		ts, err := zk.GetTopicState(t)
+		if err != nil {
+			return nil, err
+		}
+		s.Topics[t] = ts
+
+		isr, err := zk.GetTopicStateISR(t)
+		if err != nil {
+			return nil, err
+		}
+		s.PartitionStates[t] = isr
+
+		// Topics without overrides may not have a config.
+		if tc, err := zk.GetTopicConfig(t); err == nil {
+			s.TopicConfigs[t] = tc
+		}
+	}
+
+	if s.PendingDeletion, err = zk.GetPendingDeletion(); err != nil {
+		return nil, err
+	}
+
+	// Metrics.
+	if pmm, err := zk.GetAllPartitionMeta(); err == nil {
+		s.PartitionMeta = pmm
+	}
+
+	if age, err := zk.MaxMetaAge(); err == nil {
+		s.MetaTimestamp = s.CreatedAt - age.Nanoseconds()
+	}
+
+	return s, nil
+}
+
+// SnapshotHandler implements the Handler interface
+// for a *Snapshot. All write operations return an
+// ErrSnapshotReadOnly.
+type SnapshotHandler struct {
+	s *Snapshot
+}
+
+// NewSnapshotHandler takes a *Snapshot and returns a Handler.
+func NewSnapshotHandler(s *Snapshot) Handler {
+	return &SnapshotHandler{s: s}
+}
+
+// NewSnapshotHandlerFromFile reads a JSON *Snapshot
+// from the file path p and returns a Handler.
+func NewSnapshotHandlerFromFile(p string) (Handler, error) {
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling snapshot: %s", err)
+	}
+
+	return NewSnapshotHandler(s), nil
+}
+
+// Ready returns true.
+func (z *SnapshotHandler) Ready() bool {
+	return true
+}
+
+// Close is a no-op.
+func (z *SnapshotHandler) Close() {}
+
+// Get returns an ErrSnapshotNoZnodes.
+func (z *SnapshotHandler) Get(p string) ([]byte, error) {
+	return nil, ErrSnapshotNoZnodes
+}
+
+// Set returns an ErrSnapshotReadOnly.
+func (z *SnapshotHandler) Set(p string, d string) error {
+	return ErrSnapshotReadOnly
+}
+
+// Delete returns an ErrSnapshotReadOnly.
+func (z *SnapshotHandler) Delete(p string) error {
+	return ErrSnapshotReadOnly
+}
+
+// CreateSequential returns an ErrSnapshotReadOnly.
+func (z *SnapshotHandler) CreateSequential(p string, d string) error {
+	return ErrSnapshotReadOnly
+}
+
+// Create returns an ErrSnapshotReadOnly.
+func (z *SnapshotHandler) Create(p string, d string) error {
+	return ErrSnapshotReadOnly
+}
+
+// Exists returns an ErrSnapshotNoZnodes.
+func (z *SnapshotHandler) Exists(p string) (bool, error) {
+	return false, ErrSnapshotNoZnodes
+}
+
+// Children returns an ErrSnapshotNoZnodes.
+func (z *SnapshotHandler) Children(p string) ([]string, error) {
+	return nil, ErrSnapshotNoZnodes
+}
+
+// GetReassignments returns any reassignments
+// ongoing at the time of the snapshot.
+func (z *SnapshotHandler) GetReassignments() Reassignments {
+	reassigns := Reassignments{}
+	for t, partns := range z.s.Reassignments {
+		reassigns[t] = map[int][]int{}
+		for p, replicas := range partns {
+			reassigns[t][p] = copyInts(replicas)
+		}
+	}
+
+	return reassigns
+}
+
+// ListReassignments returns any reassignments
+// ongoing at the time of the snapshot.
+func (z *SnapshotHandler) ListReassignments() (Reassignments, error) {
+	return z.GetReassignments(), nil
+}
+
+// CreateReassignments returns an ErrSnapshotReadOnly.
+func (z *SnapshotHandler) CreateReassignments(pm *PartitionMap) error {
+	return ErrSnapshotReadOnly
+}
+
+// GetPendingDeletion returns any topics pending deletion.
+func (z *SnapshotHandler) GetPendingDeletion() ([]string, error) {
+	return append([]string{}, z.s.PendingDeletion...), nil
+}
+
+// GetTopics takes a []*regexp.Regexp and returns a []string of all topic
+// names that match any of the provided regex.
+func (z *SnapshotHandler) GetTopics(ts []*regexp.Regexp) ([]string, error) {
+	matchingTopics := []string{}
+
+	for topic := range z.s.Topics {
+		for _, topicRe := range ts {
+			if topicRe.MatchString(topic) {
+				matchingTopics = append(matchingTopics, topic)
+				break
+			}
+		}
+	}
+
+	return matchingTopics, nil
+}
+
+// GetTopicConfig takes a topic name. If the topic exists, the topic config
+// is returned as a *TopicConfig.
+func (z *SnapshotHandler) GetTopicConfig(t string) (*TopicConfig, error) {
+	tc, exist := z.s.TopicConfigs[t]
+	if !exist {
+		return nil, ErrNoNode{s: fmt.Sprintf("[%s] topic config not found in snapshot", t)}
+	}
+
+	config := &TopicConfig{Version: tc.Version, Config: map[string]string{}}
+	for k, v := range tc.Config {
+		config.Config[k] = v
+	}
+
+	return config, nil
+}
+
+// GetAllBrokerMeta returns the metadata of all registered Kafka brokers as
+// a BrokerMetaMap. A withMetrics bool param determines whether we
+// additionally want to populate broker metrics.
+func (z *SnapshotHandler) GetAllBrokerMeta(withMetrics bool) (BrokerMetaMap, []error) {
+	var errs []error
+
+	if withMetrics && z.s.BrokerMetrics == nil {
+		return nil, []error{errors.New("Error fetching broker metrics: not found in snapshot")}
+	}
+
+	bmm := BrokerMetaMap{}
+	for id, m := range z.s.Brokers {
+		meta := *m
+		bmm[id] = &meta
+
+		if !withMetrics {
+			continue
+		}
+
+		if bm, exists := z.s.BrokerMetrics[id]; exists {
+			meta.StorageFree = bm.StorageFree
+			meta.StorageTotal = bm.StorageTotal
+			meta.LogDirs = bm.LogDirs.Copy()
+			meta.InstanceType = bm.InstanceType
+		} else {
+			errs = append(errs, fmt.Errorf("Metrics not found for broker %d", id))
+			meta.MetricsIncomplete = true
+		}
+	}
+
+	return bmm, errs
+}
+
+// GetAllPartitionMeta returns partition metadata.
+func (z *SnapshotHandler) GetAllPartitionMeta() (PartitionMetaMap, error) {
+	if z.s.PartitionMeta == nil {
+		return nil, errors.New("No partition meta")
+	}
+
+	pmm := NewPartitionMetaMap()
+	for t, partns := range z.s.PartitionMeta {
+		pmm[t] = map[int]*PartitionMeta{}
+		for p, m := range partns {
+			meta := *m
+			pmm[t][p] = &meta
+		}
+	}
+
+	return pmm, nil
+}
+
+// MaxMetaAge returns the greatest age between the partitionmeta
+// and brokermetrics stuctures as of the time of the snapshot.
+// The snapshot age itself is not included.
+func (z *SnapshotHandler) MaxMetaAge() (time.Duration, error) {
+	if z.s.MetaTimestamp == 0 {
+		return time.Nanosecond, ErrNoNode{s: "metrics metadata not found in snapshot"}
+	}
+
+	return time.Duration(z.s.CreatedAt - z.s.MetaTimestamp), nil
+}
+
+// GetTopicState takes a topic name. If the topic exists,
+// the topic state is returned as a *TopicState.
+func (z *SnapshotHandler) GetTopicState(t string) (*TopicState, error) {
+	state, exist := z.s.Topics[t]
+	if !exist {
+		return nil, ErrNoNode{s: fmt.Sprintf("[%s] topic not found in snapshot", t)}
+	}
+
+	ts := &TopicState{Partitions: map[string][]int{}}
+	for p, replicas := range state.Partitions {
+		ts.Partitions[p] = copyInts(replicas)
+	}
+
+	return ts, nil
+}
+
+// GetTopicStateISR takes a topic name. If the topic exists,
+// the topic state is returned as a TopicStateISR.
+func (z *SnapshotHandler) GetTopicStateISR(t string) (TopicStateISR, error) {
+	state, exist := z.s.PartitionStates[t]
+	if !exist {
+		return nil, ErrNoNode{s: fmt.Sprintf("[%s] partition states not found in snapshot", t)}
+	}
+
+	ts := TopicStateISR{}
+	for p, s := range state {
+		s.ISR = copyInts(s.ISR)
+		ts[p] = s
+	}
+
+	return ts, nil
+}
+
+// GetPartitionMap takes a topic name. If the topic exists, the state of
+// the topic is returned as a *PartitionMap. Partitions undergoing a
+// reassignment at the time of the snapshot reflect the reassignment
+// target replica sets.
+func (z *SnapshotHandler) GetPartitionMap(t string) (*PartitionMap, error) {
+	ts, err := z.GetTopicState(t)
+	if err != nil {
+		return nil, err
+	}
+
+	for p, replicas := range z.s.Reassignments[t] {
+		ts.Partitions[strconv.Itoa(p)] = copyInts(replicas)
+	}
+
+	pm := NewPartitionMap()
+	for partition, replicas := range ts.Partitions {
+		i, _ := strconv.Atoi(partition)
+		pm.Partitions = append(pm.Partitions, Partition{
+			Topic:     t,
+			Partition: i,
+			Replicas:  replicas,
+		})
+	}
+
+	sort.Sort(pm.Partitions)
+
+	return pm, nil
+}
+
+// UpdateKafkaConfig returns an ErrSnapshotReadOnly.
+func (z *SnapshotHandler) UpdateKafkaConfig(c KafkaConfig) ([]bool, error) {
+	return make([]bool, len(c.Configs)), ErrSnapshotReadOnly
+}
+
+func copyInts(s []int) []int {
+	return append([]int{}, s...)
+}
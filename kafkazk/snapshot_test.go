@@ -0,0 +1,146 @@
+package kafkazk
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+func testSnapshotHandler(t *testing.T) Handler {
+	s, err := TakeSnapshot(&Mock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Round trip through a file.
+	dir, err := ioutil.TempDir("", "kafkazk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "snapshot.json")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	zk, err := NewSnapshotHandlerFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return zk
+}
+
+func TestSnapshotGetTopics(t *testing.T) {
+	zk := testSnapshotHandler(t)
+
+	topics, _ := zk.GetTopics([]*regexp.Regexp{regexp.MustCompile("test_topic.*")})
+	sort.Strings(topics)
+
+	if len(topics) != 2 || topics[0] != "test_topic" || topics[1] != "test_topic2" {
+		t.Errorf("Expected topics [test_topic test_topic2], got %v", topics)
+	}
+
+	pd, _ := zk.GetPendingDeletion()
+	if len(pd) != 1 || pd[0] != "deleting_topic" {
+		t.Errorf("Expected pending deletion [deleting_topic], got %v", pd)
+	}
+}
+
+func TestSnapshotGetAllBrokerMeta(t *testing.T) {
+	zk := testSnapshotHandler(t)
+	expected, _ := (&Mock{}).GetAllBrokerMeta(true)
+
+	bmm, errs := zk.GetAllBrokerMeta(true)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if len(bmm) != len(expected) {
+		t.Fatalf("Expected %d brokers, got %d", len(expected), len(bmm))
+	}
+
+	for id, m := range expected {
+		if bmm[id].Rack != m.Rack || bmm[id].StorageFree != m.StorageFree {
+			t.Errorf("Expected broker %d meta %v, got %v", id, m, bmm[id])
+		}
+	}
+
+	bmm, _ = zk.GetAllBrokerMeta(false)
+	for id, m := range bmm {
+		if m.StorageFree != 0 {
+			t.Errorf("Expected no storage free for broker %d, got %f", id, m.StorageFree)
+		}
+	}
+}
+
+func TestSnapshotGetPartitionMap(t *testing.T) {
+	zk := testSnapshotHandler(t)
+
+	pm, err := zk.GetPartitionMap("test_topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, _ := (&Mock{}).GetTopicState("test_topic")
+	if len(pm.Partitions) != len(ts.Partitions) {
+		t.Fatalf("Expected %d partitions, got %d", len(ts.Partitions), len(pm.Partitions))
+	}
+
+	if pm.Partitions[2].Replicas[0] != 1004 {
+		t.Errorf("Expected p2 replicas %v, got %v", ts.Partitions["2"], pm.Partitions[2].Replicas)
+	}
+
+	if _, err := zk.GetPartitionMap("missing"); err == nil {
+		t.Error("Expected error for missing topic")
+	}
+}
+
+func TestSnapshotMetadata(t *testing.T) {
+	zk := testSnapshotHandler(t)
+
+	pmm, err := zk.GetAllPartitionMeta()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s, _ := pmm.Size(Partition{Topic: "test_topic", Partition: 1}); s != 1500.00 {
+		t.Errorf("Expected size 1500.00, got %f", s)
+	}
+
+	if _, err := zk.MaxMetaAge(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := zk.GetTopicConfig("test_topic"); err != nil {
+		t.Error(err)
+	}
+
+	isr, err := zk.GetTopicStateISR("test_topic")
+	if err != nil || isr["0"].Leader != 1000 {
+		t.Errorf("Unexpected topic state %v: %v", isr, err)
+	}
+}
+
+func TestSnapshotReadOnly(t *testing.T) {
+	zk := testSnapshotHandler(t)
+
+	if err := zk.Set("/path", "data"); err != ErrSnapshotReadOnly {
+		t.Errorf("Expected ErrSnapshotReadOnly, got %v", err)
+	}
+
+	if err := zk.CreateReassignments(NewPartitionMap()); err != ErrSnapshotReadOnly {
+		t.Errorf("Expected ErrSnapshotReadOnly, got %v", err)
+	}
+}
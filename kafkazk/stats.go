@@ -118,7 +118,14 @@ func (b BrokerMap) StorageDiff(b2 BrokerMap) map[int][2]float64 {
 
 // StorageRangeSpread returns the range spread
 // of free storage for all brokers in the BrokerMap.
+// If the StorageTotal is known for all brokers, the
+// range of storage used percentages is returned.
 func (b BrokerMap) StorageRangeSpread() float64 {
+	if b.HasStorageTotal() {
+		l, h := b.StorageUsedMinMax()
+		return h - l
+	}
+
 	l, h := b.MinMax()
 	// Return range spread.
 	return (h - l) / l * 100
@@ -265,3 +272,127 @@ func (b BrokerMap) BelowMean(d float64, f func() float64) []int {
 
 	return ids
 }
+
+// StorageUsedPercent returns the percentage of the broker
+// storage capacity used. If the StorageTotal is unknown,
+// 0 is returned.
+func (b *Broker) StorageUsedPercent() float64 {
+	if b.StorageTotal <= 0 {
+		return 0
+	}
+
+	return (b.StorageTotal - b.StorageFree) / b.StorageTotal * 100
+}
+
+// HasStorageTotal returns whether the StorageTotal
+// is known for all brokers in the BrokerMap.
+func (b BrokerMap) HasStorageTotal() bool {
+	var c int
+
+	for id, br := range b {
+		if id == StubBrokerID {
+			continue
+		}
+
+		if br.StorageTotal <= 0 {
+			return false
+		}
+
+		c++
+	}
+
+	return c > 0
+}
+
+// StorageUsedMean returns the storage used percentage of the combined
+// capacity of all brokers in the BrokerMap; this is the storage used
+// percentage of every broker when perfectly balanced.
+func (b BrokerMap) StorageUsedMean() float64 {
+	var used float64
+	var total float64
+
+	for _, br := range b {
+		if br.ID != StubBrokerID && br.StorageTotal > 0 {
+			used += br.StorageTotal - br.StorageFree
+			total += br.StorageTotal
+		}
+	}
+
+	return used / total * 100
+}
+
+// StorageUsedMinMax returns the low and high storage
+// used percentages for all brokers in the BrokerMap.
+func (b BrokerMap) StorageUsedMinMax() (float64, float64) {
+	h, l := -math.MaxFloat64, math.MaxFloat64
+
+	for id := range b {
+		if id == StubBrokerID {
+			continue
+		}
+
+		v := b[id].StorageUsedPercent()
+
+		if v > h {
+			h = v
+		}
+
+		if v < l {
+			l = v
+		}
+	}
+
+	return l, h
+}
+
+// StorageUsedStdDev returns the standard deviation of the
+// storage used percentages for all brokers in the BrokerMap.
+func (b BrokerMap) StorageUsedStdDev() float64 {
+	var t float64
+	var s float64
+	var l float64
+
+	for id := range b {
+		if id == StubBrokerID {
+			continue
+		}
+		l++
+		t += b[id].StorageUsedPercent()
+	}
+
+	m := t / l
+
+	for id := range b {
+		if id == StubBrokerID {
+			continue
+		}
+		s += math.Pow(m-b[id].StorageUsedPercent(), 2)
+	}
+
+	return math.Sqrt(s / l)
+}
+
+// AboveStorageUsedMean returns a sorted []int of broker IDs with a storage
+// used percentage above the StorageUsedMean by d percent (0.00 < d).
+func (b BrokerMap) AboveStorageUsedMean(d float64) []int {
+	m := b.StorageUsedMean()
+	var ids []int
+
+	if d <= 0.00 {
+		return ids
+	}
+
+	for _, br := range b {
+		if br.ID == StubBrokerID {
+			continue
+		}
+
+		if (br.StorageUsedPercent()-m)/m > d {
+			ids = append(ids, br.ID)
+		}
+	}
+
+	sort.Ints(ids)
+
+	return ids
+}
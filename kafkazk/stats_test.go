@@ -241,3 +241,70 @@ func sameIDs(a, b []int) bool {
 
 	return true
 }
+
+func newMockBrokerMapWithTotals() BrokerMap {
+	bm := newMockBrokerMap()
+	totals := map[int]float64{1001: 400, 1002: 400, 1003: 1200, 1004: 800}
+
+	for id, t := range totals {
+		bm[id].StorageTotal = t
+	}
+
+	return bm
+}
+
+func TestStorageUsed(t *testing.T) {
+	bm := newMockBrokerMap()
+	if bm.HasStorageTotal() {
+		t.Error("Expected HasStorageTotal false")
+	}
+
+	bm = newMockBrokerMapWithTotals()
+	if !bm.HasStorageTotal() {
+		t.Error("Expected HasStorageTotal true")
+	}
+
+	if u := bm[1003].StorageUsedPercent(); u != 75.00 {
+		t.Errorf("Expected storage used 75.00%%, got %.2f%%", u)
+	}
+
+	m := fmt.Sprintf("%.4f", bm.StorageUsedMean())
+	if m != "64.2857" {
+		t.Errorf("Expected storage used mean of 64.2857, got %s", m)
+	}
+
+	if l, h := bm.StorageUsedMinMax(); l != 50.00 || h != 75.00 {
+		t.Errorf("Expected min-max 50.00, 75.00, got %.2f, %.2f", l, h)
+	}
+
+	// With capacities, the range spread
+	// is of storage used percentages.
+	if rs := bm.StorageRangeSpread(); rs != 25.00 {
+		t.Errorf("Expected storage range spread 25, got %f", rs)
+	}
+
+	if sd := bm.StorageUsedStdDev(); sd != 12.50 {
+		t.Errorf("Expected storage used std. deviation 12.50, got %f", sd)
+	}
+
+	tests := map[float64][]int{
+		0.10: []int{1001, 1003},
+		0.20: []int{},
+	}
+
+	for d, expected := range tests {
+		if results := bm.AboveStorageUsedMean(d); !sameIDs(results, expected) {
+			t.Errorf("Expected %v, got %v for distance %.2f", expected, results, d)
+		}
+	}
+
+	bl := bm.List()
+	bl.SortByStorageUsed()
+
+	expected := []int{1002, 1004, 1001, 1003}
+	for i, br := range bl[:4] {
+		if br.ID != expected[i] {
+			t.Errorf("Expected broker %d, got %d", expected[i], br.ID)
+		}
+	}
+}
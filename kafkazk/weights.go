@@ -0,0 +1,220 @@
+package kafkazk
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PlacementWeights is a mapping of placement dimensions to
+// weights for the weighted placement strategy. Valid dimensions
+// are count, storage, leaders and throughput.
+type PlacementWeights map[string]float64
+
+// WeightDimensions lists the valid PlacementWeights dimensions.
+var WeightDimensions = []string{"count", "leaders", "storage", "throughput"}
+
+// ParsePlacementWeights parses a comma delimited list of
+// dimension=weight pairs, e.g. "storage=0.5,count=0.3,leaders=0.2".
+func ParsePlacementWeights(s string) (PlacementWeights, error) {
+	w := PlacementWeights{}
+
+	for _, kv := range strings.Split(s, ",") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+
+		parts := strings.Split(kv, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid weight '%s'", kv)
+		}
+
+		dim := strings.TrimSpace(parts[0])
+		v, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight '%s'", kv)
+		}
+
+		if _, exist := w[dim]; exist {
+			return nil, fmt.Errorf("duplicate weight '%s'", dim)
+		}
+
+		w[dim] = v
+	}
+
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Validate returns an error if the PlacementWeights reference an unknown
+// dimension, include a negative weight or have no positive weights.
+func (w PlacementWeights) Validate() error {
+	var total float64
+
+	for dim, v := range w {
+		var valid bool
+		for _, d := range WeightDimensions {
+			if d == dim {
+				valid = true
+			}
+		}
+
+		switch {
+		case !valid:
+			return fmt.Errorf("unknown weight dimension '%s'", dim)
+		case v < 0:
+			return fmt.Errorf("weight '%s' must be >= 0", dim)
+		}
+
+		total += v
+	}
+
+	if total == 0 {
+		return fmt.Errorf("at least one weight must be > 0")
+	}
+
+	return nil
+}
+
+// Dimensions returns the sorted dimensions with a non-zero weight.
+func (w PlacementWeights) Dimensions() []string {
+	var dims []string
+	for d, v := range w {
+		if v > 0 {
+			dims = append(dims, d)
+		}
+	}
+
+	sort.Strings(dims)
+
+	return dims
+}
+
+// normalizedDeviation returns the deviation of v from the mean
+// m, normalized by the mean. The absolute deviation is returned
+// if the mean is 0.
+func normalizedDeviation(v, m float64) float64 {
+	if m == 0 {
+		return v
+	}
+
+	return (v - m) / math.Abs(m)
+}
+
+// score takes a candidate *Broker, the BrokerList of all candidates and
+// ConstraintsParams and returns the weighted sum of the candidate's
+// normalized deviation from the candidate mean in each dimension, as if
+// the request were placed on the candidate. Lower scores are better.
+func (w PlacementWeights) score(b *Broker, bl BrokerList, p ConstraintsParams) float64 {
+	var count, storage, throughput, leaders float64
+	for _, c := range bl {
+		count += float64(c.Used)
+		storage += c.StorageFree
+		throughput += c.Throughput
+		leaders += float64(p.LeaderCounts[c.ID])
+	}
+
+	n := float64(len(bl))
+
+	var s float64
+
+	for _, dim := range w.Dimensions() {
+		var d float64
+
+		switch dim {
+		case "count":
+			d = normalizedDeviation(float64(b.Used+1), count/n)
+		case "storage":
+			// Less storage free is worse.
+			d = -normalizedDeviation(b.StorageFree-p.RequestSize, storage/n)
+		case "throughput":
+			d = normalizedDeviation(b.Throughput+p.RequestThroughput, throughput/n)
+		case "leaders":
+			// Only leader placements
+			// affect leadership.
+			if !p.Leader {
+				continue
+			}
+			d = normalizedDeviation(float64(p.LeaderCounts[b.ID]+1), leaders/n)
+		}
+
+		s += w[dim] * d
+	}
+
+	return s
+}
+
+// DimensionScores takes a PartitionMap, BrokerMap and PartitionMetaMap and
+// returns the mean absolute normalized deviation from the mean across
+// brokers for each weighted dimension, along with the weighted total
+// under the "weighted" key. Brokers referenced in the PartitionMap and
+// brokers in the BrokerMap not marked for replacement are scored. The
+// storage dimension uses the BrokerMap StorageFree values. Lower scores
+// indicate a more even distribution.
+func (w PlacementWeights) DimensionScores(pm *PartitionMap, bm BrokerMap, pmm PartitionMetaMap) map[string]float64 {
+	ids := map[int]bool{}
+	for _, b := range bm {
+		if b.ID != StubBrokerID && !b.Replace {
+			ids[b.ID] = true
+		}
+	}
+
+	values := map[string]map[int]float64{}
+	for _, d := range WeightDimensions {
+		values[d] = map[int]float64{}
+	}
+
+	for _, partn := range pm.Partitions {
+		tp, _ := pmm.Throughput(partn)
+
+		for i, id := range partn.Replicas {
+			if id == StubBrokerID {
+				continue
+			}
+
+			ids[id] = true
+			values["count"][id]++
+			values["throughput"][id] += tp
+
+			if i == 0 {
+				values["leaders"][id]++
+			}
+		}
+	}
+
+	for id := range ids {
+		if b, exist := bm[id]; exist {
+			values["storage"][id] = b.StorageFree
+		}
+	}
+
+	scores := map[string]float64{}
+
+	if len(ids) == 0 {
+		return scores
+	}
+
+	for _, dim := range w.Dimensions() {
+		var sum float64
+		for id := range ids {
+			sum += values[dim][id]
+		}
+
+		mean := sum / float64(len(ids))
+
+		var dev float64
+		for id := range ids {
+			dev += math.Abs(normalizedDeviation(values[dim][id], mean))
+		}
+
+		scores[dim] = dev / float64(len(ids))
+		scores["weighted"] += w[dim] * scores[dim]
+	}
+
+	return scores
+}
@@ -0,0 +1,183 @@
+package kafkazk
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePlacementWeights(t *testing.T) {
+	w, err := ParsePlacementWeights("storage=0.5, count=0.3,leaders=0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := PlacementWeights{"storage": 0.5, "count": 0.3, "leaders": 0.2}
+	for d, v := range expected {
+		if w[d] != v {
+			t.Errorf("Expected %s weight %.1f, got %.1f", d, v, w[d])
+		}
+	}
+
+	dims := w.Dimensions()
+	if len(dims) != 3 || dims[0] != "count" || dims[2] != "storage" {
+		t.Errorf("Unexpected dimensions %v", dims)
+	}
+
+	invalid := []string{
+		"",
+		"count",
+		"count=x",
+		"count=-1",
+		"count=0",
+		"disk=1",
+		"count=1,count=2",
+	}
+
+	for _, s := range invalid {
+		if _, err := ParsePlacementWeights(s); err == nil {
+			t.Errorf("Expected non-nil error for '%s'", s)
+		}
+	}
+}
+
+func TestSelectBrokerWeighted(t *testing.T) {
+	newList := func() BrokerList {
+		return BrokerList{
+			&Broker{ID: 1001, Used: 5, StorageFree: 1000},
+			&Broker{ID: 1002, Used: 1, StorageFree: 100},
+		}
+	}
+
+	tests := []struct {
+		params   ConstraintsParams
+		expected int
+	}{
+		{ConstraintsParams{Weights: PlacementWeights{"count": 1}}, 1002},
+		{ConstraintsParams{Weights: PlacementWeights{"storage": 1}}, 1001},
+		// 1002 has count deviation -0.33 and storage deviation 0.82;
+		// 1001 has count deviation 1.0 and storage deviation -0.82.
+		{ConstraintsParams{Weights: PlacementWeights{"count": 0.5, "storage": 0.5}}, 1001},
+		{ConstraintsParams{Weights: PlacementWeights{"count": 0.8, "storage": 0.2}}, 1002},
+		{ConstraintsParams{
+			Weights:      PlacementWeights{"count": 0.1, "leaders": 0.9},
+			Leader:       true,
+			LeaderCounts: map[int]int{1002: 4},
+		}, 1001},
+		// Leadership is ignored for follower placements.
+		{ConstraintsParams{
+			Weights:      PlacementWeights{"count": 0.1, "leaders": 0.9},
+			LeaderCounts: map[int]int{1002: 4},
+		}, 1002},
+	}
+
+	for i, test := range tests {
+		test.params.SelectorMethod = "weighted"
+		b, err := NewConstraints().SelectBroker(newList(), test.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if b.ID != test.expected {
+			t.Errorf("[test %d] Expected broker %d, got %d", i, test.expected, b.ID)
+		}
+	}
+
+	// Weights are required.
+	_, err := NewConstraints().SelectBroker(newList(), ConstraintsParams{SelectorMethod: "weighted"})
+	if err != ErrInvalidSelectionMethod {
+		t.Errorf("Expected ErrInvalidSelectionMethod, got %v", err)
+	}
+}
+
+func TestRebuildWeighted(t *testing.T) {
+	zk := &Mock{}
+	bm, _ := zk.GetAllBrokerMeta(false)
+	pm, _ := PartitionMapFromString(testGetMapString4("test_topic"))
+	brokers := BrokerMapFromPartitionMap(pm, bm, true)
+
+	rebuildParams := RebuildParams{
+		PMM:      NewPartitionMetaMap(),
+		BM:       brokers,
+		Strategy: "weighted",
+		Weights:  PlacementWeights{"count": 0.5, "leaders": 0.5},
+	}
+
+	out, errs := pm.Strip().Rebuild(rebuildParams)
+	if errs != nil {
+		t.Fatalf("Unexpected error(s): %s", errs)
+	}
+
+	spread := func(m map[int]int) int {
+		min, max := math.MaxInt32, 0
+		for _, v := range m {
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+		return max - min
+	}
+
+	counts, leaders := map[int]int{}, map[int]int{}
+	for _, partn := range out.Partitions {
+		for i, id := range partn.Replicas {
+			counts[id]++
+			if i == 0 {
+				leaders[id]++
+			}
+		}
+	}
+
+	if len(counts) != 4 || spread(counts) > 1 {
+		t.Errorf("Unexpected replica counts %v", counts)
+	}
+
+	if len(leaders) != 4 || spread(leaders) > 1 {
+		t.Errorf("Unexpected leader counts %v", leaders)
+	}
+
+	// Weights are required.
+	rebuildParams.Weights = nil
+	if _, errs := pm.Strip().Rebuild(rebuildParams); errs == nil {
+		t.Error("Expected non-nil errors")
+	}
+}
+
+func TestDimensionScores(t *testing.T) {
+	zk := &Mock{}
+	pm, _ := zk.GetPartitionMap("test_topic")
+	pmm, _ := zk.GetAllPartitionMeta()
+	bm := BrokerMapFromPartitionMap(pm, nil, false)
+
+	for id, b := range bm {
+		b.StorageFree = 1000
+		if id == 1001 {
+			b.StorageFree = 3000
+		}
+	}
+
+	w := PlacementWeights{"count": 0.5, "leaders": 0.25, "storage": 0.25}
+	scores := w.DimensionScores(pm, bm, pmm)
+
+	// Counts: 1001 3, 1002 3, 1003 2, 1004 2; mean 2.5.
+	// Leaders: 1 each.
+	// Storage: 3000, 1000, 1000, 1000; mean 1500.
+	expected := map[string]float64{
+		"count":    0.2,
+		"leaders":  0,
+		"storage":  0.5,
+		"weighted": 0.225,
+	}
+
+	if len(scores) != len(expected) {
+		t.Errorf("Unexpected scores %v", scores)
+	}
+
+	for d, e := range expected {
+		if math.Abs(scores[d]-e) > 0.0001 {
+			t.Errorf("Expected %s score %.4f, got %.4f", d, e, scores[d])
+		}
+	}
+}
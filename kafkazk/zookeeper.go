@@ -55,6 +55,8 @@ type Handler interface {
 	GetTopicStateISR(string) (TopicStateISR, error)
 	UpdateKafkaConfig(KafkaConfig) ([]bool, error)
 	GetReassignments() Reassignments
+	ListReassignments() (Reassignments, error)
+	CreateReassignments(*PartitionMap) error
 	GetPendingDeletion() ([]string, error)
 	GetTopics([]*regexp.Regexp) ([]string, error)
 	GetTopicConfig(string) (*TopicConfig, error)
@@ -289,8 +291,19 @@ func (z *ZKHandler) Children(p string) ([]string, error) {
 }
 
 // GetReassignments looks up any ongoing topic reassignments and
-// returns the data as a Reassignments.
+// returns the data as a Reassignments. Lookup errors are ignored;
+// use ListReassignments where an error must be distinguished from
+// no reassignments in progress.
 func (z *ZKHandler) GetReassignments() Reassignments {
+	reassigns, _ := z.ListReassignments()
+	return reassigns
+}
+
+// ListReassignments looks up any ongoing topic reassignments and
+// returns the data as a Reassignments. An empty Reassignments is
+// returned if no reassignment is in progress (the znode doesn't
+// exist); all other lookup errors are returned.
+func (z *ZKHandler) ListReassignments() (Reassignments, error) {
 	reassigns := Reassignments{}
 
 	var path string
@@ -303,11 +316,18 @@ func (z *ZKHandler) GetReassignments() Reassignments {
 	// Get reassignment config.
 	data, err := z.Get(path)
 	if err != nil {
-		return reassigns
+		switch err.(type) {
+		case ErrNoNode:
+			return reassigns, nil
+		default:
+			return nil, err
+		}
 	}
 
 	rec := &reassignPartitions{}
-	json.Unmarshal(data, rec)
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, err
+	}
 
 	// Map reassignment config to a
 	// Reassignments.
@@ -318,7 +338,27 @@ func (z *ZKHandler) GetReassignments() Reassignments {
 		reassigns[cfg.Topic][cfg.Partition] = cfg.Replicas
 	}
 
-	return reassigns
+	return reassigns, nil
+}
+
+// CreateReassignments takes a *PartitionMap and writes it to
+// /admin/reassign_partitions, which initiates a partition reassignment
+// for all partitions in the map. An error is returned if a reassignment
+// is already in progress.
+func (z *ZKHandler) CreateReassignments(pm *PartitionMap) error {
+	var path string
+	if z.Prefix != "" {
+		path = fmt.Sprintf("/%s/admin/reassign_partitions", z.Prefix)
+	} else {
+		path = "/admin/reassign_partitions"
+	}
+
+	data, err := json.Marshal(pm)
+	if err != nil {
+		return fmt.Errorf("Error marshalling partition map: %s", err)
+	}
+
+	return z.Create(path, string(data))
 }
 
 // GetPendingDeletion returns any topics pending deletion.
@@ -467,6 +507,9 @@ func (z *ZKHandler) GetAllBrokerMeta(withMetrics bool) (BrokerMetaMap, []error)
 				bmm[bid].MetricsIncomplete = true
 			} else {
 				bmm[bid].StorageFree = m.StorageFree
+				bmm[bid].StorageTotal = m.StorageTotal
+				bmm[bid].LogDirs = m.LogDirs
+				bmm[bid].InstanceType = m.InstanceType
 			}
 		}
 
@@ -22,6 +22,17 @@ func (zk *Mock) GetReassignments() Reassignments {
 	return r
 }
 
+// ListReassignments mocks ListReassignments.
+func (zk *Mock) ListReassignments() (Reassignments, error) {
+	return zk.GetReassignments(), nil
+}
+
+// CreateReassignments mocks CreateReassignments.
+func (zk *Mock) CreateReassignments(pm *PartitionMap) error {
+	_ = pm
+	return nil
+}
+
 func (zk *Mock) GetPendingDeletion() ([]string, error) {
 	return []string{"deleting_topic"}, nil
 }
@@ -377,6 +377,36 @@ func TestGetReassignments(t *testing.T) {
 	}
 }
 
+func TestListReassignments(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	re, err := zki.ListReassignments()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, exist := re["topic0"]; !exist {
+		t.Error("Expected 'topic0' in reassignments")
+	}
+}
+
+func TestCreateReassignments(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	// A reassignment is already in progress
+	// from the test setup and should not be
+	// overwritten.
+	pm, _ := PartitionMapFromString(testGetMapString("topic0"))
+
+	if err := zki.CreateReassignments(pm); err == nil {
+		t.Error("Expected error creating reassignments while one is in progress")
+	}
+}
+
 func TestGetPendingDeletion(t *testing.T) {
 	if testing.Short() {
 		t.Skip()